@@ -0,0 +1,137 @@
+package rauther
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rosberry/rauther/audit"
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/logger"
+	"github.com/rosberry/rauther/session"
+	"github.com/rosberry/rauther/user"
+)
+
+type (
+	// clientInfo is IP and user agent of client set by ContextWithClient
+	clientInfo struct {
+		ip        string
+		userAgent string
+	}
+
+	// auditRecord is audit event of flow filled while flow runs:
+	//
+	//	rec := r.startAudit(ctx, audit.SignIn, at, uid)
+	//	defer rec.end(&err)
+	auditRecord struct {
+		r     *Rauther
+		ctx   context.Context
+		event audit.Event
+	}
+)
+
+// clientIP returns host of remote address. Use middleware of HTTP framework to set RemoteAddr behind proxy
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
+
+// auditUserID formats user ID for audit event
+func auditUserID(id interface{}) string {
+	if id == nil {
+		return ""
+	}
+
+	return fmt.Sprint(id)
+}
+
+func (r *Rauther) startAudit(ctx context.Context, t audit.Type, at *authtype.AuthMethod, uid string) *auditRecord {
+	rec := &auditRecord{
+		r:   r,
+		ctx: ctx,
+		event: audit.Event{
+			Type: t,
+			UID:  uid,
+		},
+	}
+
+	if at != nil {
+		rec.event.AuthKey = at.Key
+	}
+
+	return rec
+}
+
+// setUser sets user of event, nil user is ignored
+func (rec *auditRecord) setUser(u user.User) {
+	if u != nil {
+		rec.event.UserID = auditUserID(u.GetID())
+	}
+}
+
+// setSession sets session ID of event and user of session if user is not set yet
+func (rec *auditRecord) setSession(info sessionInfo) {
+	if idSession, ok := info.Session.(session.IDSession); ok {
+		rec.event.SessionID = idSession.GetID()
+	}
+
+	if rec.event.UserID == "" {
+		rec.event.UserID = auditUserID(info.UserID)
+	}
+}
+
+// end records event with outcome of flow error
+func (rec *auditRecord) end(err *error) {
+	rec.event.Outcome = audit.Success
+
+	if *err != nil {
+		_, body, _ := ErrorDetails(*err)
+
+		rec.event.Outcome = audit.Failure
+		rec.event.ErrorCode = body.Code
+	}
+
+	rec.r.recordAudit(rec.ctx, rec.event)
+}
+
+// recordAudit sends event with client info and time to audit sink. Errors of sink are logged only
+func (r *Rauther) recordAudit(ctx context.Context, e audit.Event) {
+	client := contextClient(ctx)
+	e.IP, e.UserAgent = client.ip, client.userAgent
+	e.Time = time.Now()
+
+	if err := r.deps.AuditSink.Record(ctx, e); err != nil {
+		r.logger.Log(logger.ErrorLevel, "record audit event", logger.F("event", string(e.Type)), logger.Err(err))
+	}
+}
+
+// removeGuestUser removes guest user replaced by signed in user
+func (r *Rauther) removeGuestUser(ctx context.Context, id interface{}) {
+	e := audit.Event{
+		Type:    audit.GuestDelete,
+		Outcome: audit.Success,
+		UserID:  auditUserID(id),
+	}
+
+	if err := r.remover(ctx).RemoveByID(id); err != nil {
+		r.logger.Log(logger.WarnLevel, "failed delete guest user", logger.UserID(id), logger.Err(err))
+
+		e.Outcome = audit.Failure
+	} else {
+		r.publish(ctx, events.GuestRemoved{UserID: id})
+	}
+
+	r.recordAudit(ctx, e)
+}
+
+// AuditHistory returns last events of user (all if limit is 0). Audit sink must implement audit.Querier
+func (r *Rauther) AuditHistory(ctx context.Context, userID interface{}, limit int) ([]audit.Event, error) {
+	return audit.UserHistory(ctx, r.deps.AuditSink, auditUserID(userID), limit)
+}
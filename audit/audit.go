@@ -0,0 +1,199 @@
+// Package audit defines tamper-evident log of security-relevant events: sign-ups, sign-ins, sign-outs,
+// confirmations, recoveries, UID changes, links, merges and guest deletions. Entries are hash-chained:
+// every event contains hash of previous event, so removed or changed entries are detected by Verify.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Types of events
+const (
+	SignUp           Type = "sign_up"
+	SignIn           Type = "sign_in"
+	SignOut          Type = "sign_out"
+	Confirm          Type = "confirm"
+	RecoveryRequest  Type = "recovery_request"
+	RecoveryReset    Type = "recovery_reset"
+	RecoveryLink     Type = "recovery_link"
+	UIDChangeRequest Type = "uid_change_request"
+	Link             Type = "link"
+	Merge            Type = "merge"
+	GuestDelete      Type = "guest_delete"
+	UIDChange        Type = "uid_change"
+)
+
+// Outcomes of events
+const (
+	Success Outcome = "success"
+	Failure Outcome = "failure"
+)
+
+var (
+	ErrBrokenChain        = errors.New("audit chain is broken")
+	ErrQueryNotSupported  = errors.New("audit sink does not support queries")
+	errUnexpectedLastHash = errors.New("unexpected hash of last event")
+)
+
+type (
+	// Type of event
+	Type string
+
+	// Outcome of event
+	Outcome string
+
+	// Event is audit log entry. PrevHash and Hash are set by sink
+	Event struct {
+		Type    Type    `json:"type"`
+		Outcome Outcome `json:"outcome"`
+		// ErrorCode is code of common.Errors for failures
+		ErrorCode string `json:"error_code,omitempty"`
+
+		// UserID is ID of user (formatted with fmt.Sprint), empty if user is unknown
+		UserID  string `json:"user_id,omitempty"`
+		AuthKey string `json:"auth_key,omitempty"`
+		UID     string `json:"uid,omitempty"`
+		// SessionID is ID of session (device), if session implements session.IDSession
+		SessionID string `json:"session_id,omitempty"`
+
+		IP        string `json:"ip,omitempty"`
+		UserAgent string `json:"user_agent,omitempty"`
+
+		// Data contains details of event, e.g. ID of merged user
+		Data map[string]string `json:"data,omitempty"`
+
+		Time     time.Time `json:"time"`
+		PrevHash string    `json:"prev_hash"`
+		Hash     string    `json:"hash"`
+	}
+
+	// Sink receives events. Implementations must be safe for concurrent use and chain events with Chain
+	Sink interface {
+		Record(ctx context.Context, e Event) error
+	}
+
+	// Querier is optional interface of Sink for reading events
+	Querier interface {
+		// Query returns events matching filter in order of recording
+		Query(ctx context.Context, f Filter) ([]Event, error)
+	}
+
+	// Filter of events. Empty fields match all events
+	Filter struct {
+		UserID string
+		Types  []Type
+		Since  time.Time
+		// Limit returns only last events
+		Limit int
+	}
+
+	// Nop is sink that does nothing. Used by default
+	Nop struct{}
+)
+
+func (Nop) Record(context.Context, Event) error { return nil }
+
+// Match reports whether event matches filter (except Limit)
+func (f Filter) Match(e Event) bool {
+	if f.UserID != "" && e.UserID != f.UserID {
+		return false
+	}
+
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+
+	if len(f.Types) == 0 {
+		return true
+	}
+
+	for _, t := range f.Types {
+		if e.Type == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply returns events matching filter
+func (f Filter) Apply(events []Event) []Event {
+	var matched []Event
+
+	for _, e := range events {
+		if f.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+
+	if f.Limit > 0 && len(matched) > f.Limit {
+		matched = matched[len(matched)-f.Limit:]
+	}
+
+	return matched
+}
+
+// UserHistory returns last events of user (all if limit is 0) of sink implementing Querier
+func UserHistory(ctx context.Context, sink Sink, userID string, limit int) ([]Event, error) {
+	q, ok := sink.(Querier)
+	if !ok {
+		return nil, ErrQueryNotSupported
+	}
+
+	return q.Query(ctx, Filter{UserID: userID, Limit: limit})
+}
+
+// Hash returns hex encoded SHA-256 of event JSON with PrevHash = prevHash and empty Hash
+func Hash(prevHash string, e Event) string {
+	e.PrevHash = prevHash
+	e.Hash = ""
+
+	b, _ := json.Marshal(e) // nolint:errchkjson
+
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Chain sets PrevHash and Hash of event after last event with hash lastHash
+func Chain(lastHash string, e Event) Event {
+	e.Time = e.Time.UTC()
+	e.PrevHash = lastHash
+	e.Hash = Hash(lastHash, e)
+
+	return e
+}
+
+// Verify checks hashes of events and links between them. Events must be consecutive part of chain
+func Verify(events []Event) error {
+	for i, e := range events {
+		if i > 0 && e.PrevHash != events[i-1].Hash {
+			return fmt.Errorf("%w: event %d is not linked with previous event", ErrBrokenChain, i)
+		}
+
+		if Hash(e.PrevHash, e) != e.Hash {
+			return fmt.Errorf("%w: hash of event %d mismatch", ErrBrokenChain, i)
+		}
+	}
+
+	return nil
+}
+
+// VerifyLast checks events and that last event has expected hash (e.g. stored separately from log)
+func VerifyLast(events []Event, lastHash string) error {
+	if err := Verify(events); err != nil {
+		return err
+	}
+
+	if len(events) > 0 && events[len(events)-1].Hash != lastHash {
+		return fmt.Errorf("%w: %v", ErrBrokenChain, errUnexpectedLastHash)
+	}
+
+	return nil
+}
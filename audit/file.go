@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	filePerm      = 0o600
+	maxLineLength = 1 << 20
+)
+
+// FileSink writes events to file in JSON lines format. Chain is continued after last event of existing file
+type FileSink struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+	last string
+}
+
+var (
+	_ Sink    = (*FileSink)(nil)
+	_ Querier = (*FileSink)(nil)
+)
+
+// NewFileSink opens (creates if not exists) file for appending events
+func NewFileSink(path string) (*FileSink, error) {
+	s := &FileSink{path: path}
+
+	err := s.scan(func(e Event) {
+		s.last = e.Hash
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	s.file, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, filePerm)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+
+	return s, nil
+}
+
+// Record chains event and appends it to file
+func (s *FileSink) Record(_ context.Context, e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e = Chain(s.last, e)
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal audit event: %w", err)
+	}
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+
+	s.last = e.Hash
+
+	return nil
+}
+
+// Query reads events of file matching filter
+func (s *FileSink) Query(_ context.Context, f Filter) ([]Event, error) {
+	var events []Event
+
+	err := s.scan(func(e Event) {
+		if f.Match(e) {
+			events = append(events, e)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Apply(events), nil
+}
+
+// Events reads all events of file, e.g. for Verify
+func (s *FileSink) Events(ctx context.Context) ([]Event, error) {
+	return s.Query(ctx, Filter{})
+}
+
+// Close closes file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+func (s *FileSink) scan(fn func(e Event)) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineLength)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("read audit log line %d: %w", line, err)
+		}
+
+		fn(e)
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type (
+	// Storer saves events in DB
+	Storer interface {
+		// SaveEvent saves chained event
+		SaveEvent(ctx context.Context, e Event) error
+
+		// LastEvent returns last saved event or nil if there are no events
+		LastEvent(ctx context.Context) (*Event, error)
+
+		// FindEvents returns events matching filter (see Filter.Match) in order of saving
+		FindEvents(ctx context.Context, f Filter) ([]Event, error)
+	}
+
+	// StorerSink chains events and saves them by Storer.
+	// Events are chained in one process: use one instance (or lock in storer) to keep chain linear
+	StorerSink struct {
+		storer Storer
+
+		mu     sync.Mutex
+		loaded bool
+		last   string
+	}
+)
+
+var (
+	_ Sink    = (*StorerSink)(nil)
+	_ Querier = (*StorerSink)(nil)
+)
+
+// NewStorerSink returns sink that saves events by storer. Chain is continued after last saved event
+func NewStorerSink(s Storer) *StorerSink {
+	return &StorerSink{storer: s}
+}
+
+// Record chains event and saves it
+func (s *StorerSink) Record(ctx context.Context, e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		last, err := s.storer.LastEvent(ctx)
+		if err != nil {
+			return fmt.Errorf("load last audit event: %w", err)
+		}
+
+		if last != nil {
+			s.last = last.Hash
+		}
+
+		s.loaded = true
+	}
+
+	e = Chain(s.last, e)
+
+	if err := s.storer.SaveEvent(ctx, e); err != nil {
+		return fmt.Errorf("save audit event: %w", err)
+	}
+
+	s.last = e.Hash
+
+	return nil
+}
+
+// Query returns events of storer
+func (s *StorerSink) Query(ctx context.Context, f Filter) ([]Event, error) {
+	return s.storer.FindEvents(ctx, f)
+}
@@ -1,33 +1,65 @@
 package rauther
 
 import (
-	"log"
+	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rosberry/rauther/audit"
 	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/hooks"
+	"github.com/rosberry/rauther/metrics"
+	"github.com/rosberry/rauther/transport"
 )
 
-func (r *Rauther) signOutHandler(c *gin.Context) {
+func (r *Rauther) signOutHandler(c transport.Context) {
 	sessionInfo, success := r.checkSession(c)
 	if !success {
 		return
 	}
 
+	// sign-out has no request body, hooks get HTTP request (e.g. for headers)
+	if err := r.signOut(contextWithRequest(requestContext(c), c.Request()), sessionInfo); err != nil {
+		r.flowErrorResponse(c, err)
+		return
+	}
+
+	r.respond(c, Response{
+		Route:   RouteSignOut,
+		Token:   sessionInfo.Session.GetToken(),
+		User:    sessionInfo.User,
+		Session: sessionInfo.Session,
+		Fields: gin.H{
+			"result": true,
+			"token":  sessionInfo.Session.GetToken(),
+		},
+	})
+}
+
+// signOut unbinds user (binds new guest user if enabled) and sets new token of session
+func (r *Rauther) signOut(ctx context.Context, sessionInfo sessionInfo) (err error) {
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowSignOut, nil)
+	defer endFlow(&err)
+
+	rec := r.startAudit(ctx, audit.SignOut, nil, "")
+	defer rec.end(&err)
+	rec.setSession(sessionInfo)
+
+	if err := r.beforeHook(ctx, r.hooks.BeforeSignOut, hooks.Input{User: sessionInfo.User}, sessionInfo); err != nil {
+		return err
+	}
+
 	sessionInfo.Session.UnbindUser()
 
 	if r.Modules.GuestUser {
 		if sessionInfo.UserIsGuest {
-			err := r.deps.Storage.UserRemover.RemoveByID(sessionInfo.UserID)
-			if err != nil {
-				log.Printf("Failed delete guest user %v: %v", sessionInfo.UserID, err)
-			}
+			r.removeGuestUser(ctx, sessionInfo.UserID)
 		}
 
-		us, errType := r.createGuestUser()
+		us, errType := r.createGuestUser(ctx)
 		if errType != 0 {
-			errorResponse(c, http.StatusInternalServerError, errType)
-			return
+			return newError(http.StatusInternalServerError, errType)
 		}
 
 		sessionInfo.Session.BindUser(us)
@@ -35,14 +67,11 @@ func (r *Rauther) signOutHandler(c *gin.Context) {
 
 	sessionInfo.Session.SetToken(generateSessionToken())
 
-	err := r.deps.SessionStorer.Save(sessionInfo.Session)
-	if err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrSessionSave)
-		return
+	if err := r.sessions(ctx).Save(sessionInfo.Session); err != nil {
+		return wrapError(http.StatusInternalServerError, common.ErrSessionSave, err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"result": true,
-		"token":  sessionInfo.Session.GetToken(),
-	})
+	r.publish(ctx, events.SessionRevoked{Session: sessionInfo.Session, User: sessionInfo.User})
+
+	return nil
 }
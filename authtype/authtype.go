@@ -3,6 +3,7 @@ package authtype
 // Own package ?
 
 import (
+	"errors"
 	"log"
 	"reflect"
 
@@ -10,6 +11,9 @@ import (
 	"github.com/rosberry/auth"
 	"github.com/rosberry/rauther/code"
 	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/transport"
+	"github.com/rosberry/rauther/transport/gintransport"
+	"github.com/rosberry/rauther/uid"
 	"github.com/rosberry/rauther/user"
 )
 
@@ -32,6 +36,25 @@ type (
 
 		CodeGenerator code.Generator
 		CodeLength    int
+		// CodeLengths overrides CodeLength for events, e.g. longer codes for password recovery
+		CodeLengths map[sender.Event]int
+		// CodeVerifier compares stored and received codes. Default: code.Equal
+		CodeVerifier code.Verifier
+
+		// ConfirmLink sends signed confirmation link (sender.ConfirmationLinkEvent) instead of code on sign-up and resend.
+		// Link is configured by Config.ConfirmLink, codes of confirm route are still valid
+		ConfirmLink bool
+
+		// ResetLink sends signed single-use password reset link (sender.PasswordResetLinkEvent) instead of recovery code.
+		// Link is configured by Config.ResetLink, recovery routes with code are not valid for auth method
+		ResetLink bool
+
+		// UIDPipeline normalizes and validates UID of requests, e.g. uid.EmailPipeline.
+		// Default: uid.EmailPipeline for default email requests (SignUpRequest and SignInRequest are not set),
+		// UID is not changed for own requests
+		UIDPipeline uid.Pipeline
+		// DisableDefaultUIDPipeline keeps UID of default email requests as is (UIDPipeline is not set by default)
+		DisableDefaultUIDPipeline bool
 
 		DisableLink bool
 	}
@@ -43,11 +66,25 @@ type (
 	AuthMethods struct {
 		List          list
 		ExistingTypes map[Type]bool
-		Selector      Selector
+		// Selector is gin based selector. If it is set, ContextSelector is not used
+		Selector        Selector
+		ContextSelector ContextSelector
+
+		// errs are problems of added auth methods
+		errs []MethodError
+	}
+
+	// MethodError is problem of auth method found by Add
+	MethodError struct {
+		Key string
+		Err error
 	}
 
 	// Selector defines the key of authorization type using gin context
 	Selector func(c *gin.Context, t Type) (senderKey string)
+
+	// ContextSelector defines the key of authorization type using transport context (any HTTP framework)
+	ContextSelector func(c transport.Context, t Type) (senderKey string)
 )
 
 type (
@@ -75,6 +112,12 @@ type (
 	}
 )
 
+// Errors of Add
+var (
+	ErrInvalidType  = errors.New("invalid auth type")
+	ErrDuplicateKey = errors.New("auth method with such key already added")
+)
+
 const (
 	Password Type = iota
 	Social
@@ -92,8 +135,8 @@ const (
 // If selector is nil - used default selector
 func New(selector Selector) *AuthMethods {
 	authMethods := &AuthMethods{
-		List:     make(list),
-		Selector: DefaultSelector,
+		List:            make(list),
+		ContextSelector: DefaultContextSelector,
 		ExistingTypes: map[Type]bool{
 			Password: false,
 			Social:   false,
@@ -111,15 +154,25 @@ func New(selector Selector) *AuthMethods {
 // Add new AuthType in AuthTypes list
 func (a *AuthMethods) Add(cfg AuthMethod) *AuthMethods {
 	if a == nil {
-		log.Fatal("auth types is nil")
+		panic("auth types is nil")
 	}
 
 	if _, ok := a.ExistingTypes[cfg.Type]; !ok {
-		log.Fatalf("invalid auth type %v for '%s' key", cfg.Type, cfg.Key)
+		a.errs = append(a.errs, MethodError{Key: cfg.Key, Err: ErrInvalidType})
+		return a
+	}
+
+	if _, ok := a.List[cfg.Key]; ok {
+		a.errs = append(a.errs, MethodError{Key: cfg.Key, Err: ErrDuplicateKey})
 	}
 
 	a.ExistingTypes[cfg.Type] = true
 
+	defaultRequests := cfg.Type != Social && cfg.SignUpRequest == nil && cfg.SignInRequest == nil
+	if defaultRequests && cfg.UIDPipeline == nil && !cfg.DisableDefaultUIDPipeline {
+		cfg.UIDPipeline = uid.EmailPipeline
+	}
+
 	if cfg.SignUpRequest == nil {
 		cfg.SignUpRequest = &SignUpRequestByEmail{}
 	}
@@ -140,11 +193,50 @@ func (a *AuthMethods) Add(cfg AuthMethod) *AuthMethods {
 		cfg.CodeGenerator = code.Numeric
 	}
 
+	if cfg.CodeVerifier == nil {
+		cfg.CodeVerifier = code.Equal
+	}
+
 	a.List[cfg.Key] = cfg
 
 	return a
 }
 
+// GetCodeLength returns code length for event or 0 if length is not specified in auth method
+func (am *AuthMethod) GetCodeLength(event sender.Event) int {
+	if length, ok := am.CodeLengths[event]; ok && length > 0 {
+		return length
+	}
+
+	return am.CodeLength
+}
+
+// VerifyCode checks received code with auth method verifier. Empty expected code never matches
+func (am *AuthMethod) VerifyCode(expected, actual string) bool {
+	if expected == "" {
+		return false
+	}
+
+	if am.CodeVerifier == nil {
+		return code.Equal(expected, actual)
+	}
+
+	return am.CodeVerifier(expected, actual)
+}
+
+// Errors returns problems of added auth methods: invalid types and duplicate keys
+func (a *AuthMethods) Errors() []MethodError {
+	return a.errs
+}
+
+func (e MethodError) Error() string {
+	return "'" + e.Key + "': " + e.Err.Error()
+}
+
+func (e MethodError) Unwrap() error {
+	return e.Err
+}
+
 func (a *AuthMethods) IsEmpty() bool {
 	return len(a.List) == 0
 }
@@ -153,16 +245,21 @@ func (a *AuthMethods) IsEmpty() bool {
 //
 // if selector returned the empty key and in auth list only one method - use first method as default and return it
 // if selector returned the empty key and in auth list only one method of t Type - return this method
-func (a *AuthMethods) Select(c *gin.Context, t Type) *AuthMethod {
+func (a *AuthMethods) Select(c transport.Context, t Type) *AuthMethod {
 	if a == nil {
 		log.Fatal("AuthMethods is nil")
 	}
 
-	if a.Selector == nil {
-		a.Selector = DefaultSelector
-	}
+	var key string
 
-	key := a.Selector(c, t)
+	switch {
+	case a.Selector != nil:
+		key = a.Selector(gintransport.GinContext(c), t)
+	case a.ContextSelector != nil:
+		key = a.ContextSelector(c, t)
+	default:
+		key = DefaultContextSelector(c, t)
+	}
 
 	var foundedAuthMethod *AuthMethod
 
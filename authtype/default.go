@@ -1,10 +1,9 @@
 package authtype
 
 import (
-	"log"
-
 	"github.com/gin-gonic/gin"
-	"github.com/gin-gonic/gin/binding"
+	"github.com/rosberry/rauther/transport"
+	"github.com/rosberry/rauther/transport/gintransport"
 )
 
 type SignUpRequestByEmail struct {
@@ -13,7 +12,7 @@ type SignUpRequestByEmail struct {
 	ConfirmMerge bool   `json:"confirmMerge" form:"confirmMerge"`
 }
 
-func (r SignUpRequestByEmail) GetUID() (uid string)           { return r.Email } // trim spaces, toLower
+func (r SignUpRequestByEmail) GetUID() (uid string)           { return r.Email }
 func (r SignUpRequestByEmail) GetPassword() (password string) { return r.Password }
 func (r SignUpRequestByEmail) GetConfirmMerge() bool          { return r.ConfirmMerge }
 
@@ -23,7 +22,13 @@ type CheckLoginFieldRequestByEmail struct {
 
 func (r CheckLoginFieldRequestByEmail) GetUID() (uid string) { return r.Email }
 
+// DefaultSelector selects auth method by "type" field of JSON body
 func DefaultSelector(c *gin.Context, t Type) string {
+	return DefaultContextSelector(gintransport.Wrap(c), t)
+}
+
+// DefaultContextSelector selects auth method by "type" field of JSON body
+func DefaultContextSelector(c transport.Context, t Type) string {
 	const defaultKey = ""
 
 	type Request struct {
@@ -31,8 +36,8 @@ func DefaultSelector(c *gin.Context, t Type) string {
 	}
 
 	var r Request
-	if err := c.ShouldBindBodyWith(&r, binding.JSON); err != nil {
-		log.Print("[DefaultSelector] bind err:", err)
+	// bind error is returned by handler
+	if err := c.BindJSON(&r); err != nil {
 		return defaultKey
 	}
 
@@ -10,6 +10,7 @@ type Checker struct {
 	Guest              bool
 	Confirmable        bool
 	Recoverable        bool
+	UIDChangeable      bool
 	CodeSentTime       bool
 	OTPAuth            bool
 	LinkAccount        bool
@@ -52,6 +53,12 @@ func (c *Checker) IsRecoverableUser(u user.User) (ok bool) {
 	return
 }
 
+// IsUIDChangeableUser checks if user implements UIDChangeableUser interface
+func (c *Checker) IsUIDChangeableUser(u user.User) (ok bool) {
+	_, ok = u.(user.UIDChangeableUser)
+	return
+}
+
 func (c *Checker) IsCodeSentTimeUser(u user.User) (ok bool) {
 	_, ok = u.(user.CodeSentTimeUser)
 	return
@@ -83,6 +90,7 @@ func (c *Checker) checkAllInterfaces(u user.User) {
 	c.Guest = c.IsGuest(u)
 	c.Confirmable = c.IsConfirmableUser(u)
 	c.Recoverable = c.IsRecoverableUser(u)
+	c.UIDChangeable = c.IsUIDChangeableUser(u)
 	c.CodeSentTime = c.IsCodeSentTimeUser(u)
 	c.OTPAuth = c.IsOTPAuth(u)
 	c.LinkAccount = c.IsLinkAccount(u)
@@ -0,0 +1,186 @@
+package code
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalidAlphabet is returned by Build for empty alphabet, alphabet with non-ASCII or repeated symbols
+var ErrInvalidAlphabet = errors.New("invalid code alphabet")
+
+// Builder configures code Generator and matching Verifier
+//
+//	b := code.NewBuilder().Alphabet(code.AlphabetCrockford).CaseInsensitive().Group(4, "-")
+//	authMethod.CodeGenerator = b.Generator() // "7KQ2-M9XD"
+//	authMethod.CodeVerifier = b.Verifier()   // accepts "7kq2m9xd"
+//
+// Use Build to check alphabet set by Alphabet.
+type Builder struct {
+	alphabet        string
+	groupSize       int
+	separator       string
+	caseInsensitive bool
+	aliases         map[rune]rune
+}
+
+// NewBuilder returns builder for numeric codes
+func NewBuilder() *Builder {
+	return &Builder{
+		alphabet: AlphabetNumeric,
+		aliases:  map[rune]rune{},
+	}
+}
+
+// Crockford returns builder for case insensitive Crockford's base32 codes.
+// Verifier treats O as 0 and I, L as 1.
+func Crockford() *Builder {
+	return NewBuilder().
+		Alphabet(AlphabetCrockford).
+		CaseInsensitive().
+		Alias('O', '0').
+		Alias('I', '1').
+		Alias('L', '1')
+}
+
+// Alphabet sets symbols used in codes: non-empty string of unique ASCII symbols
+func (b *Builder) Alphabet(alphabet string) *Builder {
+	b.alphabet = alphabet
+	return b
+}
+
+// Group splits code to groups of size symbols joined with separator for better readability.
+// Separators are not counted in code length and ignored by Verifier.
+func (b *Builder) Group(size int, separator string) *Builder {
+	b.groupSize = size
+	b.separator = separator
+
+	return b
+}
+
+// CaseInsensitive makes Verifier ignore case of letters
+func (b *Builder) CaseInsensitive() *Builder {
+	b.caseInsensitive = true
+	return b
+}
+
+// Alias makes Verifier treat symbol from as symbol to, e.g. 'O' as '0'
+func (b *Builder) Alias(from, to rune) *Builder {
+	b.aliases[from] = to
+	return b
+}
+
+// Build returns code generator and verifier or ErrInvalidAlphabet
+func (b *Builder) Build() (Generator, Verifier, error) {
+	if err := validateAlphabet(b.alphabet); err != nil {
+		return nil, nil, err
+	}
+
+	return b.Generator(), b.Verifier(), nil
+}
+
+// Generator returns code generator. It panics if alphabet is invalid, use Build to get error instead
+func (b *Builder) Generator() Generator {
+	if err := validateAlphabet(b.alphabet); err != nil {
+		panic(err)
+	}
+
+	alphabet := b.alphabet
+	groupSize, separator := b.groupSize, b.separator
+
+	return func(length int) string {
+		code := stringWithCharset(length, alphabet)
+
+		if groupSize <= 0 || separator == "" {
+			return code
+		}
+
+		sb := strings.Builder{}
+
+		for i := 0; i < len(code); i += groupSize {
+			if i > 0 {
+				sb.WriteString(separator)
+			}
+
+			end := i + groupSize
+			if end > len(code) {
+				end = len(code)
+			}
+
+			sb.WriteString(code[i:end])
+		}
+
+		return sb.String()
+	}
+}
+
+// Verifier returns code verifier, that normalizes both codes before comparison
+func (b *Builder) Verifier() Verifier {
+	normalize := b.normalizer()
+
+	return func(expected, actual string) bool {
+		return Equal(normalize(expected), normalize(actual))
+	}
+}
+
+func (b *Builder) normalizer() func(string) string {
+	separator := b.separator
+	caseInsensitive := b.caseInsensitive
+
+	aliases := make(map[rune]rune, len(b.aliases))
+	for from, to := range b.aliases {
+		if caseInsensitive {
+			from = unicode.ToUpper(from)
+		}
+
+		aliases[from] = to
+	}
+
+	return func(code string) string {
+		code = strings.TrimSpace(code)
+
+		if separator != "" {
+			code = strings.ReplaceAll(code, separator, "")
+		}
+
+		if caseInsensitive {
+			code = strings.ToUpper(code)
+		}
+
+		return strings.Map(func(r rune) rune {
+			if to, ok := aliases[r]; ok {
+				return to
+			}
+
+			return r
+		}, code)
+	}
+}
+
+// validateAlphabet checks that symbols of alphabet can be indexed by random bytes without bias.
+// Unique ASCII symbols also limit alphabet to 128 symbols, less than 256 values of byte
+func validateAlphabet(alphabet string) error {
+	if alphabet == "" {
+		return fmt.Errorf("%w: alphabet is empty", ErrInvalidAlphabet)
+	}
+
+	seen := make(map[byte]bool, len(alphabet))
+
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+
+		if c >= utf8.RuneSelf {
+			return fmt.Errorf("%w: non-ASCII symbol at %d", ErrInvalidAlphabet, i)
+		}
+
+		if seen[c] {
+			return fmt.Errorf("%w: repeated symbol %q", ErrInvalidAlphabet, c)
+		}
+
+		seen[c] = true
+	}
+
+	return nil
+}
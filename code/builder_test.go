@@ -0,0 +1,75 @@
+package code
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuilderBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		alphabet string
+		wantErr  bool
+	}{
+		{name: "numeric", alphabet: AlphabetNumeric},
+		{name: "crockford", alphabet: AlphabetCrockford},
+		{name: "empty", alphabet: "", wantErr: true},
+		{name: "multi-byte", alphabet: "абвгд", wantErr: true},
+		{name: "repeated", alphabet: "0123456789abc0", wantErr: true},
+		{name: "longer than 256 bytes", alphabet: strings.Repeat(AlphabetAlphanumeric, 5), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		generate, verify, err := NewBuilder().Alphabet(tt.alphabet).Group(3, "-").Build()
+
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAlphabet) {
+				t.Errorf("%s: Build() error = %v, want ErrInvalidAlphabet", tt.name, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: Build() error = %v", tt.name, err)
+		}
+
+		code := generate(8)
+		if len(code) != 10 {
+			t.Errorf("%s: code %q length = %d, want 10", tt.name, code, len(code))
+		}
+
+		for _, r := range strings.ReplaceAll(code, "-", "") {
+			if !strings.ContainsRune(tt.alphabet, r) {
+				t.Errorf("%s: code %q has symbol %q out of alphabet", tt.name, code, r)
+			}
+		}
+
+		if !verify(code, strings.ReplaceAll(code, "-", "")) {
+			t.Errorf("%s: verifier rejects code %q without separators", tt.name, code)
+		}
+	}
+}
+
+func TestBuilderGeneratorPanicsOnInvalidAlphabet(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Generator() does not panic for invalid alphabet")
+		}
+	}()
+
+	NewBuilder().Alphabet("").Generator()
+}
+
+func TestCrockfordVerifier(t *testing.T) {
+	verify := Crockford().Verifier()
+
+	if !verify("01AB", " oiab ") {
+		t.Error("verifier does not apply aliases and case insensitivity")
+	}
+
+	if verify("01AB", "01AC") {
+		t.Error("verifier accepts different code")
+	}
+}
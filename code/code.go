@@ -2,11 +2,27 @@ package code
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 
 	"github.com/google/uuid"
 )
 
-type Generator func(length int) string
+type (
+	Generator func(length int) string
+
+	// Verifier checks that code from request (actual) matches stored code (expected)
+	Verifier func(expected, actual string) bool
+)
+
+const (
+	// AlphabetNumeric contains digits
+	AlphabetNumeric = "0123456789"
+	// AlphabetAlphanumeric contains digits and latin letters in both cases
+	AlphabetAlphanumeric = "abcdefghijklmnopqrstuvwxyz" +
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// AlphabetCrockford is Crockford's base32 alphabet without ambiguous I, L, O and U
+	AlphabetCrockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
 
 // UUID code
 // length not used
@@ -17,25 +33,52 @@ func UUID(length int) (code string) {
 // String code
 // from charset: "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 func String(length int) string {
-	return stringWithCharset(length, charset)
+	return stringWithCharset(length, AlphabetAlphanumeric)
 }
 
 // Numeric code
 func Numeric(length int) string {
-	return stringWithCharset(length, charsetNum)
+	return stringWithCharset(length, AlphabetNumeric)
 }
 
-const charset = "abcdefghijklmnopqrstuvwxyz" +
-	"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-const charsetNum = "0123456789"
+// Equal is default Verifier - exact match in constant time
+func Equal(expected, actual string) bool {
+	if expected == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) == 1
+}
 
+// stringWithCharset returns random string with uniform distribution of charset symbols.
+// Random bytes out of the largest multiple of len(charset) are rejected to avoid modulo bias.
+// Charset is ASCII string not longer than 256 symbols, see validateAlphabet
 func stringWithCharset(length int, charset string) string {
-	b := make([]byte, length)
-	rand.Read(b) // nolint
+	const byteValues = 256
+
+	if length <= 0 || len(charset) == 0 || len(charset) > byteValues {
+		return ""
+	}
+
+	limit := byteValues - byteValues%len(charset)
+	result := make([]byte, 0, length)
+	buf := make([]byte, length)
+
+	for len(result) < length {
+		rand.Read(buf) // nolint
+
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+
+			result = append(result, charset[int(b)%len(charset)])
 
-	for i := 0; i < len(b); i++ {
-		b[i] = charset[int(b[i])%len(charset)]
+			if len(result) == length {
+				break
+			}
+		}
 	}
 
-	return string(b)
+	return string(result)
 }
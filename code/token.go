@@ -0,0 +1,117 @@
+package code
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned by ParseToken for malformed token
+var ErrInvalidToken = errors.New("invalid token")
+
+const tokenSeparator = "\x00"
+
+// SignToken returns URL safe token of auth key and UID for links sent instead of codes.
+// Token is signed with current code of user: it is not valid after code is changed or cleared.
+// Purpose separates tokens of different links, e.g. "confirm" and "reset"
+//
+//	token := code.SignToken(secret, "confirm", "email", "user@example.com", confirmCode)
+//	authKey, uid, err := code.ParseToken(token)
+//	// load user by authKey and uid
+//	ok := code.VerifyToken(secret, "confirm", token, u.GetConfirmCode(authKey))
+func SignToken(secret, purpose, authKey, uid, code string) string {
+	return signToken(secret, purpose, authKey+tokenSeparator+uid, code)
+}
+
+// SignTokenUntil returns token like SignToken that is not valid after expiration time
+func SignTokenUntil(secret, purpose, authKey, uid, code string, expires time.Time) string {
+	data := authKey + tokenSeparator + uid + tokenSeparator + strconv.FormatInt(expires.Unix(), 10)
+
+	return signToken(secret, purpose, data, code)
+}
+
+func signToken(secret, purpose, data, code string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(data))
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(tokenSignature(secret, purpose, payload, code))
+}
+
+// ParseToken returns auth key and UID of token without checking signature
+func ParseToken(token string) (authKey, uid string, err error) {
+	authKey, uid, _, err = parseToken(token)
+	return authKey, uid, err
+}
+
+// VerifyToken checks signature of token with current code of user and expiration time of token.
+// Empty code never matches
+func VerifyToken(secret, purpose, token, code string) bool {
+	if secret == "" || code == "" {
+		return false
+	}
+
+	_, _, expires, err := parseToken(token)
+	if err != nil || (!expires.IsZero() && time.Now().After(expires)) {
+		return false
+	}
+
+	payload, signature, ok := splitToken(token)
+	if !ok {
+		return false
+	}
+
+	actual, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(tokenSignature(secret, purpose, payload, code), actual)
+}
+
+// parseToken returns fields of payload, expiration time is zero for tokens of SignToken
+func parseToken(token string) (authKey, uid string, expires time.Time, err error) {
+	payload, _, ok := splitToken(token)
+	if !ok {
+		return "", "", expires, ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", expires, ErrInvalidToken
+	}
+
+	parts := strings.Split(string(raw), tokenSeparator)
+	if len(parts) < 2 || len(parts) > 3 || parts[1] == "" { // nolint:gomnd
+		return "", "", expires, ErrInvalidToken
+	}
+
+	if len(parts) == 3 { // nolint:gomnd
+		sec, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return "", "", expires, ErrInvalidToken
+		}
+
+		expires = time.Unix(sec, 0)
+	}
+
+	return parts[0], parts[1], expires, nil
+}
+
+func splitToken(token string) (payload, signature string, ok bool) {
+	i := strings.LastIndexByte(token, '.')
+	if i <= 0 || i == len(token)-1 {
+		return "", "", false
+	}
+
+	return token[:i], token[i+1:], true
+}
+
+func tokenSignature(secret, purpose, payload, code string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose + tokenSeparator + payload + tokenSeparator + code)) // nolint:errcheck
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,98 @@
+package code
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseToken(t *testing.T) {
+	for _, token := range []string{
+		SignToken("secret", "confirm", "email", "user@example.com", "123456"),
+		SignTokenUntil("secret", "confirm", "email", "user@example.com", "123456", time.Now().Add(time.Hour)),
+	} {
+		authKey, uid, err := ParseToken(token)
+		if err != nil {
+			t.Fatalf("ParseToken(%q) error = %v", token, err)
+		}
+
+		if authKey != "email" || uid != "user@example.com" {
+			t.Errorf("ParseToken(%q) = %q, %q, want email, user@example.com", token, authKey, uid)
+		}
+	}
+}
+
+func TestParseTokenMalformed(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := map[string]string{
+		"empty":           "",
+		"no signature":    encode("email\x00uid"),
+		"empty signature": encode("email\x00uid") + ".",
+		"empty payload":   ".c2ln",
+		"invalid base64":  "!!!.c2ln",
+		"no uid":          encode("email") + ".c2ln",
+		"empty uid":       encode("email\x00") + ".c2ln",
+		"extra fields":    encode("email\x00uid\x001\x00x") + ".c2ln",
+		"invalid expires": encode("email\x00uid\x00soon") + ".c2ln",
+	}
+
+	for name, token := range tests {
+		if _, _, err := ParseToken(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: ParseToken() error = %v, want ErrInvalidToken", name, err)
+		}
+
+		if VerifyToken("secret", "confirm", token, "123456") {
+			t.Errorf("%s: VerifyToken() = true", name)
+		}
+	}
+}
+
+func TestVerifyToken(t *testing.T) {
+	const (
+		secret = "secret"
+		code   = "123456"
+	)
+
+	token := SignToken(secret, "confirm", "email", "user@example.com", code)
+	valid := SignTokenUntil(secret, "confirm", "email", "user@example.com", code, time.Now().Add(time.Hour))
+	expired := SignTokenUntil(secret, "confirm", "email", "user@example.com", code, time.Now().Add(-time.Second))
+
+	payload, signature, _ := splitToken(token)
+	otherPayload := base64.RawURLEncoding.EncodeToString([]byte("email\x00other@example.com"))
+
+	// signature with other first character
+	first := "A"
+	if strings.HasPrefix(signature, first) {
+		first = "B"
+	}
+
+	tests := []struct {
+		name    string
+		secret  string
+		purpose string
+		token   string
+		code    string
+		want    bool
+	}{
+		{name: "valid", secret: secret, purpose: "confirm", token: token, code: code, want: true},
+		{name: "valid with expiration", secret: secret, purpose: "confirm", token: valid, code: code, want: true},
+		{name: "expired", secret: secret, purpose: "confirm", token: expired, code: code},
+		{name: "wrong purpose", secret: secret, purpose: "reset", token: token, code: code},
+		{name: "wrong secret", secret: "other", purpose: "confirm", token: token, code: code},
+		{name: "empty secret", secret: "", purpose: "confirm", token: token, code: code},
+		{name: "previous code", secret: secret, purpose: "confirm", token: token, code: "654321"},
+		{name: "empty code", secret: secret, purpose: "confirm", token: token, code: ""},
+		{name: "tampered payload", secret: secret, purpose: "confirm", token: otherPayload + "." + signature, code: code},
+		{name: "tampered signature", secret: secret, purpose: "confirm", token: payload + "." + first + signature[1:], code: code}, // nolint:lll
+		{name: "invalid signature encoding", secret: secret, purpose: "confirm", token: payload + ".!!!", code: code},
+	}
+
+	for _, tt := range tests {
+		if got := VerifyToken(tt.secret, tt.purpose, tt.token, tt.code); got != tt.want {
+			t.Errorf("%s: VerifyToken() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -56,6 +56,10 @@ const (
 	ErrMergeWarning
 	ErrLinkingNotAllowed
 	ErrCannotMergeSelf
+	ErrForbidden
+	ErrInvalidCredentials
+	ErrInvalidUID
+	ErrUIDNotChanged
 )
 
 var Errors = map[ErrTypes]Err{
@@ -74,15 +78,15 @@ var Errors = map[ErrTypes]Err{
 	ErrUserSave:                         {"failed_save_user", "Failed save user"},
 	ErrUnknownError:                     {"unknown_error", "Unknown server error"},
 	ErrNotConfirmed:                     {"email_not_confirmed", "Email not confirmed"},
-	ErrInvalidConfirmCode:               {"invalid_code", "Invalid confirm code"},
-	ErrGinDependency:                    {"gin_dependency_nil", "Nil gin dependency"},
+	ErrInvalidConfirmCode:               {"invalid_confirm_code", "Invalid confirm code"},
+	ErrGinDependency:                    {"gin_dependency_nil", "Nil router dependency"},
 	ErrSessionStorerDependency:          {"session_storer_nil", "Nil SessionStorer dependency"},
 	ErrAuthableUserNotImplement:         {"authable_user_not_implement", "Please implement AuthableUser interface"},
 	ErrPasswordAuthableUserNotImplement: {"password_authable_user_not_implement", "Please implement PasswordAuthableUser interface"}, // nolint:lll
 	ErrConfirmableUserNotImplement:      {"confirmable_user_not_implement", "Please implement ConfirmableUser interface"},
 	ErrSenderRequired:                   {"sender_required", "At least one sender is required"},
 	ErrRecoverableUserNotImplement:      {"recoverable_user_not_implement", "Please implement RecoverableUser interface"},
-	ErrInvalidRecoveryCode:              {"invalid_code", "Invalid recovery code"},
+	ErrInvalidRecoveryCode:              {"invalid_recovery_code", "Invalid recovery code"},
 	ErrAlreadyAuth:                      {"already_auth", "User already authorised"},
 	ErrRequestCodeTimeout:               {"code_timeout", "Cannot request code, please wait and try later"},
 	ErrInvalidAuthToken:                 {"invalid_auth_token", "invalid auth token"},
@@ -93,4 +97,8 @@ var Errors = map[ErrTypes]Err{
 	ErrMergeWarning:                     {"merge_warning", "Users will be merged"},
 	ErrLinkingNotAllowed:                {"linking_not_allowed", "Linking not allowed for this auth method"},
 	ErrCannotMergeSelf:                  {"cannot_merge_self", "Cannot merge self"},
+	ErrForbidden:                        {"forbidden", "Action is forbidden"},
+	ErrInvalidCredentials:               {"invalid_credentials", "Invalid UID or password"},
+	ErrInvalidUID:                       {"invalid_uid", "Invalid UID"},
+	ErrUIDNotChanged:                    {"uid_not_changed", "New UID is equal to current UID"},
 }
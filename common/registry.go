@@ -0,0 +1,100 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrCustom is first type of errors registered by RegisterError
+const ErrCustom ErrTypes = 1000
+
+// Statuses are default HTTP statuses of error types (see rauther.NewError). Default: 400
+var Statuses = map[ErrTypes]int{
+	ErrSessionLoad:        http.StatusInternalServerError,
+	ErrSessionSave:        http.StatusInternalServerError,
+	ErrUserLoad:           http.StatusInternalServerError,
+	ErrUserSave:           http.StatusInternalServerError,
+	ErrUnknownError:       http.StatusInternalServerError,
+	ErrNotAuth:            http.StatusUnauthorized,
+	ErrNotSignIn:          http.StatusUnauthorized,
+	ErrAuthFailed:         http.StatusUnauthorized,
+	ErrNotConfirmed:       http.StatusUnauthorized,
+	ErrIncorrectPassword:  http.StatusForbidden,
+	ErrForbidden:          http.StatusForbidden,
+	ErrInvalidCredentials: http.StatusForbidden,
+	ErrRequestCodeTimeout: http.StatusTooManyRequests,
+	ErrMergeWarning:       http.StatusConflict,
+}
+
+var (
+	registryMu sync.Mutex
+	nextCustom = ErrCustom
+)
+
+// RegisterError adds error of application to Errors and returns its type. Register errors before rauther.InitHandlers:
+//
+//	var ErrDomainNotAllowed = common.RegisterError("domain_not_allowed", "Domain is not allowed", http.StatusForbidden)
+func RegisterError(code, message string, status int) ErrTypes {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	t := nextCustom
+	nextCustom++
+
+	Errors[t] = Err{Code: code, Message: message}
+	Statuses[t] = status
+
+	return t
+}
+
+// Status returns default HTTP status of error type
+func Status(t ErrTypes) int {
+	if status, ok := Statuses[t]; ok {
+		return status
+	}
+
+	return http.StatusBadRequest
+}
+
+// ErrorByCode returns type of error with code
+func ErrorByCode(code string) (ErrTypes, bool) {
+	for t, e := range Errors {
+		if e.Code == code {
+			return t, true
+		}
+	}
+
+	return 0, false
+}
+
+// ValidateErrors checks that codes of Errors are not empty and unique
+func ValidateErrors() error {
+	types := map[string][]int{}
+
+	for t, e := range Errors {
+		types[e.Code] = append(types[e.Code], int(t))
+	}
+
+	if t, ok := types[""]; ok {
+		return fmt.Errorf("error types %v: empty code", t)
+	}
+
+	var duplicates []string
+
+	for code, t := range types {
+		if len(t) > 1 {
+			sort.Ints(t)
+			duplicates = append(duplicates, fmt.Sprintf("%q (types %v)", code, t))
+		}
+	}
+
+	if len(duplicates) > 0 {
+		sort.Strings(duplicates)
+		return fmt.Errorf("duplicate error codes: %s", strings.Join(duplicates, ", "))
+	}
+
+	return nil
+}
@@ -1,6 +1,9 @@
 package config
 
-import "time"
+import (
+	"net/http"
+	"time"
+)
 
 // Config contain all configurations for Rauther and modules
 type Config struct {
@@ -30,6 +33,10 @@ type Config struct {
 		// ConfirmResend is gin route path for request resend confirm code. Default: "confirm/resend"
 		ConfirmResend string
 
+		// ConfirmLink is route path (GET) for confirmation by signed link. Default: "auth/confirm/:token".
+		// Token is read from last path segment if router has no path parameters, e.g. "auth/confirm/" for http.ServeMux
+		ConfirmLink string
+
 		// RecoveryRequest is gin route path for request send a password recovery code. Default: "recovery/request"
 		RecoveryRequest string
 
@@ -39,6 +46,13 @@ type Config struct {
 		// RecoveryCode is gin route path for confirm the password recovery and set a new password. Default "recovery"
 		RecoveryCode string
 
+		// RecoveryLink is route path (GET) of password reset link. Default: "recover/link/:token".
+		// Token is read from last path segment if router has no path parameters, e.g. "recover/link/" for http.ServeMux
+		RecoveryLink string
+
+		// RecoveryLinkReset is route path for setting new password with reset token of link. Default: "recover/link"
+		RecoveryLinkReset string
+
 		// OTPRequestCode is gin route path for One Time Password auth - code request (sign-up)
 		OTPRequestCode string
 
@@ -50,6 +64,23 @@ type Config struct {
 
 		// Link is gin route path for linking password account
 		Link string
+
+		// ChangeUID is route path for request of UID change (password and OTP auth methods). Default: "auth/uid/change"
+		ChangeUID string
+
+		// ConfirmUIDChange is route path for confirmation of UID change with code. Default: "auth/uid/confirm"
+		ConfirmUIDChange string
+
+		// OpenAPI is route path for OpenAPI document (GET). Default: "" - document is not served
+		OpenAPI string
+	}
+
+	// OpenAPI is info of generated OpenAPI document
+	OpenAPI struct {
+		// Title of API. Default: "Rauther API"
+		Title string
+		// Version of API. Default: "1.0.0"
+		Version string
 	}
 
 	// Context Names is group for setup how save data in context
@@ -67,6 +98,63 @@ type Config struct {
 	// CodeLength is default code length for all auth methods (if not specified in auth method)
 	CodeLength int
 
+	// FatalValidation makes Init and InitHandlers call log.Fatal on invalid configuration instead of returning error. Default: false
+	FatalValidation bool
+
+	// LogCodes enables writing of sent confirmation/recovery/OTP codes to log.
+	// Use it only for development. Default: false
+	LogCodes bool
+
+	// UserEnumeration protects from discovering of registered accounts by responses of handlers
+	UserEnumeration struct {
+		// Protect makes sign-in, OTP sign-in and password recovery respond the same way for existing and not existing users.
+		// Default: false
+		Protect bool
+		// DisableCheck removes ValidateLoginField route in protection mode. Default: false - route is rate limited
+		DisableCheck bool
+		// CheckLimit is count of ValidateLoginField requests of client per CheckWindow in protection mode.
+		// Default: 10 per minute
+		CheckLimit  int
+		CheckWindow time.Duration
+		// CheckKey returns client key of CheckLimit counter for HTTP requests. Default: host of request RemoteAddr.
+		// Behind proxy or load balancer all clients share RemoteAddr of proxy and one counter: set CheckKey to read
+		// client IP from header set by trusted proxy (e.g. X-Real-IP) or restore RemoteAddr by middleware.
+		// Go API and gRPC use IP of rauther.ContextWithClient (peer address for gRPC)
+		CheckKey func(req *http.Request) string
+		// MaxPendingRecoveries is count of recovery requests processed after response at the same time in protection mode.
+		// Requests over limit are dropped and logged. Default: 100
+		MaxPendingRecoveries int
+	}
+
+	// ConfirmLink configures confirmation by signed link for auth methods with ConfirmLink
+	ConfirmLink struct {
+		// Secret is key of link signatures. Required
+		Secret string
+		// URL is public URL of ConfirmLink route, token is appended: "https://api.example.com/auth/confirm/". Required
+		URL string
+		// SuccessURL is redirect location after confirmation. Default: "" - JSON response
+		SuccessURL string
+		// FailureURL is redirect location if link is invalid or expired, error code is added as "error" query parameter.
+		// Default: "" - JSON error response
+		FailureURL string
+	}
+
+	// ResetLink configures password reset by signed link for auth methods with ResetLink
+	ResetLink struct {
+		// Secret is key of link and reset token signatures. Required
+		Secret string
+		// URL is public URL of RecoveryLink route, token is appended: "https://api.example.com/recover/link/". Required
+		URL string
+		// RedirectURL is page of new password form, reset token is added as "token" query parameter.
+		// Default: "" - JSON response with token
+		RedirectURL string
+		// FailureURL is redirect location if link is invalid, used or expired, error code is added as "error" query
+		// parameter. Default: "" - JSON error response
+		FailureURL string
+		// TokenLifeTime is lifetime of reset token. Default: 15 minutes
+		TokenLifeTime time.Duration
+	}
+
 	Password struct {
 		CodeLifeTime time.Duration
 		ResendDelay  time.Duration
@@ -93,10 +181,15 @@ func (c *Config) Default() {
 
 	c.Routes.ConfirmCode = "confirm"
 	c.Routes.ConfirmResend = "confirm/resend"
+	c.Routes.ConfirmLink = "auth/confirm/:token"
 
 	c.Routes.RecoveryRequest = "recover"
 	c.Routes.RecoveryValidateCode = "recover/validate"
 	c.Routes.RecoveryCode = "recover/reset"
+	c.Routes.RecoveryLink = "recover/link/:token"
+	c.Routes.RecoveryLinkReset = "recover/link"
+
+	c.ResetLink.TokenLifeTime = time.Minute * 15 // nolint:gomnd
 
 	c.Password.CodeLifeTime = time.Minute * 30 // nolint:gomnd
 	c.Password.ResendDelay = time.Minute * 2   // nolint:gomnd
@@ -111,4 +204,14 @@ func (c *Config) Default() {
 
 	c.Routes.InitLink = "initLink"
 	c.Routes.Link = "link"
+
+	c.Routes.ChangeUID = "auth/uid/change"
+	c.Routes.ConfirmUIDChange = "auth/uid/confirm"
+
+	c.UserEnumeration.CheckLimit = 10
+	c.UserEnumeration.CheckWindow = time.Minute
+	c.UserEnumeration.MaxPendingRecoveries = 100
+
+	c.OpenAPI.Title = "Rauther API"
+	c.OpenAPI.Version = "1.0.0"
 }
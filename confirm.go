@@ -1,66 +1,166 @@
 package rauther
 
 import (
-	"log"
+	"context"
+	"errors"
 	"net/http"
+	"path"
 	"time"
 
-	"github.com/gin-gonic/gin"
-	"github.com/gin-gonic/gin/binding"
+	"github.com/rosberry/rauther/audit"
 	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/code"
 	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/metrics"
+	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/transport"
 	"github.com/rosberry/rauther/user"
 )
 
-func (r *Rauther) confirmHandler(c *gin.Context) {
-	type confirmRequest struct {
-		UID  string `json:"uid" binding:"required"`
-		Code string `json:"code" binding:"required"`
-	}
+type confirmRequest struct {
+	UID  string `json:"uid" binding:"required"`
+	Code string `json:"code" binding:"required"`
+}
 
+func (r *Rauther) confirmHandler(c transport.Context) {
 	at, ok := r.findAuthMethod(c, authtype.Password)
 	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
 
 	var request confirmRequest
 
-	if err := c.ShouldBindBodyWith(&request, binding.JSON); err != nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+	if err := c.BindJSON(&request); err != nil {
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 		return
 	}
 
-	u, err := r.LoadByUID(at.Key, request.UID)
-	if err != nil || u == nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
+	uid, err := r.confirm(requestContext(c), at, request.UID, request.Code)
+	if err != nil {
+		r.flowErrorResponse(c, err)
 		return
 	}
 
-	if u.(user.ConfirmableUser).GetConfirmed(at.Key) {
-		c.JSON(http.StatusOK, gin.H{
-			"result": true,
-		})
+	r.respond(c, Response{Route: RouteConfirm, AuthKey: at.Key, UID: uid})
+}
+
+// confirmLinkPurpose separates tokens of confirmation links from other signed links
+const confirmLinkPurpose = "confirm"
+
+func (r *Rauther) confirmLinkHandler(c transport.Context) {
+	token := c.Param("token")
+	if token == "" {
+		token = path.Base(c.Request().URL.Path)
+	}
+
+	cfg := r.Config.ConfirmLink
+
+	authKey, uid, err := r.confirmLink(requestContext(c), token)
+	if err != nil {
+		if cfg.FailureURL == "" {
+			r.flowErrorResponse(c, err)
+			return
+		}
+
+		r.logFlowError(c, err)
+
+		_, body, _ := ErrorDetails(err)
+		c.Redirect(http.StatusFound, withQuery(cfg.FailureURL, "error", body.Code))
 
 		return
 	}
 
-	code := u.(user.ConfirmableUser).GetConfirmCode(at.Key)
-	if request.Code != code || code == "" {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidConfirmCode)
+	if cfg.SuccessURL == "" {
+		r.respond(c, Response{Route: RouteConfirmLink, AuthKey: authKey, UID: uid})
 		return
 	}
 
-	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
-		codeSent := u.(user.CodeSentTimeUser).GetCodeSentTime(at.Key)
+	c.Redirect(http.StatusFound, cfg.SuccessURL)
+}
 
-		expiredAt := calcExpiredAt(codeSent, r.Config.Password.CodeLifeTime)
+func (r *Rauther) confirm(ctx context.Context, at *authtype.AuthMethod, uid, confirmCode string) (string, error) {
+	return r.confirmUID(ctx, at, uid, false, func(expected string) bool {
+		return at.VerifyCode(expected, confirmCode)
+	})
+}
 
-		if expiredAt.Before(time.Now()) {
-			errorResponse(c, http.StatusBadRequest, common.ErrCodeExpired)
-			return
+// confirmLink confirms UID of signed confirmation link, returns auth key and normalized UID of link
+func (r *Rauther) confirmLink(ctx context.Context, token string) (authKey, uid string, err error) {
+	authKey, uid, err = code.ParseToken(token)
+	if err != nil {
+		return "", "", wrapError(http.StatusBadRequest, common.ErrInvalidConfirmCode, err)
+	}
+
+	at, err := r.authMethodByKey(authKey, authtype.Password, r.Modules.ConfirmableUser)
+	if err != nil || !at.ConfirmLink {
+		return "", "", wrapError(http.StatusBadRequest, common.ErrInvalidConfirmCode, err)
+	}
+
+	uid, err = r.confirmUID(ctx, at, uid, true, func(expected string) bool {
+		return code.VerifyToken(r.Config.ConfirmLink.Secret, confirmLinkPurpose, token, expected)
+	})
+
+	// token is signed with confirmation code of user and can be verified after user is loaded only:
+	// forged tokens get the same error for not existing, not confirmed and confirmed users
+	var e *Error
+	if errors.As(err, &e) && e.Type == common.ErrUserNotFound {
+		err = wrapError(http.StatusBadRequest, common.ErrInvalidConfirmCode, err)
+	}
+
+	return authKey, uid, err
+}
+
+// confirmLinkEnabled returns true if any password auth method sends confirmation links
+func (r *Rauther) confirmLinkEnabled() bool {
+	for _, at := range r.methods.List {
+		if at.Type == authtype.Password && at.ConfirmLink {
+			return true
+		}
+	}
+
+	return false
+}
+
+// confirmUID confirms UID if verify accepts confirmation code of user.
+// Already confirmed UID is accepted without verification unless verifyConfirmed is set
+func (r *Rauther) confirmUID(ctx context.Context, at *authtype.AuthMethod, uid string, verifyConfirmed bool, verify func(expected string) bool) (_ string, err error) { // nolint:lll
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowConfirm, at)
+	defer endFlow(&err)
+
+	if uid, err = r.normalizeUID(at, uid); err != nil {
+		return "", err
+	}
+
+	rec := r.startAudit(ctx, audit.Confirm, at, uid)
+	defer rec.end(&err)
+
+	u, err := r.loadByUID(ctx, at.Key, uid)
+	if err != nil || u == nil {
+		return "", wrapError(http.StatusBadRequest, common.ErrUserNotFound, err)
+	}
+
+	rec.setUser(u)
+
+	confirmed := u.(user.ConfirmableUser).GetConfirmed(at.Key)
+	if confirmed && !verifyConfirmed {
+		return uid, nil
+	}
+
+	if !verify(u.(user.ConfirmableUser).GetConfirmCode(at.Key)) {
+		return "", newError(http.StatusBadRequest, common.ErrInvalidConfirmCode)
+	}
+
+	// repeated click on valid link
+	if confirmed {
+		return uid, nil
+	}
+
+	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
+		if err := r.checkCodeExpired(u, at, r.Config.Password.CodeLifeTime); err != nil {
+			return "", err
 		}
 
 		u.(user.CodeSentTimeUser).SetCodeSentTime(at.Key, nil)
@@ -68,22 +168,19 @@ func (r *Rauther) confirmHandler(c *gin.Context) {
 
 	u.(user.ConfirmableUser).SetConfirmed(at.Key, true)
 
-	err = r.deps.UserStorer.Save(u)
-	if err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
-		return
+	if err = r.users(ctx).Save(u); err != nil {
+		return "", wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"result": true,
-	})
+	r.publish(ctx, events.UserConfirmed{User: u, AuthKey: at.Key, UID: uid})
+
+	return uid, nil
 }
 
-func (r *Rauther) resendCodeHandler(c *gin.Context) {
+func (r *Rauther) resendCodeHandler(c transport.Context) {
 	at, ok := r.findAuthMethod(c, authtype.Password)
 	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
@@ -93,29 +190,34 @@ func (r *Rauther) resendCodeHandler(c *gin.Context) {
 		return
 	}
 
-	if sessionInfo.User == nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+	if err := r.resendConfirmCode(requestContext(c), at, sessionInfo.User); err != nil {
+		r.flowErrorResponse(c, err)
 		return
 	}
 
-	u := sessionInfo.User
+	r.respond(c, Response{Route: RouteResendCode, AuthKey: at.Key, User: sessionInfo.User, Session: sessionInfo.Session})
+}
+
+func (r *Rauther) resendConfirmCode(ctx context.Context, at *authtype.AuthMethod, u user.User) (err error) {
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowResendCode, at)
+	defer endFlow(&err)
+
+	if u == nil {
+		return newError(http.StatusBadRequest, common.ErrInvalidRequest)
+	}
 
 	uid := u.(user.AuthableUser).GetUID(at.Key)
 	if uid == "" {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
-		return
+		return newError(http.StatusBadRequest, common.ErrInvalidRequest)
 	}
 
-	confirmCode := r.generateCode(at)
+	confirmCode := r.generateCode(at, sender.ConfirmationEvent)
 
 	// check resend timeout
 	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
 		curTime := time.Now()
-		if resendTime, ok := r.checkResendTime(u, curTime, at); !ok {
-			resp, code := getCodeTimeoutResponse(*resendTime, curTime)
-			c.JSON(code, resp)
-
-			return
+		if err := r.checkCodeTimeout(u, curTime, at); err != nil {
+			return err
 		}
 
 		u.(user.CodeSentTimeUser).SetCodeSentTime(at.Key, &curTime)
@@ -123,21 +225,26 @@ func (r *Rauther) resendCodeHandler(c *gin.Context) {
 
 	u.(user.ConfirmableUser).SetConfirmCode(at.Key, confirmCode)
 
-	if err := r.deps.UserStorer.Save(u); err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
+	// send before save: previous code stays valid if sending failed
+	if err := r.sendConfirmation(ctx, at, uid, confirmCode, at.ConfirmLink); err != nil {
+		return sendError(err)
+	}
 
-		return
+	if err := r.users(ctx).Save(u); err != nil {
+		return wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 	}
 
-	err := sendConfirmCode(at.Sender, uid, confirmCode)
-	if err != nil {
-		log.Print(err)
-		errorResponse(c, http.StatusInternalServerError, common.ErrUnknownError)
+	return nil
+}
 
-		return
+// sendConfirmation sends signed confirmation link if byLink is set, otherwise confirmation code
+func (r *Rauther) sendConfirmation(ctx context.Context, at *authtype.AuthMethod, uid, confirmCode string, byLink bool) error {
+	if !byLink {
+		return r.sendConfirmCode(ctx, at.Sender, uid, confirmCode)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"result": true,
-	})
+	expires := time.Now().Add(r.Config.Password.CodeLifeTime)
+	token := code.SignTokenUntil(r.Config.ConfirmLink.Secret, confirmLinkPurpose, at.Key, uid, confirmCode, expires)
+
+	return r.sendCode(ctx, at.Sender, sender.ConfirmationLinkEvent, uid, r.Config.ConfirmLink.URL+token)
 }
@@ -0,0 +1,131 @@
+package rauther_test
+
+import (
+	"net/http"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rosberry/rauther"
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/code"
+	"github.com/rosberry/rauther/deps"
+	"github.com/rosberry/rauther/example/basic/models"
+	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/sender/sendertest"
+	"github.com/rosberry/rauther/transport/httptransport"
+)
+
+const confirmLinkSecret = "secret"
+
+func newConfirmLinkServer(t *testing.T, codeLifeTime time.Duration) (*testServer, *sendertest.Recorder) {
+	t.Helper()
+
+	users := &models.UserStorer{Users: map[uint]*models.User{}}
+	sessions := &models.Sessioner{Sessions: map[string]*models.Session{}}
+	mux := http.NewServeMux()
+	rec := sendertest.New()
+
+	r := rauther.New(deps.NewWithRouter(httptransport.New(mux), deps.Storage{SessionStorer: sessions, UserStorer: users}))
+	r.AddAuthMethod(authtype.AuthMethod{Key: "email", Sender: rec, ConfirmLink: true})
+	r.Config.ConfirmLink.Secret = confirmLinkSecret
+	r.Config.ConfirmLink.URL = "https://api.example.com/auth/confirm/"
+	r.Config.Routes.ConfirmLink = "auth/confirm/"
+	r.Config.Password.CodeLifeTime = codeLifeTime
+	r.Config.Password.ResendDelay = 0
+
+	if err := r.InitHandlers(); err != nil {
+		t.Fatal(err)
+	}
+
+	return &testServer{t: t, mux: mux, users: users}, rec
+}
+
+// openConfirmLink opens confirmation link and returns status and error code of response
+func openConfirmLink(s *testServer, link string) (int, string) {
+	s.t.Helper()
+
+	status, resp := s.request(http.MethodGet, "", "/auth/confirm/"+path.Base(link), "")
+
+	errCode := ""
+	if e, ok := resp["error"].(map[string]interface{}); ok {
+		errCode, _ = e["code"].(string)
+	}
+
+	return status, errCode
+}
+
+func lastConfirmLink(t *testing.T, rec *sendertest.Recorder, uid string) string {
+	t.Helper()
+
+	link, ok := rec.LastCode(uid, sender.ConfirmationLinkEvent)
+	if !ok {
+		t.Fatalf("confirmation link is not sent to %s", uid)
+	}
+
+	return link
+}
+
+func TestConfirmLink(t *testing.T) {
+	s, rec := newConfirmLinkServer(t, time.Hour)
+
+	token := s.auth("device1")
+	s.do(token, "/register", `{"email":"user@mail.com","password":"password"}`)
+
+	first := lastConfirmLink(t, rec, "user@mail.com")
+
+	// new link replaces previous one
+	s.do(token, "/confirm/resend", `{"email":"user@mail.com"}`)
+
+	link := lastConfirmLink(t, rec, "user@mail.com")
+
+	if status, errCode := openConfirmLink(s, first); status != http.StatusBadRequest || errCode != "invalid_confirm_code" {
+		t.Errorf("previous link: %d %q, want 400 invalid_confirm_code", status, errCode)
+	}
+
+	if status, errCode := openConfirmLink(s, link); status != http.StatusOK {
+		t.Fatalf("link: %d %q, want 200", status, errCode)
+	}
+
+	for _, u := range s.users.Users {
+		if !u.GetConfirmed("email") {
+			t.Error("user is not confirmed")
+		}
+	}
+
+	// repeated click on valid link
+	if status, errCode := openConfirmLink(s, link); status != http.StatusOK {
+		t.Errorf("replayed link: %d %q, want 200", status, errCode)
+	}
+
+	// forged links of confirmed user are rejected
+	forged := []string{
+		code.SignToken(confirmLinkSecret, "confirm", "email", "user@mail.com", "123456"),
+		code.SignToken("other secret", "confirm", "email", "user@mail.com", "123456"),
+		"invalid",
+	}
+
+	for _, link := range forged {
+		if status, errCode := openConfirmLink(s, link); status != http.StatusBadRequest || errCode != "invalid_confirm_code" {
+			t.Errorf("forged link %q: %d %q, want 400 invalid_confirm_code", link, status, errCode)
+		}
+	}
+}
+
+func TestConfirmLinkExpired(t *testing.T) {
+	s, rec := newConfirmLinkServer(t, -time.Second)
+
+	s.do(s.auth("device1"), "/register", `{"email":"user@mail.com","password":"password"}`)
+
+	link := lastConfirmLink(t, rec, "user@mail.com")
+
+	if status, errCode := openConfirmLink(s, link); status != http.StatusBadRequest || errCode != "invalid_confirm_code" {
+		t.Errorf("expired link: %d %q, want 400 invalid_confirm_code", status, errCode)
+	}
+
+	for _, u := range s.users.Users {
+		if u.GetConfirmed("email") {
+			t.Error("user is confirmed by expired link")
+		}
+	}
+}
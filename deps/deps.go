@@ -2,16 +2,40 @@ package deps
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/rosberry/rauther/audit"
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/logger"
+	"github.com/rosberry/rauther/metrics"
 	"github.com/rosberry/rauther/storage"
+	"github.com/rosberry/rauther/transport"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Deps contain dependencies for Rauther
 type Deps struct {
-	// R is gin RouterGroup
+	// R is gin RouterGroup. Used if Router is nil
 	R *gin.RouterGroup
 
+	// Router registers handlers in any HTTP framework (see transport/* adapters)
+	Router transport.Router
+
 	// Storage is wrapper for User/Session and other storers
 	Storage
+
+	// Logger for library logs. Secrets are redacted (see logger.Redact). Default: logger.Nop
+	Logger logger.Logger
+
+	// Metrics collects results of auth flows and latency (see metrics/prommetrics). Default: metrics.Nop
+	Metrics metrics.Collector
+
+	// TracerProvider enables OpenTelemetry spans of handlers, flows, storers and senders. Default: nil - tracing is disabled
+	TracerProvider trace.TracerProvider
+
+	// AuditSink records security-relevant events (see audit.NewFileSink, audit.NewStorerSink). Default: audit.Nop
+	AuditSink audit.Sink
+
+	// EventBus delivers auth lifecycle events to subscribers (see Rauther.Subscribe). Default: new bus, panics of handlers are logged
+	EventBus *events.Bus
 }
 
 type Storage struct {
@@ -31,3 +55,13 @@ func New(r *gin.RouterGroup, storage Storage) Deps {
 		Storage: storage,
 	}
 }
+
+// NewWithRouter returns dependencies for any HTTP framework, e.g.
+//
+//	deps.NewWithRouter(chitransport.New(chiRouter), storage)
+func NewWithRouter(router transport.Router, storage Storage) Deps {
+	return Deps{
+		Router:  router,
+		Storage: storage,
+	}
+}
@@ -0,0 +1,139 @@
+package rauther
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/logger"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const defaultMaxPendingRecoveries = 100
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// detachedContext keeps values of parent context but is not canceled with it
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// uniformError returns err as error of errType in user enumeration protection mode, err is kept as cause for logs
+func (r *Rauther) uniformError(err error, errType common.ErrTypes) error {
+	if !r.Config.UserEnumeration.Protect {
+		return err
+	}
+
+	return wrapError(common.Status(errType), errType, err)
+}
+
+// dummyPasswordCompare spends time of password check if user is not found in protection mode
+func (r *Rauther) dummyPasswordCompare(ctx context.Context, password string) {
+	if !r.Config.UserEnumeration.Protect {
+		return
+	}
+
+	r.passwordCompare(ctx, password, dummyPasswordHash())
+}
+
+// dummyPasswordHash returns hash of random password with cost of real hashes
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		secret := make([]byte, 16) // nolint:gomnd
+		rand.Read(secret)          // nolint:errcheck
+
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(secret)), bcrypt.DefaultCost)
+	})
+
+	return string(dummyHash)
+}
+
+// allowCheckUID limits UID checks of client in protection mode. Client is IP of ContextWithClient or key of
+// Config.UserEnumeration.CheckKey for HTTP requests
+func (r *Rauther) allowCheckUID(ctx context.Context) error {
+	cfg := r.Config.UserEnumeration
+
+	if !cfg.Protect {
+		return nil
+	}
+
+	if cfg.DisableCheck {
+		return wrapError(http.StatusBadRequest, common.ErrInvalidRequest, errModuleDisabled)
+	}
+
+	if cfg.CheckLimit <= 0 {
+		return nil
+	}
+
+	key := contextClient(ctx).ip
+	if req, ok := ctx.Value(httpRequestContextKey).(*http.Request); ok && cfg.CheckKey != nil {
+		key = cfg.CheckKey(req)
+	}
+
+	count, resetAt, err := r.checkLimits.Incr("check_uid:"+key, cfg.CheckWindow)
+	if err != nil {
+		return wrapError(http.StatusInternalServerError, common.ErrUnknownError, err)
+	}
+
+	if count > cfg.CheckLimit {
+		return codeTimeoutError(resetAt, time.Now())
+	}
+
+	return nil
+}
+
+// startRecovery requests password recovery, in protection mode request runs after response
+func (r *Rauther) startRecovery(ctx context.Context, at *authtype.AuthMethod, uid string) (string, error) {
+	if r.Config.UserEnumeration.Protect {
+		return r.requestRecoveryDetached(ctx, at, uid)
+	}
+
+	return r.requestRecovery(ctx, at, uid)
+}
+
+// requestRecoveryDetached runs recovery request after response in protection mode: response and its time do not depend
+// on existence of user. At most MaxPendingRecoveries requests run at the same time, others are dropped.
+// Only invalid UID is returned as error, errors of request are logged only. Returns normalized UID
+func (r *Rauther) requestRecoveryDetached(ctx context.Context, at *authtype.AuthMethod, uid string) (string, error) {
+	uid, err := r.normalizeUID(at, uid)
+	if err != nil {
+		return "", err
+	}
+
+	select {
+	case r.pendingRecoveries <- struct{}{}:
+	default:
+		r.logger.Log(logger.WarnLevel, "recovery request dropped: too many pending requests", logger.AuthMethod(at.Key))
+		return uid, nil
+	}
+
+	go func() {
+		defer func() { <-r.pendingRecoveries }()
+
+		if _, err := r.requestRecovery(detachedContext{ctx}, at, uid); err != nil {
+			status, body, _ := ErrorDetails(err)
+
+			level := logger.DebugLevel
+			if status >= http.StatusInternalServerError {
+				level = logger.ErrorLevel
+			}
+
+			r.logger.Log(level, "recovery request failed", logger.AuthMethod(at.Key), logger.ErrorCode(body.Code), logger.Err(err))
+		}
+	}()
+
+	return uid, nil
+}
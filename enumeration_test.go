@@ -0,0 +1,65 @@
+package rauther_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rosberry/rauther"
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/deps"
+	"github.com/rosberry/rauther/example/basic/models"
+	"github.com/rosberry/rauther/sender/sendertest"
+	"github.com/rosberry/rauther/transport/httptransport"
+)
+
+func errorCode(resp map[string]interface{}) string {
+	e, _ := resp["error"].(map[string]interface{})
+	code, _ := e["code"].(string)
+
+	return code
+}
+
+func TestUserEnumerationProtection(t *testing.T) {
+	users := &models.UserStorer{Users: map[uint]*models.User{}}
+	sessions := &models.Sessioner{Sessions: map[string]*models.Session{}}
+	mux := http.NewServeMux()
+	rec := sendertest.New()
+
+	r := rauther.New(deps.NewWithRouter(httptransport.New(mux), deps.Storage{SessionStorer: sessions, UserStorer: users}))
+	r.AddAuthMethod(authtype.AuthMethod{Key: "email", Sender: rec})
+	r.Config.UserEnumeration.Protect = true
+	r.Config.UserEnumeration.CheckLimit = 2
+
+	if err := r.InitHandlers(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{t: t, mux: mux, users: users}
+
+	token := s.auth("device1")
+	s.do(token, "/register", `{"email":"user@mail.com","password":"password"}`)
+
+	otherToken := s.auth("device2")
+
+	// recovery responds the same way for existing and not existing users
+	s.do(otherToken, "/recover", `{"uid":"user@mail.com"}`)
+	s.do(otherToken, "/recover", `{"uid":"unknown@mail.com"}`)
+
+	for _, body := range []string{
+		`{"email":"unknown@mail.com","password":"password"}`,
+		`{"email":"user@mail.com","password":"wrong password"}`,
+	} {
+		status, resp := s.request(http.MethodPost, otherToken, "/login", body)
+		if status != http.StatusForbidden || errorCode(resp) != "invalid_credentials" {
+			t.Errorf("login %s: %d %v, want 403 invalid_credentials", body, status, resp)
+		}
+	}
+
+	s.do(otherToken, "/register/check", `{"email":"new@mail.com"}`)
+	s.do(otherToken, "/register/check", `{"email":"other@mail.com"}`)
+
+	status, resp := s.request(http.MethodPost, otherToken, "/register/check", `{"email":"third@mail.com"}`)
+	if status != http.StatusTooManyRequests || errorCode(resp) != "code_timeout" {
+		t.Errorf("check over limit: %d %v, want 429 code_timeout", status, resp)
+	}
+}
@@ -0,0 +1,268 @@
+package rauther
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/logger"
+	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/transport"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Error is typed error of auth flow. Returned by Go API methods (SignUp, Confirm, ...) and rendered by handlers
+type Error struct {
+	// Type is error type from common.Errors
+	Type common.ErrTypes
+	// Status is suggested HTTP status code
+	Status int
+	// Info is additional data, e.g. common.ResendCodeErrInfo for ErrRequestCodeTimeout
+	Info interface{}
+	// Err is cause of error
+	Err error
+}
+
+// NewError returns error of registered type (see common.RegisterError) with default status of type.
+// Return it from hooks or storers to respond with own code
+func NewError(errType common.ErrTypes, cause error) *Error {
+	return wrapError(common.Status(errType), errType, cause)
+}
+
+func newError(status int, errType common.ErrTypes) *Error {
+	return &Error{
+		Type:   errType,
+		Status: status,
+	}
+}
+
+func wrapError(status int, errType common.ErrTypes, cause error) *Error {
+	return &Error{
+		Type:   errType,
+		Status: status,
+		Err:    cause,
+	}
+}
+
+func (e *Error) Error() string {
+	msg := common.Errors[e.Type].Message
+
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Code returns error code, e.g. "user_not_found"
+func (e *Error) Code() string {
+	return common.Errors[e.Type].Code
+}
+
+// IsErrorType checks that err is Error with errType
+func IsErrorType(err error, errType common.ErrTypes) bool {
+	var e *Error
+
+	return errors.As(err, &e) && e.Type == errType
+}
+
+// codeTimeoutError returns ErrRequestCodeTimeout error with time of next allowed request
+func codeTimeoutError(resendTime, curTime time.Time) *Error {
+	return &Error{
+		Type:   common.ErrRequestCodeTimeout,
+		Status: http.StatusTooManyRequests,
+		Info: common.ResendCodeErrInfo{
+			TimeoutSec:      resendTime.Sub(curTime) / time.Second,
+			NextRequestTime: resendTime.Format(time.RFC3339),
+		},
+	}
+}
+
+// sendError converts sender error to flow error
+func sendError(err error) *Error {
+	var throttleErr sender.ThrottleError
+	if errors.As(err, &throttleErr) {
+		e := codeTimeoutError(throttleErr.RetryAt, time.Now())
+		e.Err = err
+
+		return e
+	}
+
+	return wrapError(http.StatusInternalServerError, common.ErrUnknownError, err)
+}
+
+// linkError converts linking errors to flow errors
+func linkError(err error) error {
+	var (
+		customErr CustomError
+		mergeErr  MergeError
+		flowErr   *Error
+	)
+
+	switch {
+	case errors.Is(err, errAuthIdentityExists):
+		return wrapError(http.StatusBadRequest, common.ErrAuthIdentityExists, err)
+	case errors.Is(err, errCurrentUserNotConfirmed):
+		return wrapError(http.StatusBadRequest, common.ErrUserNotConfirmed, err)
+	case errors.Is(err, errUserAlreadyRegistered):
+		return wrapError(http.StatusBadRequest, common.ErrUserExist, err)
+	case errors.Is(err, errCannotMergeSelf):
+		return wrapError(http.StatusBadRequest, common.ErrCannotMergeSelf, err)
+	case errors.As(err, &customErr):
+		return customErr
+	case errors.As(err, &mergeErr):
+		return mergeErr
+	case errors.As(err, &flowErr):
+		return flowErr
+	default:
+		return wrapError(http.StatusBadRequest, common.ErrInvalidRequest, err)
+	}
+}
+
+// loadError returns custom error of storer or nil if error should be ignored
+func (r *Rauther) loadError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	r.logger.Log(logger.DebugLevel, "load user", logger.Err(err))
+
+	var customErr CustomError
+	if errors.As(err, &customErr) {
+		return customErr
+	}
+
+	return nil
+}
+
+// ErrorDetails returns suggested HTTP status, error body and additional info of flow error
+func ErrorDetails(err error) (status int, body common.Err, info interface{}) {
+	var (
+		customErr CustomError
+		mergeErr  MergeError
+		flowErr   *Error
+	)
+
+	switch {
+	case errors.As(err, &customErr):
+		return customErr.Status, customErr.Response, nil
+	case errors.As(err, &mergeErr):
+		return http.StatusConflict, common.Errors[common.ErrMergeWarning], mergeErrorInfo(mergeErr)
+	case errors.As(err, &flowErr):
+		return flowErr.Status, common.Errors[flowErr.Type], flowErr.Info
+	default:
+		return http.StatusInternalServerError, common.Errors[common.ErrUnknownError], nil
+	}
+}
+
+// renderError writes response for flow error with additional fields of response
+func (r *Rauther) renderError(c transport.Context, err error, fields map[string]interface{}) {
+	status, body, info := ErrorDetails(err)
+
+	setSpanError(trace.SpanFromContext(requestContext(c)), err)
+
+	r.errorRenderer(c, ErrorResponse{
+		Status: status,
+		Body:   body,
+		Info:   info,
+		Fields: fields,
+		Err:    err,
+	})
+}
+
+// flowErrorResponse logs flow error and writes response
+func (r *Rauther) flowErrorResponse(c transport.Context, err error) {
+	r.logFlowError(c, err)
+	r.renderError(c, err, nil)
+}
+
+// logFlowError logs server errors with error level and client errors with debug level
+func (r *Rauther) logFlowError(c transport.Context, err error) {
+	status, body, _ := ErrorDetails(err)
+
+	level := logger.DebugLevel
+	if status >= http.StatusInternalServerError {
+		level = logger.ErrorLevel
+	}
+
+	r.logRequest(c, level, "request failed", logger.ErrorCode(body.Code), logger.Err(err))
+}
+
+type (
+	// ErrorResponse is failed request result passed to ErrorRenderer
+	ErrorResponse struct {
+		// Status is HTTP status of response
+		Status int
+		// Body contains code and message of error
+		Body common.Err
+		// Info is additional data of error, e.g. lost auth methods of merge warning
+		Info interface{}
+		// Fields are additional fields of response, e.g. "action" of link init response
+		Fields map[string]interface{}
+		// Err is flow error
+		Err error
+	}
+
+	// ErrorRenderer writes error response of handler
+	ErrorRenderer func(c transport.Context, resp ErrorResponse)
+)
+
+// DefaultErrorRenderer writes {"result": false, "error": {"code", "message"}, "info"} response
+func DefaultErrorRenderer(c transport.Context, resp ErrorResponse) {
+	body := gin.H{
+		"result": false,
+		"error":  resp.Body,
+	}
+
+	if resp.Info != nil {
+		body["info"] = resp.Info
+	}
+
+	for k, v := range resp.Fields {
+		body[k] = v
+	}
+
+	c.JSON(resp.Status, body)
+}
+
+// ProblemErrorRenderer returns renderer of RFC 7807 problem details with application/problem+json content type.
+// Type of problem is typeBaseURI + "/" + code or "about:blank" if typeBaseURI is empty.
+// Code, info and fields of error are added as extension members
+func ProblemErrorRenderer(typeBaseURI string) ErrorRenderer {
+	return func(c transport.Context, resp ErrorResponse) {
+		body := gin.H{
+			"type":   "about:blank",
+			"title":  http.StatusText(resp.Status),
+			"status": resp.Status,
+			"detail": resp.Body.Message,
+			"code":   resp.Body.Code,
+		}
+
+		if typeBaseURI != "" {
+			body["type"] = strings.TrimSuffix(typeBaseURI, "/") + "/" + resp.Body.Code
+			body["title"] = resp.Body.Message
+		}
+
+		if req := c.Request(); req != nil && req.URL != nil {
+			body["instance"] = req.URL.Path
+		}
+
+		if resp.Info != nil {
+			body["info"] = resp.Info
+		}
+
+		for k, v := range resp.Fields {
+			body[k] = v
+		}
+
+		c.Header("Content-Type", "application/problem+json")
+		c.JSON(resp.Status, body)
+	}
+}
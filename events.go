@@ -0,0 +1,28 @@
+package rauther
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/logger"
+)
+
+// Events returns bus of auth lifecycle events
+func (r *Rauther) Events() *events.Bus {
+	return r.deps.EventBus
+}
+
+// Subscribe adds handler of events with the same types as filter events (all events if filter is empty). See events.Bus.Subscribe
+func (r *Rauther) Subscribe(h events.Handler, filter []events.Event, opts ...events.SubscribeOption) (unsubscribe func()) {
+	return r.deps.EventBus.Subscribe(h, filter, opts...)
+}
+
+// publish delivers event of succeeded flow step
+func (r *Rauther) publish(ctx context.Context, e events.Event) {
+	r.deps.EventBus.Publish(ctx, e)
+}
+
+func (r *Rauther) logEventPanic(e events.Event, recovered interface{}) {
+	r.logger.Log(logger.ErrorLevel, "event handler panic", logger.F("event", e.EventName()), logger.F("panic", fmt.Sprint(recovered)))
+}
@@ -0,0 +1,136 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+type (
+	// Handler receives event
+	Handler func(ctx context.Context, e Event)
+
+	// Options of bus
+	Options struct {
+		// OnPanic is called if handler panics. Panics are recovered in any case: other handlers and flow are not affected
+		OnPanic func(e Event, recovered interface{})
+	}
+
+	// SubscribeOption changes delivery of subscription
+	SubscribeOption func(s *subscription)
+
+	// Bus delivers events to subscribers in order of subscription.
+	// Sync handlers are called by flow before its result is returned, async handlers - in separate goroutines
+	Bus struct {
+		opts Options
+
+		mu     sync.RWMutex
+		nextID int
+		subs   []*subscription
+
+		wg sync.WaitGroup
+	}
+
+	subscription struct {
+		id      int
+		names   map[string]bool
+		handler Handler
+		async   bool
+	}
+)
+
+// Async delivers events to handler in separate goroutine. Context of event may be canceled after flow ends
+func Async() SubscribeOption {
+	return func(s *subscription) {
+		s.async = true
+	}
+}
+
+// New returns bus
+func New(opts Options) *Bus {
+	return &Bus{opts: opts}
+}
+
+// Subscribe adds handler of events with the same types as filter events (all events if filter is empty):
+//
+//	unsubscribe := bus.Subscribe(func(ctx context.Context, e events.Event) {
+//		signedUp := e.(events.UserSignedUp)
+//		...
+//	}, []events.Event{events.UserSignedUp{}}, events.Async())
+func (b *Bus) Subscribe(h Handler, filter []Event, opts ...SubscribeOption) (unsubscribe func()) {
+	s := &subscription{handler: h}
+
+	if len(filter) > 0 {
+		s.names = make(map[string]bool, len(filter))
+
+		for _, e := range filter {
+			s.names[e.EventName()] = true
+		}
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	b.mu.Lock()
+	b.nextID++
+	s.id = b.nextID
+	b.subs = append(b.subs, s)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for i, sub := range b.subs {
+			if sub.id == s.id {
+				b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers event to subscribers
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		if s.names != nil && !s.names[e.EventName()] {
+			continue
+		}
+
+		if !s.async {
+			b.deliver(ctx, s.handler, e)
+			continue
+		}
+
+		b.wg.Add(1)
+
+		go func(h Handler) {
+			defer b.wg.Done()
+
+			b.deliver(ctx, h, e)
+		}(s.handler)
+	}
+}
+
+// Wait waits for async handlers, e.g. on graceful shutdown
+func (b *Bus) Wait() {
+	b.wg.Wait()
+}
+
+func (b *Bus) deliver(ctx context.Context, h Handler, e Event) {
+	defer func() {
+		if rec := recover(); rec != nil && b.opts.OnPanic != nil {
+			b.opts.OnPanic(e, rec)
+		}
+	}()
+
+	h(ctx, e)
+}
@@ -0,0 +1,134 @@
+// Package events defines typed events of auth lifecycle and in-process bus that delivers them to subscribers.
+package events
+
+import (
+	"github.com/rosberry/rauther/session"
+	"github.com/rosberry/rauther/user"
+)
+
+type (
+	// Event of auth lifecycle. Events are passed by value: use type switch or assertion in handler
+	Event interface {
+		// EventName returns name of event type, e.g. "user_signed_up"
+		EventName() string
+	}
+
+	// SessionStarted - auth handler issued token for device
+	SessionStarted struct {
+		DeviceID string
+		Session  session.Session
+	}
+
+	// SessionRevoked - user signed out, token of session is replaced
+	SessionRevoked struct {
+		Session session.Session
+		User    user.User
+	}
+
+	// GuestCreated - guest user created for session
+	GuestCreated struct {
+		User user.User
+	}
+
+	// GuestRemoved - guest user removed after sign-in or sign-out
+	GuestRemoved struct {
+		UserID interface{}
+	}
+
+	// GuestConverted - guest user of session became registered user
+	GuestConverted struct {
+		User    user.User
+		AuthKey string
+		UID     string
+	}
+
+	// UserSignedUp - new user registered by password, social or OTP auth method
+	UserSignedUp struct {
+		User    user.User
+		AuthKey string
+		UID     string
+		Session session.Session
+	}
+
+	// UserSignedIn - existing user signed in
+	UserSignedIn struct {
+		User    user.User
+		AuthKey string
+		UID     string
+		Session session.Session
+	}
+
+	// UserConfirmed - user confirmed UID of auth method by code
+	UserConfirmed struct {
+		User    user.User
+		AuthKey string
+		UID     string
+	}
+
+	// OTPRequested - one-time password sent to user
+	OTPRequested struct {
+		User    user.User
+		AuthKey string
+		UID     string
+	}
+
+	// RecoveryRequested - password recovery code sent to user
+	RecoveryRequested struct {
+		User    user.User
+		AuthKey string
+		UID     string
+	}
+
+	// PasswordReset - password changed by recovery code
+	PasswordReset struct {
+		User    user.User
+		AuthKey string
+		UID     string
+	}
+
+	// IdentityLinked - auth identity linked to current user
+	IdentityLinked struct {
+		User    user.User
+		AuthKey string
+		UID     string
+	}
+
+	// UIDChangeRequested - code sent to new UID of user
+	UIDChangeRequested struct {
+		User    user.User
+		AuthKey string
+		UID     string
+	}
+
+	// UIDChanged - user confirmed new UID, PreviousUID is not valid for sign-in anymore
+	UIDChanged struct {
+		User        user.User
+		AuthKey     string
+		UID         string
+		PreviousUID string
+	}
+
+	// AccountsMerged - user Merged is merged into User and removed
+	AccountsMerged struct {
+		User    user.User
+		Merged  user.User
+		AuthKey string
+		UID     string
+	}
+)
+
+func (SessionStarted) EventName() string     { return "session_started" }
+func (SessionRevoked) EventName() string     { return "session_revoked" }
+func (GuestCreated) EventName() string       { return "guest_created" }
+func (GuestRemoved) EventName() string       { return "guest_removed" }
+func (GuestConverted) EventName() string     { return "guest_converted" }
+func (UserSignedUp) EventName() string       { return "user_signed_up" }
+func (UserSignedIn) EventName() string       { return "user_signed_in" }
+func (UserConfirmed) EventName() string      { return "user_confirmed" }
+func (OTPRequested) EventName() string       { return "otp_requested" }
+func (RecoveryRequested) EventName() string  { return "recovery_requested" }
+func (PasswordReset) EventName() string      { return "password_reset" }
+func (IdentityLinked) EventName() string     { return "identity_linked" }
+func (AccountsMerged) EventName() string     { return "accounts_merged" }
+func (UIDChangeRequested) EventName() string { return "uid_change_requested" }
+func (UIDChanged) EventName() string         { return "uid_changed" }
@@ -188,7 +188,7 @@ func main() { // nolint
 
 	err := rauth.InitHandlers()
 	if err != nil {
-		log.Print(err)
+		log.Fatal().Err(err).Msg("Rauther init error")
 	}
 
 	err = r.Run()
@@ -55,6 +55,21 @@ func (s *Sessioner) FindByToken(token string) session.Session {
 	return nil
 }
 
+func (s *Sessioner) FindByUserID(userID interface{}) ([]session.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sessions []session.Session
+
+	for _, sess := range s.Sessions {
+		if sess.UserID != 0 && sess.UserID == userID {
+			sessions = append(sessions, sess)
+		}
+	}
+
+	return sessions, nil
+}
+
 func (s *Sessioner) Save(sess session.Session) error {
 	session, ok := sess.(*Session)
 	if !ok {
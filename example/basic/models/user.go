@@ -33,13 +33,15 @@ type (
 	}
 
 	AuthIdentities struct {
-		Type         string     `json:"type"`
-		UID          string     `json:"uid"`
-		Password     string     `json:"password"`
-		ConfirmCode  string     `json:"confirmCode"`
-		RecoveryCode string     `json:"recoveryCode"`
-		Confirmed    bool       `json:"confirmed"`
-		SentAt       *time.Time `json:"sentAt"`
+		Type          string     `json:"type"`
+		UID           string     `json:"uid"`
+		Password      string     `json:"password"`
+		ConfirmCode   string     `json:"confirmCode"`
+		RecoveryCode  string     `json:"recoveryCode"`
+		PendingUID    string     `json:"pendingUid"`
+		UIDChangeCode string     `json:"uidChangeCode"`
+		Confirmed     bool       `json:"confirmed"`
+		SentAt        *time.Time `json:"sentAt"`
 	}
 )
 
@@ -127,6 +129,26 @@ func (u *User) GetRecoveryCode(authType string) (code string) {
 	return u.Auths[authType].RecoveryCode
 }
 
+func (u *User) SetPendingUID(authType, uid string) {
+	at := u.Auths[authType]
+	at.PendingUID = uid
+	u.Auths[authType] = at
+}
+
+func (u *User) GetPendingUID(authType string) (uid string) {
+	return u.Auths[authType].PendingUID
+}
+
+func (u *User) SetUIDChangeCode(authType, code string) {
+	at := u.Auths[authType]
+	at.UIDChangeCode = code
+	u.Auths[authType] = at
+}
+
+func (u *User) GetUIDChangeCode(authType string) (code string) {
+	return u.Auths[authType].UIDChangeCode
+}
+
 func (u *User) GetField(key string) (field interface{}, err error) {
 	return user.GetField(u, key) // nolint
 }
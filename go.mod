@@ -4,10 +4,18 @@ go 1.14
 
 require (
 	github.com/gin-gonic/gin v1.7.7
+	github.com/go-chi/chi/v5 v5.0.7
 	github.com/google/uuid v1.2.0
+	github.com/labstack/echo/v4 v4.6.1
+	github.com/prometheus/client_golang v1.11.1
 	github.com/rosberry/auth v0.0.0-20210922045552-71086f41a070
 	github.com/rosberry/ginlog v0.0.0-20211206065115-f5dae98f24c6
 	github.com/rs/zerolog v1.26.1
 	github.com/smartystreets/goconvey v1.6.4 // indirect
+	go.opentelemetry.io/otel v1.3.0
+	go.opentelemetry.io/otel/trace v1.3.0
 	golang.org/x/crypto v0.0.0-20211215165025-cf75a172585e
+	golang.org/x/text v0.3.7
+	google.golang.org/grpc v1.43.0
+	google.golang.org/protobuf v1.27.1
 )
@@ -1,18 +1,23 @@
 package rauther
 
 import (
+	"context"
+
 	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/events"
 	"github.com/rosberry/rauther/user"
 )
 
-func (r *Rauther) createGuestUser() (user.User, common.ErrTypes) {
+func (r *Rauther) createGuestUser(ctx context.Context) (user.User, common.ErrTypes) {
 	usr := r.deps.UserStorer.Create()
 	usr.(user.GuestUser).SetGuest(true)
 
-	err := r.deps.UserStorer.Save(usr)
+	err := r.users(ctx).Save(usr)
 	if err != nil {
 		return nil, common.ErrUserSave
 	}
 
+	r.publish(ctx, events.GuestCreated{User: usr})
+
 	return usr, common.ErrTypes(0)
 }
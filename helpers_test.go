@@ -0,0 +1,57 @@
+package rauther_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rosberry/rauther/example/basic/models"
+)
+
+type testServer struct {
+	t     *testing.T
+	mux   *http.ServeMux
+	users *models.UserStorer
+}
+
+// request sends request to server and returns status and JSON response
+func (s *testServer) request(method, token, path, body string) (int, map[string]interface{}) {
+	s.t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, req)
+
+	resp := map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		s.t.Fatalf("%s %s: invalid response %q", method, path, w.Body.String())
+	}
+
+	return w.Code, resp
+}
+
+// do sends POST request and fails test if result is not successful
+func (s *testServer) do(token, path, body string) map[string]interface{} {
+	s.t.Helper()
+
+	code, resp := s.request(http.MethodPost, token, path, body)
+	if resp["result"] != true {
+		s.t.Fatalf("%s: %d %v", path, code, resp)
+	}
+
+	return resp
+}
+
+func (s *testServer) auth(deviceID string) string {
+	s.t.Helper()
+
+	resp := s.do("", "/auth", `{"device_id":"`+deviceID+`"}`)
+
+	return resp["token"].(string)
+}
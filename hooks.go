@@ -1,7 +1,13 @@
 package rauther
 
 import (
+	"context"
+	"errors"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
+	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/hooks"
 	"github.com/rosberry/rauther/session"
 	"github.com/rosberry/rauther/user"
 )
@@ -37,3 +43,74 @@ func (r *Rauther) AfterSocialSignUp(f func(resp gin.H, sess session.Session, u u
 func (r *Rauther) AfterOTPSignUp(f func(resp gin.H, sess session.Session, u user.User, authKey string)) {
 	r.hooks.AfterOTPSignUp = f
 }
+
+func (r *Rauther) BeforeSignUp(f hooks.BeforeHook) {
+	r.hooks.BeforeSignUp = f
+}
+
+func (r *Rauther) BeforeSignIn(f hooks.BeforeHook) {
+	r.hooks.BeforeSignIn = f
+}
+
+func (r *Rauther) BeforeSocialSignIn(f hooks.BeforeHook) {
+	r.hooks.BeforeSocialSignIn = f
+}
+
+func (r *Rauther) BeforeOTPRequest(f hooks.BeforeHook) {
+	r.hooks.BeforeOTPRequest = f
+}
+
+func (r *Rauther) BeforeOTPVerify(f hooks.BeforeHook) {
+	r.hooks.BeforeOTPVerify = f
+}
+
+func (r *Rauther) BeforeLink(f hooks.BeforeHook) {
+	r.hooks.BeforeLink = f
+}
+
+func (r *Rauther) BeforeMerge(f hooks.BeforeHook) {
+	r.hooks.BeforeMerge = f
+}
+
+func (r *Rauther) BeforeRecoveryRequest(f hooks.BeforeHook) {
+	r.hooks.BeforeRecoveryRequest = f
+}
+
+func (r *Rauther) BeforeRecoveryReset(f hooks.BeforeHook) {
+	r.hooks.BeforeRecoveryReset = f
+}
+
+func (r *Rauther) BeforeSignOut(f hooks.BeforeHook) {
+	r.hooks.BeforeSignOut = f
+}
+
+// contextWithRequest returns context with bound request of handler for before hooks
+func contextWithRequest(ctx context.Context, request interface{}) context.Context {
+	return context.WithValue(ctx, hookRequestContextKey, request)
+}
+
+// beforeHook calls hook if it is set. Request of handler and session are added to input
+func (r *Rauther) beforeHook(ctx context.Context, hook hooks.BeforeHook, in hooks.Input, info sessionInfo) error {
+	if hook == nil {
+		return nil
+	}
+
+	in.Request = ctx.Value(hookRequestContextKey)
+	in.Session = info.Session
+
+	err := hook(ctx, in)
+	if err == nil {
+		return nil
+	}
+
+	var (
+		customErr CustomError
+		flowErr   *Error
+	)
+
+	if errors.As(err, &customErr) || errors.As(err, &flowErr) {
+		return err
+	}
+
+	return wrapError(http.StatusForbidden, common.ErrForbidden, err)
+}
@@ -1,20 +1,67 @@
 package hooks
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
+	"github.com/rosberry/rauther/authtype"
 	"github.com/rosberry/rauther/session"
 	"github.com/rosberry/rauther/user"
 )
 
-type HookOptions struct {
-	AfterAuth      func(gin.H, session.Session)
-	AfterAuthCheck func(gin.H, session.Session)
+type (
+	HookOptions struct {
+		AfterAuth      func(gin.H, session.Session)
+		AfterAuthCheck func(gin.H, session.Session)
+
+		AfterPasswordSignUp func(resp gin.H, sess session.Session, u user.User, authKey string)
+		AfterSocialSignUp   func(resp gin.H, sess session.Session, u user.User, authKey string)
+		AfterOTPSignUp      func(resp gin.H, sess session.Session, u user.User, authKey string)
+
+		AfterPasswordSignIn func(resp gin.H, sess session.Session, u user.User, authKey string)
+		AfterSocialSignIn   func(resp gin.H, sess session.Session, u user.User, authKey string)
+		AfterOTPSignIn      func(resp gin.H, sess session.Session, u user.User, authKey string)
+
+		BeforeSignUp          BeforeHook
+		BeforeSignIn          BeforeHook
+		BeforeSocialSignIn    BeforeHook
+		BeforeOTPRequest      BeforeHook
+		BeforeOTPVerify       BeforeHook
+		BeforeLink            BeforeHook
+		BeforeMerge           BeforeHook
+		BeforeRecoveryRequest BeforeHook
+		BeforeRecoveryReset   BeforeHook
+		BeforeSignOut         BeforeHook
+	}
+
+	// BeforeHook is called by flow before changes are saved. Returned error rejects flow:
+	// rauther.CustomError is responded as is (e.g. NewCustomError(403, "banned", "User is banned")),
+	// other errors are responded as "forbidden". Hook may change User, it is saved by flow
+	BeforeHook func(ctx context.Context, in Input) error
 
-	AfterPasswordSignUp func(resp gin.H, sess session.Session, u user.User, authKey string)
-	AfterSocialSignUp   func(resp gin.H, sess session.Session, u user.User, authKey string)
-	AfterOTPSignUp      func(resp gin.H, sess session.Session, u user.User, authKey string)
+	// Input of before hook
+	Input struct {
+		// AuthMethod of flow, nil for sign-out
+		AuthMethod *authtype.AuthMethod
 
-	AfterPasswordSignIn func(resp gin.H, sess session.Session, u user.User, authKey string)
-	AfterSocialSignIn   func(resp gin.H, sess session.Session, u user.User, authKey string)
-	AfterOTPSignIn      func(resp gin.H, sess session.Session, u user.User, authKey string)
-}
+		// Request is bound request of HTTP handler: SignUpRequest of auth method, rauther.LinkRequest,
+		// rauther.RecoveryRequest or *http.Request for sign-out. It is nil for Go API calls
+		Request interface{}
+
+		// UID of auth identity, empty for sign-out
+		UID string
+
+		// Session of request, nil for Go API calls without session
+		Session session.Session
+
+		// User is candidate user:
+		//	sign-up - new user before Save;
+		//	sign-in, OTP verify, recovery - found user;
+		//	social sign-in, OTP request - found or new user;
+		//	link, merge, sign-out - current user of session
+		User user.User
+
+		// LinkUser is user that will be linked or merged into User (link and merge hooks)
+		LinkUser user.User
+	}
+)
@@ -0,0 +1,71 @@
+package rauther_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rosberry/rauther"
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/deps"
+	"github.com/rosberry/rauther/example/basic/models"
+	"github.com/rosberry/rauther/hooks"
+	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/sender/sendertest"
+	"github.com/rosberry/rauther/transport/httptransport"
+)
+
+func TestBeforeHookRequest(t *testing.T) {
+	users := &models.UserStorer{Users: map[uint]*models.User{}}
+	sessions := &models.Sessioner{Sessions: map[string]*models.Session{}}
+	mux := http.NewServeMux()
+	rec := sendertest.New()
+
+	r := rauther.New(deps.NewWithRouter(httptransport.New(mux), deps.Storage{SessionStorer: sessions, UserStorer: users}))
+	r.AddAuthMethod(authtype.AuthMethod{Key: "email", Sender: rec})
+	r.Config.Password.ResendDelay = 0
+
+	requests := map[string]interface{}{}
+
+	record := func(name string) hooks.BeforeHook {
+		return func(ctx context.Context, in hooks.Input) error {
+			requests[name] = in.Request
+			return nil
+		}
+	}
+
+	r.BeforeRecoveryRequest(record("recovery request"))
+	r.BeforeRecoveryReset(record("recovery reset"))
+	r.BeforeSignOut(record("sign-out"))
+
+	if err := r.InitHandlers(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{t: t, mux: mux, users: users}
+
+	token := s.auth("device1")
+	s.do(token, "/register", `{"email":"user@mail.com","password":"password"}`)
+	s.do(token, "/recover", `{"uid":"user@mail.com"}`)
+
+	code, ok := rec.LastCode("user@mail.com", sender.PasswordRecoveryEvent)
+	if !ok {
+		t.Fatal("recovery code is not sent")
+	}
+
+	s.do(token, "/recover/reset", `{"uid":"user@mail.com","code":"`+code+`","password":"new password"}`)
+	s.do(token, "/logout", "")
+
+	if got, want := requests["recovery request"], (rauther.RecoveryRequest{UID: "user@mail.com"}); got != want {
+		t.Errorf("recovery request: Request = %#v, want %#v", got, want)
+	}
+
+	want := rauther.RecoveryRequest{UID: "user@mail.com", Code: code, Password: "new password"}
+	if got := requests["recovery reset"]; got != want {
+		t.Errorf("recovery reset: Request = %#v, want %#v", got, want)
+	}
+
+	if req, ok := requests["sign-out"].(*http.Request); !ok || req.Header.Get("Authorization") != "Bearer "+token {
+		t.Errorf("sign-out: Request = %#v, want *http.Request of handler", requests["sign-out"])
+	}
+}
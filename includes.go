@@ -1,36 +1,27 @@
 package rauther
 
 import (
-	"log"
+	"net/http"
 
-	"github.com/gin-gonic/gin"
 	"github.com/rosberry/rauther/authtype"
-	"github.com/rosberry/rauther/common"
-	"github.com/rosberry/rauther/storage"
+	"github.com/rosberry/rauther/transport"
 )
 
 func (r *Rauther) includeSession() {
-	r.deps.R.POST(r.Config.Routes.Auth, r.authHandler())
+	r.deps.Router.Handle(http.MethodPost, r.Config.Routes.Auth, r.authHandler())
 
-	withSession := r.deps.R.Group("", r.authMiddleware())
+	withSession := securedGroup(r.deps.Router, securitySession, r.authMiddleware())
 	{
-		withSession.GET(r.Config.Routes.Auth, r.checkAuthHandler)
+		withSession.Handle(http.MethodGet, r.Config.Routes.Auth, r.checkAuthHandler)
 
 		if r.Modules.AuthableUser {
-			r.includeAuthable(r.deps.R, withSession)
+			r.includeAuthable(r.deps.Router, withSession)
 		}
 	}
 }
 
-func (r *Rauther) includeAuthable(router *gin.RouterGroup, authRouter *gin.RouterGroup) {
-	if !r.checker.Authable {
-		log.Fatal(common.Errors[common.ErrAuthableUserNotImplement])
-	}
-
-	r.checkLink()
-	r.checkRemovableUser()
-
-	authRouter.POST(r.Config.Routes.SignOut, r.signOutHandler)
+func (r *Rauther) includeAuthable(router, authRouter transport.Router) {
+	authRouter.Handle(http.MethodPost, r.Config.Routes.SignOut, r.signOutHandler)
 
 	if r.Modules.PasswordAuthableUser && r.methods.ExistingTypes[authtype.Password] {
 		r.includePasswordAuthable(router, authRouter)
@@ -43,122 +34,70 @@ func (r *Rauther) includeAuthable(router *gin.RouterGroup, authRouter *gin.Route
 	if r.Modules.OTP && r.methods.ExistingTypes[authtype.OTP] {
 		r.includeOTPAuthable(authRouter)
 	}
-}
 
-func (r *Rauther) includePasswordAuthable(router *gin.RouterGroup, authRouter *gin.RouterGroup) {
-	if !r.checker.PasswordAuthable {
-		log.Fatal(common.Errors[common.ErrPasswordAuthableUserNotImplement])
+	if r.Modules.ChangeUID && (r.methods.ExistingTypes[authtype.Password] || r.methods.ExistingTypes[authtype.OTP]) {
+		r.includeUIDChangeable(authRouter)
 	}
+}
 
-	authRouter.POST(r.Config.Routes.SignUp, r.signUpHandler)
-	authRouter.POST(r.Config.Routes.SignIn, r.signInHandler)
-	authRouter.POST(r.Config.Routes.ValidateLoginField, r.validateLoginField)
+func (r *Rauther) includePasswordAuthable(router, authRouter transport.Router) {
+	authRouter.Handle(http.MethodPost, r.Config.Routes.SignUp, r.signUpHandler)
+	authRouter.Handle(http.MethodPost, r.Config.Routes.SignIn, r.signInHandler)
+
+	if !(r.Config.UserEnumeration.Protect && r.Config.UserEnumeration.DisableCheck) {
+		authRouter.Handle(http.MethodPost, r.Config.Routes.ValidateLoginField, r.validateLoginField)
+	}
 
 	if r.Modules.ConfirmableUser {
 		r.includeConfirmable(router, authRouter)
 	}
 
 	if r.Modules.RecoverableUser {
-		r.includeRecoverable(authRouter)
+		r.includeRecoverable(router, authRouter)
 	}
 
 	if r.Modules.LinkAccount {
-		withUser := authRouter.Group("", r.authUserMiddleware())
+		withUser := securedGroup(authRouter, securityUser, r.authUserMiddleware())
 		{
-			withUser.POST(r.Config.Routes.InitLink, r.initLinkingPasswordAccount)
-			withUser.POST(r.Config.Routes.Link, r.linkPasswordAccount)
+			withUser.Handle(http.MethodPost, r.Config.Routes.InitLink, r.initLinkingPasswordAccount)
+			withUser.Handle(http.MethodPost, r.Config.Routes.Link, r.linkPasswordAccount)
 		}
 	}
 }
 
-func (r *Rauther) includeSocialAuthable(router *gin.RouterGroup) {
-	router.POST(r.Config.Routes.SocialSignIn, r.socialSignInHandler)
-}
-
-func (r *Rauther) includeOTPAuthable(router *gin.RouterGroup) {
-	if !r.checker.OTPAuth {
-		log.Fatal(common.Errors[common.ErrOTPNotImplement])
-	}
-
-	router.POST(r.Config.Routes.OTPRequestCode, r.otpGetCodeHandler)
-	router.POST(r.Config.Routes.OTPCheckCode, r.otpAuthHandler)
-}
-
-func (r *Rauther) checkRemovableUser() {
-	if r.Modules.GuestUser && !r.checker.Guest {
-		log.Fatal("Please, implement GuestUser interface for use guest user")
-	}
-
-	if r.Modules.GuestUser || r.Modules.LinkAccount {
-		if r.deps.Storage.UserRemover == nil {
-			userRemover, isRemovable := r.deps.Storage.UserStorer.(storage.RemovableUserStorer)
-
-			if !isRemovable {
-				log.Fatal("If config approve guest then user storer must implement RemovableUserStorer interface. Change it.")
-			}
-
-			r.deps.Storage.UserRemover = userRemover
-		}
-	}
+func (r *Rauther) includeSocialAuthable(router transport.Router) {
+	router.Handle(http.MethodPost, r.Config.Routes.SocialSignIn, r.socialSignInHandler)
 }
 
-func (r *Rauther) checkLink() {
-	if r.Modules.LinkAccount {
-		if !r.Modules.ConfirmableUser {
-			log.Fatal("Please, enable ConfirmableUser module for use linking")
-		}
-
-		if !r.checker.LinkAccount {
-			log.Fatal("Please, implement TempUser interface for use linking")
-		}
-
-		if r.Modules.MergeAccount && !r.checker.MergeAccount {
-			log.Fatal("Please, implement MergeUser interface for use merging")
-		}
-	}
+func (r *Rauther) includeOTPAuthable(router transport.Router) {
+	router.Handle(http.MethodPost, r.Config.Routes.OTPRequestCode, r.otpGetCodeHandler)
+	router.Handle(http.MethodPost, r.Config.Routes.OTPCheckCode, r.otpAuthHandler)
 }
 
-func (r *Rauther) includeConfirmable(router *gin.RouterGroup, authRouter *gin.RouterGroup) {
-	if !r.checker.Confirmable {
-		log.Fatal(common.Errors[common.ErrConfirmableUserNotImplement])
-	}
+func (r *Rauther) includeConfirmable(router, authRouter transport.Router) {
+	authRouter.Handle(http.MethodPost, r.Config.Routes.ConfirmResend, r.resendCodeHandler)
+	router.Handle(http.MethodPost, r.Config.Routes.ConfirmCode, r.confirmHandler)
 
-	if !r.checkSender() {
-		log.Fatal(common.Errors[common.ErrSenderRequired])
+	if r.confirmLinkEnabled() {
+		router.Handle(http.MethodGet, r.Config.Routes.ConfirmLink, r.confirmLinkHandler)
 	}
-
-	authRouter.POST(r.Config.Routes.ConfirmResend, r.resendCodeHandler)
-	router.POST(r.Config.Routes.ConfirmCode, r.confirmHandler)
 }
 
-func (r *Rauther) includeRecoverable(router *gin.RouterGroup) {
-	if !r.checker.Recoverable {
-		log.Fatal(common.Errors[common.ErrRecoverableUserNotImplement])
-	}
+func (r *Rauther) includeRecoverable(router, authRouter transport.Router) {
+	authRouter.Handle(http.MethodPost, r.Config.Routes.RecoveryRequest, r.requestRecoveryHandler)
+	authRouter.Handle(http.MethodPost, r.Config.Routes.RecoveryValidateCode, r.validateRecoveryCodeHandler)
+	authRouter.Handle(http.MethodPost, r.Config.Routes.RecoveryCode, r.recoveryHandler)
 
-	if !r.checkSender() {
-		log.Fatal(common.Errors[common.ErrSenderRequired])
+	if r.resetLinkEnabled() {
+		router.Handle(http.MethodGet, r.Config.Routes.RecoveryLink, r.recoveryLinkHandler)
+		router.Handle(http.MethodPost, r.Config.Routes.RecoveryLinkReset, r.recoveryLinkResetHandler)
 	}
-
-	router.POST(r.Config.Routes.RecoveryRequest, r.requestRecoveryHandler)
-	router.POST(r.Config.Routes.RecoveryValidateCode, r.validateRecoveryCodeHandler)
-	router.POST(r.Config.Routes.RecoveryCode, r.recoveryHandler)
 }
 
-func (r *Rauther) checkSender() (ok bool) {
-	if r.methods != nil && !r.methods.IsEmpty() {
-		for _, t := range r.methods.List {
-			if t.Sender == nil {
-				if r.defaultSender == nil {
-					log.Fatalf("If you not define auth sender - first define default sender\nDefaultSender(s sender.Sender)")
-				}
-
-				t.Sender = r.defaultSender
-			}
-		}
-	} else if r.defaultSender == nil {
-		return false
+func (r *Rauther) includeUIDChangeable(router transport.Router) {
+	withUser := securedGroup(router, securityUser, r.authUserMiddleware())
+	{
+		withUser.Handle(http.MethodPost, r.Config.Routes.ChangeUID, r.changeUIDHandler)
+		withUser.Handle(http.MethodPost, r.Config.Routes.ConfirmUIDChange, r.confirmUIDChangeHandler)
 	}
-
-	return true
 }
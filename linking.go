@@ -1,13 +1,18 @@
 package rauther
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 
-	"github.com/gin-gonic/gin"
+	"github.com/rosberry/rauther/audit"
 	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/hooks"
+	"github.com/rosberry/rauther/logger"
+	"github.com/rosberry/rauther/transport"
+	"github.com/rosberry/rauther/transport/gintransport"
 	"github.com/rosberry/rauther/user"
 )
 
@@ -22,13 +27,13 @@ var (
 	errCannotMergeSelf         = errors.New("cannot merge self")
 )
 
-func (r *Rauther) initLinkAccount(sessionInfo sessionInfo, authKey string, uid string) (u user.User, err error) {
+func (r *Rauther) initLinkAccount(ctx context.Context, sessionInfo sessionInfo, authKey string, uid string) (u user.User, err error) {
 	err = r.checkUserCanLinkAccount(sessionInfo.User, authKey, uid)
 	if err != nil {
 		return nil, err
 	}
 
-	u, err = r.deps.UserStorer.LoadByUID(authKey, uid)
+	u, err = r.users(ctx).LoadByUID(authKey, uid)
 	if err != nil {
 		var customErr CustomError
 		if errors.As(err, &customErr) {
@@ -69,7 +74,7 @@ func (r *Rauther) checkUserCanLinkAccount(currentUser user.User, authKey, uid st
 	return nil
 }
 
-func (r *Rauther) linkAccount(sessionInfo sessionInfo, link user.User, at *authtype.AuthMethod, mergeConfirm bool, ctx *gin.Context) error {
+func (r *Rauther) linkAccount(ctx context.Context, sessionInfo sessionInfo, link user.User, at *authtype.AuthMethod, mergeConfirm bool, tContext transport.Context) error { // nolint:lll
 	uid := link.(user.AuthableUser).GetUID(at.Key)
 
 	err := r.checkUserCanLinkAccount(sessionInfo.User, at.Key, uid)
@@ -88,19 +93,38 @@ func (r *Rauther) linkAccount(sessionInfo sessionInfo, link user.User, at *autht
 		return errFailedLinkUser
 	}
 
+	in := hooks.Input{AuthMethod: at, UID: uid, User: sessionInfo.User, LinkUser: link}
+
 	if !link.(user.TempUser).IsTemp() {
 		if r.Modules.MergeAccount {
-			err = r.mergeUsers(sessionInfo.User, link, mergeConfirm, ctx)
+			if mergeConfirm {
+				if err := r.beforeHook(ctx, r.hooks.BeforeMerge, in, sessionInfo); err != nil {
+					return err
+				}
+			}
+
+			err = r.mergeUsers(ctx, sessionInfo.User, link, mergeConfirm, tContext)
+
+			if mergeConfirm {
+				r.auditMerge(ctx, sessionInfo.User, link, at.Key, uid, err)
+			}
+
 			if err != nil {
 				return fmt.Errorf("merge error: %w", err)
 			}
 
+			r.publish(ctx, events.AccountsMerged{User: sessionInfo.User, Merged: link, AuthKey: at.Key, UID: uid})
+
 			return nil
 		}
 
 		return errUserAlreadyRegistered
 	}
 
+	if err := r.beforeHook(ctx, r.hooks.BeforeLink, in, sessionInfo); err != nil {
+		return err
+	}
+
 	sessionInfo.User.(user.AuthableUser).SetUID(at.Key, uid)
 
 	if at.Type == authtype.Password {
@@ -113,15 +137,17 @@ func (r *Rauther) linkAccount(sessionInfo sessionInfo, link user.User, at *autht
 		sessionInfo.User.(user.ConfirmableUser).SetConfirmed(at.Key, confirmed)
 	}
 
-	err = r.deps.UserRemover.RemoveByID(link.GetID())
+	err = r.remover(ctx).RemoveByID(link.GetID())
 	if err != nil {
 		return fmt.Errorf("failed to remove user: %w", err)
 	}
 
+	r.publish(ctx, events.IdentityLinked{User: sessionInfo.User, AuthKey: at.Key, UID: uid})
+
 	return nil
 }
 
-func (r *Rauther) mergeUsers(current, link user.User, mergeConfirm bool, ctx *gin.Context) error {
+func (r *Rauther) mergeUsers(ctx context.Context, current, link user.User, mergeConfirm bool, tContext transport.Context) error {
 	// move all auth identities from link user to current user
 	failedMethods := r.moveAuthIdentities(current, link, mergeConfirm)
 
@@ -135,12 +161,12 @@ func (r *Rauther) mergeUsers(current, link user.User, mergeConfirm bool, ctx *gi
 		return newMergeError(failedMethods, info)
 	}
 
-	err := current.(user.MergeUser).Merge(link, ctx)
+	err := current.(user.MergeUser).Merge(link, gintransport.GinContext(tContext))
 	if err != nil {
 		return fmt.Errorf("failed to run merge function: %w", err)
 	}
 
-	err = r.deps.UserRemover.RemoveByID(link.GetID())
+	err = r.remover(ctx).RemoveByID(link.GetID())
 	if err != nil {
 		return fmt.Errorf("failed to remove user: %w", err)
 	}
@@ -158,7 +184,7 @@ func (r *Rauther) moveAuthIdentities(current, link user.User, mergeConfirm bool)
 		}
 
 		if current.(user.AuthableUser).GetUID(key) != "" {
-			log.Printf("Skip auth method %q: current type exists in current user", key)
+			r.logger.Log(logger.DebugLevel, "skip auth method: current type exists in current user", logger.AuthMethod(key))
 
 			failedMethods = append(failedMethods, authDescrip{
 				Key: key,
@@ -172,7 +198,7 @@ func (r *Rauther) moveAuthIdentities(current, link user.User, mergeConfirm bool)
 		// It is expected that all auth identities are already confirmed,
 		// but we check it twice, if the linking process changes
 		if !link.(user.ConfirmableUser).GetConfirmed(key) {
-			log.Printf("Skip move unconfirmed auth method %q: %s", key, uid)
+			r.logger.Log(logger.DebugLevel, "skip move unconfirmed auth method", logger.AuthMethod(key))
 
 			failedMethods = append(failedMethods, authDescrip{
 				Key: key,
@@ -195,7 +221,7 @@ func (r *Rauther) moveAuthIdentities(current, link user.User, mergeConfirm bool)
 			case authtype.OTP:
 				current.(user.AuthableUser).SetUID(key, uid)
 			default:
-				log.Printf("unknown auth type: %v", at.Type)
+				r.logger.Log(logger.WarnLevel, "unknown auth type", logger.AuthMethod(key), logger.F("type", at.Type))
 			}
 
 			if r.Modules.ConfirmableUser {
@@ -254,3 +280,24 @@ func (err MergeError) MarshalJSON() ([]byte, error) {
 func (err MergeError) Error() string {
 	return fmt.Sprintf("merge error: %s", err.e.Error())
 }
+
+// auditMerge records merge of link user into current user
+func (r *Rauther) auditMerge(ctx context.Context, current, link user.User, authKey, uid string, err error) {
+	e := audit.Event{
+		Type:    audit.Merge,
+		Outcome: audit.Success,
+		UserID:  auditUserID(current.GetID()),
+		AuthKey: authKey,
+		UID:     uid,
+		Data:    map[string]string{"merged_user_id": auditUserID(link.GetID())},
+	}
+
+	if err != nil {
+		_, body, _ := ErrorDetails(err)
+
+		e.Outcome = audit.Failure
+		e.ErrorCode = body.Code
+	}
+
+	r.recordAudit(ctx, e)
+}
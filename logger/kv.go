@@ -0,0 +1,40 @@
+package logger
+
+type (
+	// KVLogger is leveled logger with alternating key-value arguments, e.g. *slog.Logger (log/slog)
+	KVLogger interface {
+		Debug(msg string, args ...interface{})
+		Info(msg string, args ...interface{})
+		Warn(msg string, args ...interface{})
+		Error(msg string, args ...interface{})
+	}
+
+	kvLogger struct {
+		logger KVLogger
+	}
+)
+
+// KV returns adapter for slog-style logger:
+//
+//	rauth := rauther.New(deps.Deps{..., Logger: logger.KV(slog.Default())})
+func KV(l KVLogger) Logger {
+	return &kvLogger{logger: l}
+}
+
+func (kl *kvLogger) Log(level Level, msg string, fields ...Field) {
+	args := make([]interface{}, 0, len(fields)*2) // nolint:gomnd
+	for _, f := range fields {
+		args = append(args, f.Key, f.Value)
+	}
+
+	switch level {
+	case DebugLevel:
+		kl.logger.Debug(msg, args...)
+	case InfoLevel:
+		kl.logger.Info(msg, args...)
+	case WarnLevel:
+		kl.logger.Warn(msg, args...)
+	default:
+		kl.logger.Error(msg, args...)
+	}
+}
@@ -0,0 +1,173 @@
+// Package logger defines leveled structured logger used by rauther and adapters for popular loggers.
+package logger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+// Keys of fields used by rauther
+const (
+	RouteKey     = "route"
+	AuthKey      = "auth_key"
+	UserIDKey    = "user_id"
+	ErrorCodeKey = "error_code"
+	ErrorKey     = "error"
+)
+
+// Redacted replaces values of secret fields
+const Redacted = "[REDACTED]"
+
+type (
+	Level int
+
+	// Field is key-value pair of log entry
+	Field struct {
+		Key   string
+		Value interface{}
+	}
+
+	// Logger writes log entry with level, message and structured fields
+	Logger interface {
+		Log(level Level, msg string, fields ...Field)
+	}
+
+	// Nop is logger that does nothing. Used by default
+	Nop struct{}
+
+	redactLogger struct {
+		logger Logger
+		keys   map[string]bool
+	}
+
+	stdLogger struct {
+		logger *log.Logger
+		level  Level
+	}
+)
+
+// DefaultRedactKeys are keys of fields with secrets (case-insensitive)
+// nolint:gochecknoglobals
+var DefaultRedactKeys = []string{"authorization", "password", "token", "code", "secret"}
+
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// F returns field with any value
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Route returns field with route (request path)
+func Route(path string) Field {
+	return Field{Key: RouteKey, Value: path}
+}
+
+// AuthMethod returns field with key of auth method
+func AuthMethod(key string) Field {
+	return Field{Key: AuthKey, Value: key}
+}
+
+// UserID returns field with user ID
+func UserID(id interface{}) Field {
+	return Field{Key: UserIDKey, Value: id}
+}
+
+// ErrorCode returns field with code of common.Errors, e.g. "user_not_found"
+func ErrorCode(code string) Field {
+	return Field{Key: ErrorCodeKey, Value: code}
+}
+
+// Err returns field with error
+func Err(err error) Field {
+	return Field{Key: ErrorKey, Value: err}
+}
+
+func (Nop) Log(Level, string, ...Field) {}
+
+// Redact returns logger that replaces values of fields with secret keys by Redacted.
+// DefaultRedactKeys are used if keys are not passed
+func Redact(l Logger, keys ...string) Logger {
+	if len(keys) == 0 {
+		keys = DefaultRedactKeys
+	}
+
+	rl := &redactLogger{
+		logger: l,
+		keys:   make(map[string]bool, len(keys)),
+	}
+
+	for _, k := range keys {
+		rl.keys[strings.ToLower(k)] = true
+	}
+
+	return rl
+}
+
+func (rl *redactLogger) Log(level Level, msg string, fields ...Field) {
+	redacted := fields
+	copied := false
+
+	for i, f := range fields {
+		if !rl.keys[strings.ToLower(f.Key)] {
+			continue
+		}
+
+		// do not change fields of caller
+		if !copied {
+			redacted = append([]Field(nil), fields...)
+			copied = true
+		}
+
+		redacted[i].Value = Redacted
+	}
+
+	rl.logger.Log(level, msg, redacted...)
+}
+
+// Std returns logger that writes entries with level >= min to standard logger as
+//
+//	[level] message key=value ...
+func Std(l *log.Logger, min Level) Logger {
+	if l == nil {
+		l = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
+	return &stdLogger{logger: l, level: min}
+}
+
+func (sl *stdLogger) Log(level Level, msg string, fields ...Field) {
+	if level < sl.level {
+		return
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString("[" + level.String() + "] " + msg)
+
+	for _, f := range fields {
+		fmt.Fprintf(sb, " %s=%v", f.Key, f.Value)
+	}
+
+	sl.logger.Print(sb.String())
+}
@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"github.com/rs/zerolog"
+)
+
+type zerologLogger struct {
+	logger zerolog.Logger
+}
+
+// Zerolog returns adapter for zerolog logger
+func Zerolog(l zerolog.Logger) Logger {
+	return &zerologLogger{logger: l}
+}
+
+func (zl *zerologLogger) Log(level Level, msg string, fields ...Field) {
+	var e *zerolog.Event
+
+	switch level {
+	case DebugLevel:
+		e = zl.logger.Debug()
+	case InfoLevel:
+		e = zl.logger.Info()
+	case WarnLevel:
+		e = zl.logger.Warn()
+	default:
+		e = zl.logger.Error()
+	}
+
+	for _, f := range fields {
+		if err, ok := f.Value.(error); ok {
+			e = e.AnErr(f.Key, err)
+			continue
+		}
+
+		e = e.Interface(f.Key, f.Value)
+	}
+
+	e.Msg(msg)
+}
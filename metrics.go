@@ -0,0 +1,15 @@
+package rauther
+
+// observeFlow counts result of auth flow with error code. Use with pointer to named error result:
+//
+//	defer r.observeFlow(metrics.FlowSignUp, at.Key, &err)
+func (r *Rauther) observeFlow(flow, authKey string, err *error) {
+	code := ""
+
+	if *err != nil {
+		_, body, _ := ErrorDetails(*err)
+		code = body.Code
+	}
+
+	r.deps.Metrics.Flow(flow, authKey, code)
+}
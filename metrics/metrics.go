@@ -0,0 +1,52 @@
+// Package metrics defines collector of rauther metrics: results of auth flows, latency of handlers and senders.
+package metrics
+
+import "time"
+
+// Names of auth flows
+const (
+	FlowAuth             = "auth"
+	FlowSignOut          = "sign_out"
+	FlowSignUp           = "sign_up"
+	FlowSignIn           = "sign_in"
+	FlowCheckUID         = "check_uid"
+	FlowConfirm          = "confirm"
+	FlowResendCode       = "resend_code"
+	FlowRecoveryRequest  = "recovery_request"
+	FlowRecoveryValidate = "recovery_validate"
+	FlowRecoveryReset    = "recovery_reset"
+	FlowRecoveryLink     = "recovery_link"
+	FlowOTPRequest       = "otp_request"
+	FlowOTPVerify        = "otp_verify"
+	FlowSocialSignIn     = "social_sign_in"
+	FlowInitLink         = "init_link"
+	FlowLink             = "link"
+	FlowChangeUID        = "change_uid"
+	FlowConfirmUIDChange = "confirm_uid_change"
+)
+
+type (
+	// Collector receives metrics of rauther. Implementations must be safe for concurrent use
+	Collector interface {
+		// Flow counts result of auth flow. Code is error code (see common.Errors) or empty string on success
+		Flow(flow, authKey, code string)
+
+		// Handler observes duration of handler registered by InitHandlers
+		Handler(method, route string, d time.Duration)
+
+		// Send observes duration of sending code. Err is result of sender
+		Send(event string, err error, d time.Duration)
+
+		// ActiveSessions registers function that returns number of active sessions.
+		// It is called by InitHandlers if session storer implements storage.SessionCounter
+		ActiveSessions(count func() (int, error))
+	}
+
+	// Nop is collector that does nothing. Used by default
+	Nop struct{}
+)
+
+func (Nop) Flow(string, string, string)           {}
+func (Nop) Handler(string, string, time.Duration) {}
+func (Nop) Send(string, error, time.Duration)     {}
+func (Nop) ActiveSessions(func() (int, error))    {}
@@ -0,0 +1,136 @@
+// Package prommetrics implements metrics.Collector with Prometheus client
+package prommetrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rosberry/rauther/metrics"
+)
+
+const (
+	resultOK    = "ok"
+	resultError = "error"
+)
+
+type (
+	// Options of collector
+	Options struct {
+		// Namespace of metric names. Default: "rauther"
+		Namespace string
+		// Registerer for metrics. Default: prometheus.DefaultRegisterer
+		Registerer prometheus.Registerer
+		// Buckets of latency histograms in seconds. Default: prometheus.DefBuckets
+		Buckets []float64
+	}
+
+	// Collector exposes metrics:
+	//	<namespace>_flow_total{flow, auth_key, code} - results of auth flows, code is "ok" on success
+	//	<namespace>_handler_duration_seconds{method, route} - latency of handlers
+	//	<namespace>_send_duration_seconds{event, result} - latency of code senders
+	//	<namespace>_active_sessions - number of sessions if session storer implements storage.SessionCounter
+	Collector struct {
+		opts     Options
+		flows    *prometheus.CounterVec
+		handlers *prometheus.HistogramVec
+		sends    *prometheus.HistogramVec
+
+		sessionsOnce sync.Once
+	}
+)
+
+var _ metrics.Collector = (*Collector)(nil)
+
+// New creates collector and registers its metrics
+func New(opts Options) (*Collector, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = "rauther"
+	}
+
+	if opts.Registerer == nil {
+		opts.Registerer = prometheus.DefaultRegisterer
+	}
+
+	if len(opts.Buckets) == 0 {
+		opts.Buckets = prometheus.DefBuckets
+	}
+
+	c := &Collector{
+		opts: opts,
+		flows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace,
+			Name:      "flow_total",
+			Help:      "Results of auth flows by auth method key and error code.",
+		}, []string{"flow", "auth_key", "code"}),
+		handlers: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.Namespace,
+			Name:      "handler_duration_seconds",
+			Help:      "Latency of auth handlers.",
+			Buckets:   opts.Buckets,
+		}, []string{"method", "route"}),
+		sends: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.Namespace,
+			Name:      "send_duration_seconds",
+			Help:      "Latency of code senders.",
+			Buckets:   opts.Buckets,
+		}, []string{"event", "result"}),
+	}
+
+	for _, collector := range []prometheus.Collector{c.flows, c.handlers, c.sends} {
+		if err := opts.Registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Collector) Flow(flow, authKey, code string) {
+	if code == "" {
+		code = resultOK
+	}
+
+	c.flows.WithLabelValues(flow, authKey, code).Inc()
+}
+
+func (c *Collector) Handler(method, route string, d time.Duration) {
+	c.handlers.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+func (c *Collector) Send(event string, err error, d time.Duration) {
+	result := resultOK
+	if err != nil {
+		result = resultError
+	}
+
+	c.sends.WithLabelValues(event, result).Observe(d.Seconds())
+}
+
+// ActiveSessions registers gauge of active sessions. Only first count function is used
+func (c *Collector) ActiveSessions(count func() (int, error)) {
+	c.sessionsOnce.Do(func() {
+		// gauge function has no error result: last value is kept on error
+		var (
+			mu   sync.Mutex
+			last float64
+		)
+
+		gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: c.opts.Namespace,
+			Name:      "active_sessions",
+			Help:      "Number of active sessions.",
+		}, func() float64 {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if n, err := count(); err == nil {
+				last = float64(n)
+			}
+
+			return last
+		})
+
+		_ = c.opts.Registerer.Register(gauge)
+	})
+}
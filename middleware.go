@@ -1,42 +1,58 @@
 package rauther
 
 import (
+	"context"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/session"
+	"github.com/rosberry/rauther/transport"
+	"github.com/rosberry/rauther/transport/gintransport"
 	"github.com/rosberry/rauther/user"
 )
 
 // AuthMiddleware provide public access to auth middleware
 func (r *Rauther) AuthMiddleware() gin.HandlerFunc {
-	return r.authMiddleware()
+	return gintransport.Handler(r.authMiddleware())
 }
 
 func (r *Rauther) AuthUserMiddleware() gin.HandlerFunc {
-	return r.authUserMiddleware()
+	return gintransport.Handler(r.authUserMiddleware())
 }
 
 func (r *Rauther) AuthUserConfirmedMiddleware() gin.HandlerFunc {
+	return gintransport.Handler(r.authUserConfirmedMiddleware())
+}
+
+// AuthMiddlewareFunc provide transport independent auth middleware.
+// Use adapters to convert it, e.g. httptransport.Middleware(nil, rauth.AuthMiddlewareFunc())
+func (r *Rauther) AuthMiddlewareFunc() transport.HandlerFunc {
+	return r.authMiddleware()
+}
+
+func (r *Rauther) AuthUserMiddlewareFunc() transport.HandlerFunc {
+	return r.authUserMiddleware()
+}
+
+func (r *Rauther) AuthUserConfirmedMiddlewareFunc() transport.HandlerFunc {
 	return r.authUserConfirmedMiddleware()
 }
 
-func (r *Rauther) authMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		if token := parseAuthToken(c); token != "" {
-			session := r.deps.SessionStorer.FindByToken(token)
-			if session == nil || session.GetToken() == "" {
-				errorResponse(c, http.StatusUnauthorized, common.ErrAuthFailed)
+func (r *Rauther) authMiddleware() transport.HandlerFunc {
+	return func(c transport.Context) {
+		if token := r.parseAuthToken(c); token != "" {
+			session, u, err := r.findSession(requestContext(c), token)
+			if err != nil {
+				r.flowErrorResponse(c, err)
 				c.Abort()
 
 				return
 			}
 
-			if r.Modules.AuthableUser {
-				if u, err := r.deps.UserStorer.LoadByID(session.GetUserID()); err == nil && u != nil {
-					c.Set(r.Config.ContextNames.User, u)
-				}
+			if u != nil {
+				c.Set(r.Config.ContextNames.User, u)
 			}
 
 			c.Set(r.Config.ContextNames.Session, session)
@@ -46,17 +62,33 @@ func (r *Rauther) authMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		errorResponse(c, http.StatusUnauthorized, common.ErrNotAuth)
+		r.errorResponse(c, http.StatusUnauthorized, common.ErrNotAuth)
 		c.Abort()
 	}
 }
 
-func (r *Rauther) authUserMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
+// findSession returns session by token and user of session (if AuthableUser module enabled)
+func (r *Rauther) findSession(ctx context.Context, token string) (session.Session, user.User, error) {
+	sess := r.sessions(ctx).FindByToken(token)
+	if sess == nil || sess.GetToken() == "" {
+		return nil, nil, newError(http.StatusUnauthorized, common.ErrAuthFailed)
+	}
+
+	if r.Modules.AuthableUser {
+		if u, err := r.users(ctx).LoadByID(sess.GetUserID()); err == nil && u != nil {
+			return sess, u, nil
+		}
+	}
+
+	return sess, nil, nil
+}
+
+func (r *Rauther) authUserMiddleware() transport.HandlerFunc {
+	return func(c transport.Context) {
 		u, ok := c.Get(r.Config.ContextNames.User)
 
 		if !ok {
-			errorResponse(c, http.StatusUnauthorized, common.ErrNotAuth)
+			r.errorResponse(c, http.StatusUnauthorized, common.ErrNotAuth)
 			c.Abort()
 
 			return
@@ -68,7 +100,7 @@ func (r *Rauther) authUserMiddleware() gin.HandlerFunc {
 		}
 
 		if r.Modules.GuestUser && usr.(user.GuestUser).IsGuest() {
-			errorResponse(c, http.StatusUnauthorized, common.ErrNotSignIn)
+			r.errorResponse(c, http.StatusUnauthorized, common.ErrNotSignIn)
 			c.Abort()
 
 			return
@@ -78,8 +110,8 @@ func (r *Rauther) authUserMiddleware() gin.HandlerFunc {
 	}
 }
 
-func (r *Rauther) authUserConfirmedMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
+func (r *Rauther) authUserConfirmedMiddleware() transport.HandlerFunc {
+	return func(c transport.Context) {
 		if !r.Modules.ConfirmableUser {
 			c.Next()
 
@@ -89,7 +121,7 @@ func (r *Rauther) authUserConfirmedMiddleware() gin.HandlerFunc {
 		u, ok := c.Get(r.Config.ContextNames.User)
 
 		if !ok {
-			errorResponse(c, http.StatusUnauthorized, common.ErrNotAuth)
+			r.errorResponse(c, http.StatusUnauthorized, common.ErrNotAuth)
 			c.Abort()
 
 			return
@@ -101,7 +133,7 @@ func (r *Rauther) authUserConfirmedMiddleware() gin.HandlerFunc {
 		}
 
 		if !user.Confirmed() {
-			errorResponse(c, http.StatusUnauthorized, common.ErrNotConfirmed)
+			r.errorResponse(c, http.StatusUnauthorized, common.ErrNotConfirmed)
 			c.Abort()
 
 			return
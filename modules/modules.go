@@ -14,6 +14,7 @@ type Modules struct {
 	SocialAuthableUser       bool
 	ConfirmableUser          bool
 	RecoverableUser          bool
+	ChangeUID                bool
 	CodeSentTimeUser         bool
 	OTP                      bool
 	LinkAccount              bool
@@ -30,6 +31,7 @@ func (m Modules) String() string {
 	- SocialAuthableUser: %v
 	- ConfirmableUser: %v
 	- RecoverableUser: %v
+	- ChangeUID: %v
 	- CodeSentTimeUser: %v
 	- One Time Password: %v
 	- Link account: %v
@@ -42,6 +44,7 @@ func (m Modules) String() string {
 		m.SocialAuthableUser,
 		m.ConfirmableUser,
 		m.RecoverableUser,
+		m.ChangeUID,
 		m.CodeSentTimeUser,
 		m.OTP,
 		m.LinkAccount,
@@ -59,6 +62,7 @@ func New(checker *checker.Checker) *Modules {
 		SocialAuthableUser:       true, // no interfaces required // FIXME
 		ConfirmableUser:          checker.Confirmable,
 		RecoverableUser:          checker.Recoverable,
+		ChangeUID:                checker.UIDChangeable,
 		CodeSentTimeUser:         checker.CodeSentTime,
 		OTP:                      checker.OTPAuth,
 		LinkAccount:              checker.LinkAccount,
@@ -0,0 +1,459 @@
+package rauther
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/openapi"
+	"github.com/rosberry/rauther/transport"
+)
+
+const bearerAuth = "bearerAuth"
+
+// security of route, recorded by routeRecorder from group of auth middleware
+const (
+	securityNone = iota
+	securitySession
+	securityUser
+)
+
+// apiOperation describes handler for OpenAPI document
+type apiOperation struct {
+	route   string
+	method  string
+	id      string
+	summary string
+	tag     string
+	// request is static request body
+	request interface{}
+	// authType and authRequest define request body by auth methods
+	authType    authtype.Type
+	authRequest func(at authtype.AuthMethod) interface{}
+	response    string
+	errors      []int
+}
+
+// OpenAPI returns OpenAPI 3 document of routes registered by InitHandlers.
+// Request bodies are reflected from request structs of auth methods (json and binding tags).
+// Use Config.Routes.OpenAPI to serve the document
+func (r *Rauther) OpenAPI() *openapi.Document {
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:   r.Config.OpenAPI.Title,
+			Version: r.Config.OpenAPI.Version,
+		},
+		Paths: map[string]*openapi.PathItem{},
+		Components: openapi.Components{
+			Schemas: apiSchemas(),
+			SecuritySchemes: map[string]*openapi.SecurityScheme{
+				bearerAuth: {
+					Type:        "http",
+					Scheme:      "bearer",
+					Description: "Token of device session from auth request",
+				},
+			},
+		},
+	}
+
+	if base := r.router.basePath(); base != "" && base != "/" {
+		doc.Servers = []openapi.Server{{URL: base}}
+	}
+
+	operations := r.apiOperations()
+	tags := map[string]bool{}
+
+	for _, rt := range *r.router.routes {
+		op, ok := operations[rt.Method+" "+rt.Path]
+		if !ok {
+			continue
+		}
+
+		path, params := openAPIPath(rt.Path)
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &openapi.PathItem{}
+			doc.Paths[path] = item
+		}
+
+		(*item)[strings.ToLower(rt.Method)] = r.apiOperation(op, rt.Security, params)
+
+		if !tags[op.tag] {
+			tags[op.tag] = true
+			doc.Tags = append(doc.Tags, openapi.Tag{Name: op.tag})
+		}
+	}
+
+	return doc
+}
+
+func (r *Rauther) openAPIHandler(c transport.Context) {
+	c.JSON(http.StatusOK, r.OpenAPI())
+}
+
+func (r *Rauther) apiOperation(op apiOperation, security int, params []*openapi.Parameter) *openapi.Operation {
+	o := &openapi.Operation{
+		Tags:        []string{op.tag},
+		Summary:     op.summary,
+		OperationID: op.id,
+		Parameters:  params,
+		Responses: map[string]*openapi.Response{
+			"200": {
+				Description: "OK",
+				Content:     openapi.JSONContent(openapi.Ref(op.response)),
+			},
+		},
+	}
+
+	switch security {
+	case securitySession:
+		o.Security = []openapi.SecurityRequirement{{bearerAuth: {}}}
+		o.Description = "Requires session token"
+	case securityUser:
+		o.Security = []openapi.SecurityRequirement{{bearerAuth: {}}}
+		o.Description = "Requires session token of signed in (not guest) user"
+	}
+
+	if schema := r.apiRequestSchema(op); schema != nil {
+		o.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content:  openapi.JSONContent(schema),
+		}
+	}
+
+	statuses := append([]int{http.StatusBadRequest, http.StatusInternalServerError}, op.errors...)
+	if security != securityNone {
+		statuses = append(statuses, http.StatusUnauthorized)
+	}
+
+	for _, status := range statuses {
+		o.Responses[strconv.Itoa(status)] = &openapi.Response{
+			Description: http.StatusText(status),
+			Content:     openapi.JSONContent(openapi.Ref("ErrorResponse")),
+		}
+	}
+
+	return o
+}
+
+// apiRequestSchema returns request schema of operation. Requests of several auth methods are joined with oneOf
+func (r *Rauther) apiRequestSchema(op apiOperation) *openapi.Schema {
+	if op.request != nil {
+		return openapi.SchemaOf(op.request)
+	}
+
+	if op.authRequest == nil || r.methods == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(r.methods.List))
+
+	for key, at := range r.methods.List {
+		if at.Type == op.authType && op.authRequest(at) != nil {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	schemas := make([]*openapi.Schema, 0, len(keys))
+
+	for _, key := range keys {
+		schema := openapi.SchemaOf(op.authRequest(r.methods.List[key]))
+		schema.Title = key
+
+		// default selector reads key of auth method from "type" field
+		if len(keys) > 1 && r.defaultSelector() {
+			schema.Properties["type"] = &openapi.Schema{
+				Type:        "string",
+				Description: "Auth method key",
+				Enum:        []interface{}{key},
+			}
+			schema.Required = append(schema.Required, "type")
+		}
+
+		schemas = append(schemas, schema)
+	}
+
+	switch len(schemas) {
+	case 0:
+		return nil
+	case 1:
+		return schemas[0]
+	default:
+		return &openapi.Schema{OneOf: schemas}
+	}
+}
+
+func (r *Rauther) defaultSelector() bool {
+	if r.methods.Selector != nil {
+		return false
+	}
+
+	return r.methods.ContextSelector == nil ||
+		reflect.ValueOf(r.methods.ContextSelector).Pointer() == reflect.ValueOf(authtype.DefaultContextSelector).Pointer()
+}
+
+func (r *Rauther) apiOperations() map[string]apiOperation { // nolint:funlen
+	routes := r.Config.Routes
+
+	signUpRequest := func(at authtype.AuthMethod) interface{} { return at.SignUpRequest }
+	signInRequest := func(at authtype.AuthMethod) interface{} { return at.SignInRequest }
+
+	list := []apiOperation{
+		{
+			route: routes.Auth, method: http.MethodPost, id: "auth", tag: "session",
+			summary: "Create session of device or refresh token", request: authRequest{}, response: "AuthResponse",
+		},
+		{
+			route: routes.Auth, method: http.MethodGet, id: "checkAuth", tag: "session",
+			summary: "Check session token", response: "Result",
+		},
+		{
+			route: routes.SignOut, method: http.MethodPost, id: "signOut", tag: "session",
+			summary: "Sign out and refresh token", response: "SignOutResponse",
+		},
+		{
+			route: routes.SignUp, method: http.MethodPost, id: "signUp", tag: "password",
+			summary: "Sign up with password", response: "SignUpResponse",
+			authType: authtype.Password, authRequest: signUpRequest,
+		},
+		{
+			route: routes.SignIn, method: http.MethodPost, id: "signIn", tag: "password",
+			summary: "Sign in with password", response: "Result",
+			authType: authtype.Password, authRequest: signInRequest, errors: []int{http.StatusForbidden},
+		},
+		{
+			route: routes.ValidateLoginField, method: http.MethodPost, id: "validateLoginField", tag: "password",
+			summary: "Check that UID is not registered", response: "Result",
+			authType: authtype.Password,
+			authRequest: func(at authtype.AuthMethod) interface{} {
+				if at.CheckUserExistsRequest == nil {
+					return nil
+				}
+
+				return at.CheckUserExistsRequest
+			},
+		},
+		{
+			route: routes.ConfirmCode, method: http.MethodPost, id: "confirm", tag: "confirmation",
+			summary: "Confirm UID with code", request: confirmRequest{}, response: "Result",
+		},
+		{
+			route: routes.ConfirmLink, method: http.MethodGet, id: "confirmLink", tag: "confirmation",
+			summary: "Confirm UID by signed link, redirects to Config.ConfirmLink URLs if they are set", response: "Result",
+		},
+		{
+			route: routes.ConfirmResend, method: http.MethodPost, id: "resendConfirmCode", tag: "confirmation",
+			summary: "Resend confirmation code", response: "Result",
+			errors: []int{http.StatusTooManyRequests},
+		},
+		{
+			route: routes.RecoveryRequest, method: http.MethodPost, id: "requestRecovery", tag: "recovery",
+			summary: "Send password recovery code", request: recoveryRequest{},
+			response: "Result", errors: []int{http.StatusTooManyRequests},
+		},
+		{
+			route: routes.RecoveryValidateCode, method: http.MethodPost, id: "validateRecoveryCode", tag: "recovery",
+			summary: "Check password recovery code", request: recoveryValidationRequest{},
+			response: "Result",
+		},
+		{
+			route: routes.RecoveryCode, method: http.MethodPost, id: "resetPassword", tag: "recovery",
+			summary: "Set new password with recovery code", request: recoveryResetRequest{},
+			response: "Result",
+		},
+		{
+			route: routes.RecoveryLink, method: http.MethodGet, id: "recoveryLink", tag: "recovery",
+			summary:  "Exchange password reset link for reset token, redirects to Config.ResetLink URLs if they are set",
+			response: "ResetTokenResponse",
+		},
+		{
+			route: routes.RecoveryLinkReset, method: http.MethodPost, id: "resetPasswordByToken", tag: "recovery",
+			summary: "Set new password with reset token and sign out all sessions of user",
+			request: recoveryLinkResetRequest{}, response: "Result",
+		},
+		{
+			route: routes.SocialSignIn, method: http.MethodPost, id: "socialSignIn", tag: "social",
+			summary: "Sign in with token of social provider", response: "Result",
+			authType: authtype.Social, errors: []int{http.StatusConflict},
+			authRequest: func(at authtype.AuthMethod) interface{} {
+				if at.SocialSignInRequest == nil {
+					return nil
+				}
+
+				return at.SocialSignInRequest
+			},
+		},
+		{
+			route: routes.OTPRequestCode, method: http.MethodPost, id: "requestOTPCode", tag: "otp",
+			summary: "Send one time password", response: "Result",
+			authType: authtype.OTP, authRequest: signUpRequest, errors: []int{http.StatusTooManyRequests},
+		},
+		{
+			route: routes.OTPCheckCode, method: http.MethodPost, id: "otpAuth", tag: "otp",
+			summary: "Sign in with one time password", response: "Result",
+			authType: authtype.OTP, authRequest: signInRequest, errors: []int{http.StatusConflict},
+		},
+		{
+			route: routes.InitLink, method: http.MethodPost, id: "initLink", tag: "link",
+			summary: "Start linking of password auth identity", request: initLinkRequest{},
+			response: "InitLinkResponse", errors: []int{http.StatusTooManyRequests},
+		},
+		{
+			route: routes.Link, method: http.MethodPost, id: "link", tag: "link",
+			summary: "Link password auth identity", request: linkAccountRequest{},
+			response: "Result", errors: []int{http.StatusForbidden, http.StatusConflict},
+		},
+		{
+			route: routes.ChangeUID, method: http.MethodPost, id: "changeUID", tag: "uid",
+			summary: "Send code to new UID of current user", request: changeUIDRequest{},
+			response: "Result", errors: []int{http.StatusTooManyRequests},
+		},
+		{
+			route: routes.ConfirmUIDChange, method: http.MethodPost, id: "confirmUIDChange", tag: "uid",
+			summary: "Set new UID with code", request: confirmUIDChangeRequest{},
+			response: "Result",
+		},
+	}
+
+	operations := make(map[string]apiOperation, len(list))
+
+	for _, op := range list {
+		operations[op.method+" "+transport.JoinPaths("", op.route)] = op
+	}
+
+	return operations
+}
+
+// openAPIPath converts path params of router (":id", "*path") to OpenAPI params
+func openAPIPath(path string) (string, []*openapi.Parameter) {
+	var params []*openapi.Parameter
+
+	parts := strings.Split(path, "/")
+
+	for i, part := range parts {
+		if part == "" || (part[0] != ':' && part[0] != '*') {
+			continue
+		}
+
+		name := part[1:]
+		parts[i] = "{" + name + "}"
+
+		params = append(params, &openapi.Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &openapi.Schema{Type: "string"},
+		})
+	}
+
+	return strings.Join(parts, "/"), params
+}
+
+func apiSchemas() map[string]*openapi.Schema {
+	result := &openapi.Schema{Type: "boolean"}
+	str := &openapi.Schema{Type: "string"}
+
+	okSchema := func(props map[string]*openapi.Schema) *openapi.Schema {
+		s := &openapi.Schema{
+			Type:       "object",
+			Properties: map[string]*openapi.Schema{"result": result},
+			Required:   []string{"result"},
+			// hooks can add fields to response
+			AdditionalProperties: true,
+		}
+
+		for k, v := range props {
+			s.Properties[k] = v
+			s.Required = append(s.Required, k)
+		}
+
+		sort.Strings(s.Required)
+
+		return s
+	}
+
+	return map[string]*openapi.Schema{
+		"Result":             okSchema(nil),
+		"AuthResponse":       okSchema(map[string]*openapi.Schema{"device_id": str, "token": str}),
+		"SignUpResponse":     okSchema(map[string]*openapi.Schema{"uid": str}),
+		"SignOutResponse":    okSchema(map[string]*openapi.Schema{"token": str}),
+		"ResetTokenResponse": okSchema(map[string]*openapi.Schema{"token": str}),
+		"InitLinkResponse": okSchema(map[string]*openapi.Schema{
+			actionKey:              {Type: "string", Enum: []interface{}{linkAction, mergeAction}},
+			confirmCodeRequiredKey: {Type: "boolean"},
+		}),
+		"Error": errorSchema(),
+		"ErrorResponse": {
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"result": result,
+				"error":  openapi.Ref("Error"),
+				"info": {
+					Description: "Additional data: ResendCodeInfo for code_timeout, MergeInfo for merge_warning",
+					OneOf:       []*openapi.Schema{openapi.Ref("ResendCodeInfo"), openapi.Ref("MergeInfo")},
+				},
+			},
+			Required: []string{"error", "result"},
+		},
+		"ResendCodeInfo": openapi.SchemaOf(common.ResendCodeErrInfo{}),
+		"MergeInfo": {
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"lost": {
+					Type:        "array",
+					Description: "Auth identities that will be lost after merge",
+					Items:       openapi.SchemaOf(authDescrip{}),
+				},
+				"data": {Description: "Custom merge info of user"},
+			},
+		},
+	}
+}
+
+// errorSchema returns schema of error with codes of common.Errors
+func errorSchema() *openapi.Schema {
+	types := make([]int, 0, len(common.Errors))
+	for t := range common.Errors {
+		types = append(types, int(t))
+	}
+
+	sort.Ints(types)
+
+	// several error types can have same code
+	codes := make([]interface{}, 0, len(types))
+	messages := map[string][]string{}
+
+	for _, t := range types {
+		e := common.Errors[common.ErrTypes(t)]
+		if _, ok := messages[e.Code]; !ok {
+			codes = append(codes, e.Code)
+		}
+
+		messages[e.Code] = append(messages[e.Code], e.Message)
+	}
+
+	description := strings.Builder{}
+	description.WriteString("Error codes:\n")
+
+	for _, code := range codes {
+		description.WriteString("- `" + code.(string) + "`: " + strings.Join(messages[code.(string)], "; ") + "\n")
+	}
+
+	return &openapi.Schema{
+		Type:        "object",
+		Description: description.String(),
+		Properties: map[string]*openapi.Schema{
+			"code":    {Type: "string", Enum: codes},
+			"message": {Type: "string"},
+		},
+		Required: []string{"code", "message"},
+	}
+}
@@ -0,0 +1,205 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nolint:gochecknoglobals
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// SchemaOf returns schema of value type. Struct fields are named by json tags,
+// binding tags (validator rules of gin) are converted to required list and constraints
+func SchemaOf(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+
+	return schemaOfType(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+func schemaOfType(t reflect.Type, visited map[reflect.Type]bool) *Schema { // nolint:cyclop
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() { // nolint:exhaustive
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: schemaOfType(t.Elem(), visited)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOfType(t.Elem(), visited)}
+	case reflect.Struct:
+		if visited[t] || reflect.PtrTo(t).Implements(marshalerType) {
+			return &Schema{Type: "object"}
+		}
+
+		visited[t] = true
+		defer delete(visited, t)
+
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		addFields(s, t, visited)
+
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+func addFields(s *Schema, t reflect.Type, visited map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, opts := parseTag(f.Tag.Get("json"))
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		// embedded struct without name: fields are promoted like in encoding/json
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				addFields(s, ft, visited)
+				continue
+			}
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fs := schemaOfType(f.Type, visited)
+		if f.Type.Kind() == reflect.Ptr {
+			fs.Nullable = true
+		}
+
+		if applyBinding(fs, f.Tag.Get("binding")) {
+			s.Required = append(s.Required, name)
+		}
+
+		if description := f.Tag.Get("description"); description != "" {
+			fs.Description = description
+		}
+
+		s.Properties[name] = fs
+	}
+}
+
+func parseTag(tag string) (name, opts string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+
+	return tag, ""
+}
+
+// applyBinding applies validator rules to schema and returns true if field is required
+func applyBinding(s *Schema, binding string) (required bool) { // nolint:cyclop
+	if binding == "" {
+		return false
+	}
+
+	for _, rule := range strings.Split(binding, ",") {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+
+		switch name {
+		case "dive":
+			// rules after dive are for items
+			return required
+		case "required":
+			required = true
+		case "email":
+			s.Format = "email"
+		case "url", "uri":
+			s.Format = "uri"
+		case "uuid", "uuid4":
+			s.Format = "uuid"
+		case "e164":
+			s.Pattern = `^\+[1-9]?[0-9]{7,14}$`
+		case "numeric":
+			s.Pattern = `^[-+]?[0-9]+(?:\.[0-9]+)?$`
+		case "alphanum":
+			s.Pattern = `^[a-zA-Z0-9]+$`
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, v)
+			}
+		case "min", "gte":
+			setMin(s, param)
+		case "max", "lte":
+			setMax(s, param)
+		case "len":
+			setMin(s, param)
+			setMax(s, param)
+		}
+	}
+
+	return required
+}
+
+func setMin(s *Schema, param string) {
+	switch s.Type {
+	case "string":
+		if n, err := strconv.Atoi(param); err == nil {
+			s.MinLength = &n
+		}
+	case "array":
+		if n, err := strconv.Atoi(param); err == nil {
+			s.MinItems = &n
+		}
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(param, 64); err == nil {
+			s.Minimum = &n
+		}
+	}
+}
+
+func setMax(s *Schema, param string) {
+	switch s.Type {
+	case "string":
+		if n, err := strconv.Atoi(param); err == nil {
+			s.MaxLength = &n
+		}
+	case "array":
+		if n, err := strconv.Atoi(param); err == nil {
+			s.MaxItems = &n
+		}
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(param, 64); err == nil {
+			s.Maximum = &n
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package rauther_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rosberry/rauther"
+	"github.com/rosberry/rauther/deps"
+	"github.com/rosberry/rauther/example/basic/models"
+	"github.com/rosberry/rauther/sender/sendertest"
+	"github.com/rosberry/rauther/transport/httptransport"
+)
+
+func TestOpenAPISecurityOfRoutes(t *testing.T) {
+	users := &models.UserStorer{Users: map[uint]*models.User{}}
+	sessions := &models.Sessioner{Sessions: map[string]*models.Session{}}
+
+	r := rauther.New(deps.NewWithRouter(httptransport.New(http.NewServeMux()), deps.Storage{SessionStorer: sessions, UserStorer: users})) // nolint:lll
+	r.DefaultSender(sendertest.New())
+
+	if err := r.InitHandlers(); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := r.OpenAPI()
+
+	tests := []struct {
+		method, path string
+		secured      bool
+	}{
+		{method: "post", path: "/auth"},
+		{method: "get", path: "/auth", secured: true},
+		{method: "post", path: "/login", secured: true},
+		{method: "post", path: "/confirm"},
+		{method: "post", path: "/auth/uid/change", secured: true},
+	}
+
+	for _, tt := range tests {
+		item, ok := doc.Paths[tt.path]
+		if !ok {
+			t.Errorf("%s %s: path not found", tt.method, tt.path)
+
+			continue
+		}
+
+		op := (*item)[tt.method]
+		if op == nil {
+			t.Errorf("%s %s: operation not found", tt.method, tt.path)
+
+			continue
+		}
+
+		if secured := len(op.Security) > 0; secured != tt.secured {
+			t.Errorf("%s %s: secured = %v, want %v", tt.method, tt.path, secured, tt.secured)
+		}
+
+		if _, ok := op.Responses["401"]; ok != tt.secured {
+			t.Errorf("%s %s: 401 response = %v, want %v", tt.method, tt.path, ok, tt.secured)
+		}
+	}
+}
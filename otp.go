@@ -1,33 +1,46 @@
 package rauther
 
 import (
-	"errors"
-	"log"
+	"context"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gin-gonic/gin/binding"
+	"github.com/rosberry/rauther/audit"
 	"github.com/rosberry/rauther/authtype"
 	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/hooks"
+	"github.com/rosberry/rauther/logger"
+	"github.com/rosberry/rauther/metrics"
 	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/transport"
 	"github.com/rosberry/rauther/user"
 )
 
-func (r *Rauther) otpGetCodeHandler(c *gin.Context) {
+type otpInput struct {
+	at           *authtype.AuthMethod
+	uid          string
+	code         string
+	fields       map[string]interface{}
+	confirmMerge bool
+	session      sessionInfo
+	tContext     transport.Context
+}
+
+func (r *Rauther) otpGetCodeHandler(c transport.Context) {
 	at, ok := r.findAuthMethod(c, authtype.OTP)
 	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
 
 	request := clone(at.SignUpRequest).(authtype.AuthRequest)
 
-	err := c.ShouldBindBodyWith(request, binding.JSON)
+	err := c.BindJSON(request)
 	if err != nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
@@ -37,65 +50,48 @@ func (r *Rauther) otpGetCodeHandler(c *gin.Context) {
 		return
 	}
 
-	uid := request.GetUID()
-	if uid == "" {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
-
+	uid, err := r.requestOTP(contextWithRequest(requestContext(c), request), at, request.GetUID(), sessionInfo)
+	if err != nil {
+		r.flowErrorResponse(c, err)
 		return
 	}
 
-	var linkAccount bool
+	r.respond(c, Response{Route: RouteOTPRequest, AuthKey: at.Key, UID: uid, Session: sessionInfo.Session})
+}
+
+func (r *Rauther) requestOTP(ctx context.Context, at *authtype.AuthMethod, uid string, sessionInfo sessionInfo) (_ string, err error) { // nolint:lll
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowOTPRequest, at)
+	defer endFlow(&err)
+
+	if uid, err = r.normalizeUID(at, uid); err != nil {
+		return "", err
+	}
+
+	if uid == "" {
+		return "", newError(http.StatusBadRequest, common.ErrInvalidRequest)
+	}
 
 	var u user.User
 
 	if sessionInfo.User != nil && !sessionInfo.UserIsGuest {
 		if !r.Modules.LinkAccount {
-			errorResponse(c, http.StatusBadRequest, common.ErrAlreadyAuth)
-			return
+			return "", newError(http.StatusBadRequest, common.ErrAlreadyAuth)
 		}
 
 		if at.DisableLink {
-			errorResponse(c, http.StatusBadRequest, common.ErrLinkingNotAllowed)
-			return
+			return "", newError(http.StatusBadRequest, common.ErrLinkingNotAllowed)
 		}
 
-		u, err = r.initLinkAccount(sessionInfo, at.Key, uid)
+		u, err = r.initLinkAccount(ctx, sessionInfo, at.Key, uid)
 		if err != nil {
-			log.Print(err)
-
-			var customErr CustomError
-
-			switch {
-			case errors.Is(err, errAuthIdentityExists):
-				errorResponse(c, http.StatusBadRequest, common.ErrAuthIdentityExists)
-			case errors.Is(err, errCurrentUserNotConfirmed):
-				errorResponse(c, http.StatusBadRequest, common.ErrUserNotConfirmed)
-			case errors.Is(err, errUserAlreadyRegistered):
-				errorResponse(c, http.StatusBadRequest, common.ErrUserExist)
-			case errors.Is(err, errCannotMergeSelf):
-				errorResponse(c, http.StatusBadRequest, common.ErrCannotMergeSelf)
-			case errors.As(err, &customErr):
-				customErrorResponse(c, customErr)
-			default:
-				errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
-			}
-
-			return
+			r.logger.Log(logger.DebugLevel, "init link account", logger.AuthMethod(at.Key), logger.UserID(sessionInfo.UserID), logger.Err(err))
+			return "", linkError(err)
 		}
-
-		linkAccount = true
-	}
-
-	if !linkAccount {
+	} else {
 		// Find user by UID
-		u, err = r.deps.UserStorer.LoadByUID(at.Key, uid)
-		if err != nil {
-			log.Print(err)
-			var customErr CustomError
-			if errors.As(err, &customErr) {
-				customErrorResponse(c, customErr)
-				return
-			}
+		u, err = r.users(ctx).LoadByUID(at.Key, uid)
+		if err := r.loadError(err); err != nil {
+			return "", err
 		}
 
 		// User not found
@@ -114,51 +110,44 @@ func (r *Rauther) otpGetCodeHandler(c *gin.Context) {
 		}
 	}
 
+	if err := r.beforeHook(ctx, r.hooks.BeforeOTPRequest, hooks.Input{AuthMethod: at, UID: uid, User: u}, sessionInfo); err != nil {
+		return "", err
+	}
+
 	// Check last send time
 	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
 		curTime := time.Now()
-		if resendTime, ok := r.checkResendTime(u, curTime, at); !ok {
-			resp, code := getCodeTimeoutResponse(*resendTime, curTime)
-			c.JSON(code, resp)
-
-			return
+		if err := r.checkCodeTimeout(u, curTime, at); err != nil {
+			return "", err
 		}
 
 		u.(user.CodeSentTimeUser).SetCodeSentTime(at.Key, &curTime)
 	}
 
-	code := r.generateCode(at)
+	code := r.generateCode(at, sender.ConfirmationEvent)
 
-	err = u.(user.OTPAuth).SetOTP(at.Key, code)
-	if err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrUnknownError)
-		return
+	if err = u.(user.OTPAuth).SetOTP(at.Key, code); err != nil {
+		return "", wrapError(http.StatusInternalServerError, common.ErrUnknownError, err)
 	}
 
-	err = at.Sender.Send(sender.ConfirmationEvent, uid, code)
-	if err != nil {
-		log.Printf("send OTP code error: %v", err)
-		errorResponse(c, http.StatusInternalServerError, common.ErrUnknownError)
-
-		return
+	if err = r.sendConfirmCode(ctx, at.Sender, uid, code); err != nil {
+		return "", sendError(err)
 	}
 
-	if err = r.deps.UserStorer.Save(u); err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
-		return
+	if err = r.users(ctx).Save(u); err != nil {
+		return "", wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"result": true,
-	})
+	r.publish(ctx, events.OTPRequested{User: u, AuthKey: at.Key, UID: uid})
+
+	return uid, nil
 }
 
-func (r *Rauther) otpAuthHandler(c *gin.Context) {
+func (r *Rauther) otpAuthHandler(c transport.Context) {
 	// Check auth method
 	at, ok := r.findAuthMethod(c, authtype.OTP)
 	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
@@ -166,17 +155,9 @@ func (r *Rauther) otpAuthHandler(c *gin.Context) {
 	request := clone(at.SignInRequest).(authtype.AuthRequest)
 
 	// Check request data
-	err := c.ShouldBindBodyWith(request, binding.JSON)
+	err := c.BindJSON(request)
 	if err != nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
-
-		return
-	}
-
-	uid, code := request.GetUID(), request.GetPassword()
-
-	if uid == "" || code == "" {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
@@ -187,50 +168,106 @@ func (r *Rauther) otpAuthHandler(c *gin.Context) {
 		return
 	}
 
+	result, err := r.verifyOTP(contextWithRequest(requestContext(c), request), otpInput{
+		at:           at,
+		uid:          request.GetUID(),
+		code:         request.GetPassword(),
+		fields:       requestFields(request),
+		confirmMerge: requestConfirmMerge(request),
+		session:      sessionInfo,
+		tContext:     c,
+	})
+	if err != nil {
+		r.flowErrorResponse(c, err)
+		return
+	}
+
+	if !result.Linked {
+		c.Set(r.Config.ContextNames.User, result.User)
+		c.Set(r.Config.ContextNames.Session, sessionInfo.Session)
+	}
+
+	respMap := gin.H{
+		"result": true,
+	}
+
+	if result.IsNew {
+		if r.hooks.AfterOTPSignUp != nil {
+			r.hooks.AfterOTPSignUp(respMap, sessionInfo.Session, result.User, at.Key)
+		}
+	} else if r.hooks.AfterOTPSignIn != nil {
+		r.hooks.AfterOTPSignIn(respMap, sessionInfo.Session, result.User, at.Key)
+	}
+
+	r.respond(c, Response{
+		Route:   RouteOTPSignIn,
+		AuthKey: at.Key,
+		UID:     result.UID,
+		IsNew:   result.IsNew,
+		Linked:  result.Linked,
+		User:    result.User,
+		Session: sessionInfo.Session,
+		Fields:  respMap,
+	})
+}
+
+func (r *Rauther) verifyOTP(ctx context.Context, in otpInput) (res *OTPResult, err error) { // nolint:cyclop
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowOTPVerify, in.at)
+	defer endFlow(&err)
+
+	if in.uid, err = r.normalizeUID(in.at, in.uid); err != nil {
+		return nil, err
+	}
+
+	at, sessionInfo := in.at, in.session
+
+	rec := r.startAudit(ctx, audit.SignIn, at, in.uid)
+	defer rec.end(&err)
+	rec.setSession(sessionInfo)
+
+	if in.uid == "" || in.code == "" {
+		return nil, newError(http.StatusBadRequest, common.ErrInvalidRequest)
+	}
+
 	// Check User in current session
 	var linkAccount bool
 
 	if sessionInfo.User != nil && !sessionInfo.UserIsGuest {
 		if !r.Modules.LinkAccount {
-			errorResponse(c, http.StatusBadRequest, common.ErrAlreadyAuth)
-			return
+			return nil, newError(http.StatusBadRequest, common.ErrAlreadyAuth)
 		}
 
 		if at.DisableLink {
-			errorResponse(c, http.StatusBadRequest, common.ErrLinkingNotAllowed)
-			return
+			return nil, newError(http.StatusBadRequest, common.ErrLinkingNotAllowed)
 		}
 
 		linkAccount = true
+		rec.event.Type = audit.Link
 	}
 
 	// Find user by UID
-	u, err := r.LoadByUID(at.Key, uid)
-	if err != nil {
-		log.Print(err)
-		var customErr CustomError
-		if errors.As(err, &customErr) {
-			customErrorResponse(c, customErr)
-			return
-		}
+	u, err := r.loadByUID(ctx, at.Key, in.uid)
+	if err := r.loadError(err); err != nil {
+		return nil, err
 	}
 
 	if u == nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
-		return
+		return nil, r.uniformError(newError(http.StatusBadRequest, common.ErrUserNotFound), common.ErrInvalidCode)
+	}
+
+	if !linkAccount {
+		rec.setUser(u)
 	}
 
 	if r.Modules.LinkAccount {
 		isTempUser := u.(user.TempUser).IsTemp()
 
 		if isTempUser && !linkAccount {
-			errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
-			return
+			return nil, r.uniformError(newError(http.StatusBadRequest, common.ErrUserNotFound), common.ErrInvalidCode)
 		}
 
 		if !r.Modules.MergeAccount && !isTempUser && linkAccount {
-			errorResponse(c, http.StatusBadRequest, common.ErrUserExist)
-			return
+			return nil, newError(http.StatusBadRequest, common.ErrUserExist)
 		}
 	}
 
@@ -238,44 +275,52 @@ func (r *Rauther) otpAuthHandler(c *gin.Context) {
 	userCode := u.(user.OTPAuth).GetOTP(at.Key)
 
 	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
-		codeSent := u.(user.CodeSentTimeUser).GetCodeSentTime(at.Key)
-
-		expiredAt := calcExpiredAt(codeSent, r.Config.OTP.CodeLifeTime)
-
-		if expiredAt.Before(time.Now()) {
-			errorResponse(c, http.StatusBadRequest, common.ErrCodeExpired)
-			return
+		if err := r.checkCodeExpired(u, at, r.Config.OTP.CodeLifeTime); err != nil {
+			return nil, r.uniformError(err, common.ErrInvalidCode)
 		}
 
 		u.(user.CodeSentTimeUser).SetCodeSentTime(at.Key, nil)
 	}
 
-	if code != userCode {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidCode)
-		return
+	if !at.VerifyCode(userCode, in.code) {
+		return nil, newError(http.StatusBadRequest, common.ErrInvalidCode)
+	}
+
+	if err := r.beforeHook(ctx, r.hooks.BeforeOTPVerify, hooks.Input{AuthMethod: at, UID: in.uid, User: u}, sessionInfo); err != nil {
+		return nil, err
 	}
 
 	isNew := !u.(user.OTPAuth).GetConfirmed(at.Key)
+	if isNew && !linkAccount {
+		rec.event.Type = audit.SignUp
+	}
 
 	// If current user is GUEST, and OTP user is guest (new user) - use current user as actual
+	var guestConverted bool
+
 	if r.Modules.GuestUser && sessionInfo.UserIsGuest && !linkAccount {
 		var removeUserID interface{}
 
 		if u.(user.GuestUser).IsGuest() {
-			sessionInfo.User.(user.AuthableUser).SetUID(at.Key, uid)
+			sessionInfo.User.(user.AuthableUser).SetUID(at.Key, in.uid)
 			sessionInfo.User.(user.GuestUser).SetGuest(false)
 
 			removeUserID = u.GetID()
 
 			u = sessionInfo.User
+			rec.setUser(u)
+
+			guestConverted = true
 		} else {
 			removeUserID = sessionInfo.UserID
 		}
 
-		err := r.deps.Storage.UserRemover.RemoveByID(removeUserID)
-		if err != nil {
-			log.Printf("Failed delete guest user %v: %v", sessionInfo.UserID, err)
-		}
+		r.removeGuestUser(ctx, removeUserID)
+	}
+
+	// user created by code request without guest session
+	if r.Modules.GuestUser && !linkAccount && u.(user.GuestUser).IsGuest() {
+		u.(user.GuestUser).SetGuest(false)
 	}
 
 	if !u.(user.OTPAuth).GetConfirmed(at.Key) {
@@ -283,82 +328,47 @@ func (r *Rauther) otpAuthHandler(c *gin.Context) {
 	}
 
 	if linkAccount {
-		var mergeConfirm bool
-
-		if requestWithMergeConfirm, ok := request.(authtype.MergeConfirmRequest); ok {
-			mergeConfirm = requestWithMergeConfirm.GetConfirmMerge()
-		}
-
-		err := r.linkAccount(sessionInfo, u, at, mergeConfirm, c)
+		err := r.linkAccount(ctx, sessionInfo, u, at, in.confirmMerge, in.tContext)
 		if err != nil {
-			var mergeErr MergeError
-			var customErr CustomError
-
-			switch {
-			case errors.Is(err, errAuthIdentityExists):
-				errorResponse(c, http.StatusBadRequest, common.ErrAuthIdentityExists)
-			case errors.Is(err, errCurrentUserNotConfirmed):
-				errorResponse(c, http.StatusBadRequest, common.ErrUserNotConfirmed)
-			case errors.Is(err, errUserAlreadyRegistered):
-				errorResponse(c, http.StatusBadRequest, common.ErrUserExist)
-			case errors.Is(err, errCannotMergeSelf):
-				errorResponse(c, http.StatusBadRequest, common.ErrCannotMergeSelf)
-			case errors.As(err, &customErr):
-				customErrorResponse(c, customErr)
-			case errors.As(err, &mergeErr):
-				mergeErrorResponse(c, mergeErr)
-			default:
-				errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
-			}
-
-			return
+			return nil, linkError(err)
 		}
 
-		if err = r.deps.UserStorer.Save(sessionInfo.User); err != nil {
-			errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
-			return
+		if err = r.users(ctx).Save(sessionInfo.User); err != nil {
+			return nil, wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 		}
-	} else {
+
+		return &OTPResult{User: u, UID: in.uid, IsNew: isNew, Linked: true}, nil
+	}
+
+	if sessionInfo.Session != nil {
 		sessionInfo.Session.BindUser(u)
-		err = r.deps.SessionStorer.Save(sessionInfo.Session)
-		if err != nil {
-			errorResponse(c, http.StatusInternalServerError, common.ErrSessionSave)
-			return
-		}
 
-		if fieldableRequest, ok := request.(authtype.AuthRequestFieldable); ok {
-			if ok := r.fillFields(fieldableRequest, u); !ok {
-				errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
-				return
-			}
+		if err = r.sessions(ctx).Save(sessionInfo.Session); err != nil {
+			return nil, wrapError(http.StatusInternalServerError, common.ErrSessionSave, err)
 		}
+	}
 
-		err = u.(user.OTPAuth).SetOTP(at.Key, "")
-		if err != nil {
-			errorResponse(c, http.StatusInternalServerError, common.ErrUnknownError)
-			return
-		}
+	if ok := r.fillFields(in.fields, u); !ok {
+		return nil, newError(http.StatusBadRequest, common.ErrInvalidRequest)
+	}
 
-		if err = r.deps.UserStorer.Save(u); err != nil {
-			errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
-			return
-		}
+	if err = u.(user.OTPAuth).SetOTP(at.Key, ""); err != nil {
+		return nil, wrapError(http.StatusInternalServerError, common.ErrUnknownError, err)
+	}
 
-		c.Set(r.Config.ContextNames.User, u)
-		c.Set(r.Config.ContextNames.Session, sessionInfo.Session)
+	if err = r.users(ctx).Save(u); err != nil {
+		return nil, wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 	}
 
-	respMap := gin.H{
-		"result": true,
+	if guestConverted {
+		r.publish(ctx, events.GuestConverted{User: u, AuthKey: at.Key, UID: in.uid})
 	}
 
 	if isNew {
-		if r.hooks.AfterOTPSignUp != nil {
-			r.hooks.AfterOTPSignUp(respMap, sessionInfo.Session, u, at.Key)
-		}
-	} else if r.hooks.AfterOTPSignIn != nil {
-		r.hooks.AfterOTPSignIn(respMap, sessionInfo.Session, u, at.Key)
+		r.publish(ctx, events.UserSignedUp{User: u, AuthKey: at.Key, UID: in.uid, Session: sessionInfo.Session})
+	} else {
+		r.publish(ctx, events.UserSignedIn{User: u, AuthKey: at.Key, UID: in.uid, Session: sessionInfo.Session})
 	}
 
-	c.JSON(http.StatusOK, respMap)
+	return &OTPResult{User: u, UID: in.uid, IsNew: isNew}, nil
 }
@@ -1,17 +1,21 @@
 package rauther
 
 import (
-	"errors"
-	"log"
+	"context"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gin-gonic/gin/binding"
+	"github.com/rosberry/rauther/audit"
 	"github.com/rosberry/rauther/authtype"
 	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/hooks"
+	"github.com/rosberry/rauther/logger"
+	"github.com/rosberry/rauther/metrics"
+	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/transport"
 	"github.com/rosberry/rauther/user"
-	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -19,140 +23,214 @@ const (
 	mergeAction = "merge"
 )
 
-func (r *Rauther) signUpHandler(c *gin.Context) {
+type (
+	signUpInput struct {
+		at       *authtype.AuthMethod
+		uid      string
+		password string
+		fields   map[string]interface{}
+		session  sessionInfo
+	}
+
+	signInInput struct {
+		at       *authtype.AuthMethod
+		uid      string
+		password string
+		session  sessionInfo
+	}
+
+	initLinkRequest struct {
+		UID string `json:"uid" binding:"required"`
+	}
+
+	linkAccountRequest struct {
+		UID          string `json:"uid" binding:"required"`
+		Password     string `json:"password" binding:"required"`
+		Code         string `json:"code"`
+		Merge        bool   `json:"merge"`
+		ConfirmMerge bool   `json:"confirmMerge"`
+	}
+
+	linkInput struct {
+		at       *authtype.AuthMethod
+		request  LinkRequest
+		session  sessionInfo
+		tContext transport.Context
+	}
+)
+
+func (r *Rauther) signUpHandler(c transport.Context) {
 	at, ok := r.findAuthMethod(c, authtype.Password)
 	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
 
 	request := clone(at.SignUpRequest).(authtype.AuthRequest)
 
-	err := c.ShouldBindBodyWith(request, binding.JSON)
+	err := c.BindJSON(request)
 	if err != nil {
-		log.Print("sign up handler:", err)
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.logRequest(c, logger.DebugLevel, "invalid request", logger.Err(err))
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
 
-	uid, password := request.GetUID(), request.GetPassword()
+	sessionInfo, success := r.checkSession(c)
+	if !success {
+		return
+	}
 
-	if uid == "" || password == "" {
-		log.Print("sign up handler: empty uid or password")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+	result, err := r.signUp(contextWithRequest(requestContext(c), request), signUpInput{
+		at:       at,
+		uid:      request.GetUID(),
+		password: request.GetPassword(),
+		fields:   requestFields(request),
+		session:  sessionInfo,
+	})
+	if err != nil {
+		r.flowErrorResponse(c, err)
 
 		return
 	}
 
-	sessionInfo, success := r.checkSession(c)
-	if !success {
-		return
+	c.Set(r.Config.ContextNames.User, result.User)
+	c.Set(r.Config.ContextNames.Session, sessionInfo.Session)
+
+	respMap := gin.H{
+		"result": true,
+		"uid":    result.UID,
+	}
+
+	if r.hooks.AfterPasswordSignUp != nil {
+		r.hooks.AfterPasswordSignUp(respMap, sessionInfo.Session, result.User, at.Key)
+	}
+
+	r.respond(c, Response{
+		Route:   RouteSignUp,
+		AuthKey: at.Key,
+		UID:     result.UID,
+		User:    result.User,
+		Session: sessionInfo.Session,
+		Fields:  respMap,
+	})
+}
+
+func (r *Rauther) signUp(ctx context.Context, in signUpInput) (res *SignUpResult, err error) {
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowSignUp, in.at)
+	defer endFlow(&err)
+
+	if in.uid, err = r.normalizeUID(in.at, in.uid); err != nil {
+		return nil, err
+	}
+
+	at, sessionInfo := in.at, in.session
+
+	rec := r.startAudit(ctx, audit.SignUp, at, in.uid)
+	defer rec.end(&err)
+	rec.setSession(sessionInfo)
+
+	if in.uid == "" || in.password == "" {
+		return nil, newError(http.StatusBadRequest, common.ErrInvalidRequest)
 	}
 
 	if sessionInfo.User != nil && !sessionInfo.UserIsGuest {
-		errorResponse(c, http.StatusBadRequest, common.ErrAlreadyAuth)
-		return
+		return nil, newError(http.StatusBadRequest, common.ErrAlreadyAuth)
 	}
 
 	// Find user by UID
-	u, err := r.deps.UserStorer.LoadByUID(at.Key, uid)
-	if err != nil {
-		log.Print(err)
-		var customErr CustomError
-		if errors.As(err, &customErr) {
-			customErrorResponse(c, customErr)
-			return
-		}
+	u, err := r.users(ctx).LoadByUID(at.Key, in.uid)
+	if err := r.loadError(err); err != nil {
+		return nil, err
 	}
 
 	// User exists
 	if u != nil {
 		if tempUser, ok := u.(user.TempUser); !(ok && tempUser.IsTemp()) {
-			errorResponse(c, http.StatusBadRequest, common.ErrUserExist)
-			return
+			return nil, newError(http.StatusBadRequest, common.ErrUserExist)
 		}
 
-		err := r.deps.Storage.UserRemover.RemoveByID(u.GetID())
+		err := r.remover(ctx).RemoveByID(u.GetID())
 		if err != nil {
-			log.Printf("Failed delete guest user %v: %v", sessionInfo.UserID, err)
+			r.logger.Log(logger.WarnLevel, "failed delete temp user", logger.UserID(u.GetID()), logger.Err(err))
 		}
 	}
 
-	if r.Modules.GuestUser && sessionInfo.UserIsGuest {
+	guestConverted := r.Modules.GuestUser && sessionInfo.UserIsGuest
+
+	if guestConverted {
 		u = sessionInfo.User
 		u.(user.GuestUser).SetGuest(false)
 	} else {
 		u = r.deps.UserStorer.Create()
 	}
 
-	u.(user.AuthableUser).SetUID(at.Key, uid)
+	u.(user.AuthableUser).SetUID(at.Key, in.uid)
 
-	encryptedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	encryptedPassword, err := r.hashPassword(ctx, in.password)
 	if err != nil {
-		log.Print("encrypt password error:", err)
-		errorResponse(c, http.StatusInternalServerError, common.ErrUnknownError)
-
-		return
+		return nil, err
 	}
 
-	u.(user.PasswordAuthableUser).SetPassword(at.Key, string(encryptedPassword))
+	u.(user.PasswordAuthableUser).SetPassword(at.Key, encryptedPassword)
 
-	if fieldableRequest, ok := request.(authtype.AuthRequestFieldable); ok {
-		if ok := r.fillFields(fieldableRequest, u); !ok {
-			errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
-			return
-		}
+	if ok := r.fillFields(in.fields, u); !ok {
+		return nil, newError(http.StatusBadRequest, common.ErrInvalidRequest)
 	}
 
+	if err := r.beforeHook(ctx, r.hooks.BeforeSignUp, hooks.Input{AuthMethod: at, UID: in.uid, User: u}, sessionInfo); err != nil {
+		return nil, err
+	}
+
+	var confirmCodeSent bool
+
 	if r.Modules.ConfirmableUser {
-		r.setAndSendConfirmCode(at, u, uid)
+		confirmCodeSent = r.setAndSendConfirmCode(ctx, at, u, in.uid, at.ConfirmLink) == nil
 	}
 
-	if err = r.deps.UserStorer.Save(u); err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
-		return
+	if err = r.users(ctx).Save(u); err != nil {
+		return nil, wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 	}
 
-	sessionInfo.Session.BindUser(u)
-	c.Set(r.Config.ContextNames.User, u)
-	c.Set(r.Config.ContextNames.Session, sessionInfo.Session)
+	// ID of new user is set by storer
+	rec.setUser(u)
 
-	err = r.deps.SessionStorer.Save(sessionInfo.Session)
-	if err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrSessionSave)
-		return
-	}
+	if sessionInfo.Session != nil {
+		sessionInfo.Session.BindUser(u)
 
-	respMap := gin.H{
-		"result": true,
-		"uid":    uid,
+		if err = r.sessions(ctx).Save(sessionInfo.Session); err != nil {
+			return nil, wrapError(http.StatusInternalServerError, common.ErrSessionSave, err)
+		}
 	}
 
-	if r.hooks.AfterPasswordSignUp != nil {
-		r.hooks.AfterPasswordSignUp(respMap, sessionInfo.Session, u, at.Key)
+	if guestConverted {
+		r.publish(ctx, events.GuestConverted{User: u, AuthKey: at.Key, UID: in.uid})
 	}
 
-	c.JSON(http.StatusOK, respMap)
+	r.publish(ctx, events.UserSignedUp{User: u, AuthKey: at.Key, UID: in.uid, Session: sessionInfo.Session})
+
+	return &SignUpResult{
+		User:            u,
+		UID:             in.uid,
+		ConfirmCodeSent: confirmCodeSent,
+	}, nil
 }
 
-func (r *Rauther) signInHandler(c *gin.Context) {
+func (r *Rauther) signInHandler(c transport.Context) {
 	at, ok := r.findAuthMethod(c, authtype.Password)
 	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
 
 	request := clone(at.SignInRequest).(authtype.AuthRequest)
 
-	err := c.ShouldBindBodyWith(request, binding.JSON)
+	err := c.BindJSON(request)
 	if err != nil {
-		log.Print("sign in handler:", err)
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.logRequest(c, logger.DebugLevel, "invalid request", logger.Err(err))
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
@@ -162,124 +240,170 @@ func (r *Rauther) signInHandler(c *gin.Context) {
 		return
 	}
 
-	if sessionInfo.User != nil && !sessionInfo.UserIsGuest {
-		errorResponse(c, http.StatusBadRequest, common.ErrAlreadyAuth)
+	u, uid, err := r.signIn(contextWithRequest(requestContext(c), request), signInInput{
+		at:       at,
+		uid:      request.GetUID(),
+		password: request.GetPassword(),
+		session:  sessionInfo,
+	})
+	if err != nil {
+		r.flowErrorResponse(c, err)
+
 		return
 	}
 
-	uid, password := request.GetUID(), request.GetPassword()
+	c.Set(r.Config.ContextNames.Session, sessionInfo.Session)
+	c.Set(r.Config.ContextNames.User, u)
 
-	if uid == "" || password == "" {
-		log.Print("sign in handler: empty uid or password")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+	respMap := gin.H{
+		"result": true,
+	}
 
-		return
+	if r.hooks.AfterPasswordSignIn != nil {
+		r.hooks.AfterPasswordSignIn(respMap, sessionInfo.Session, u, at.Key)
 	}
 
-	u, err := r.LoadByUID(at.Key, uid)
-	if err != nil {
-		log.Print(err)
-		var customErr CustomError
-		if errors.As(err, &customErr) {
-			customErrorResponse(c, customErr)
-			return
-		}
+	r.respond(c, Response{
+		Route:   RouteSignIn,
+		AuthKey: at.Key,
+		UID:     uid,
+		User:    u,
+		Session: sessionInfo.Session,
+		Fields:  respMap,
+	})
+}
+
+// signIn returns signed in user and normalized UID
+func (r *Rauther) signIn(ctx context.Context, in signInInput) (u user.User, uid string, err error) {
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowSignIn, in.at)
+	defer endFlow(&err)
+
+	if in.uid, err = r.normalizeUID(in.at, in.uid); err != nil {
+		return nil, "", err
+	}
+
+	at, sessionInfo := in.at, in.session
+
+	rec := r.startAudit(ctx, audit.SignIn, at, in.uid)
+	defer rec.end(&err)
+	rec.setSession(sessionInfo)
+
+	if sessionInfo.User != nil && !sessionInfo.UserIsGuest {
+		return nil, "", newError(http.StatusBadRequest, common.ErrAlreadyAuth)
+	}
+
+	if in.uid == "" || in.password == "" {
+		return nil, "", newError(http.StatusBadRequest, common.ErrInvalidRequest)
+	}
+
+	u, err = r.loadByUID(ctx, at.Key, in.uid)
+	if err := r.loadError(err); err != nil {
+		return nil, "", err
 	}
 
 	if u == nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
-		return
+		r.dummyPasswordCompare(ctx, in.password)
+		return nil, "", r.uniformError(newError(http.StatusBadRequest, common.ErrUserNotFound), common.ErrInvalidCredentials)
 	}
 
+	rec.setUser(u)
+
 	if tempUser, ok := u.(user.TempUser); ok && tempUser.IsTemp() {
 		// TODO: Correct error about user is temporary?
-		errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
-		return
+		r.dummyPasswordCompare(ctx, in.password)
+		return nil, "", r.uniformError(newError(http.StatusBadRequest, common.ErrUserNotFound), common.ErrInvalidCredentials)
 	}
 
 	userPassword := u.(user.PasswordAuthableUser).GetPassword(at.Key)
 
-	if !passwordCompare(password, userPassword) {
-		errorResponse(c, http.StatusForbidden, common.ErrIncorrectPassword)
-		return
+	// user without password of auth method, e.g. signed up by OTP
+	if userPassword == "" {
+		r.dummyPasswordCompare(ctx, in.password)
 	}
 
-	sessionInfo.Session.BindUser(u)
-
-	if err = r.deps.SessionStorer.Save(sessionInfo.Session); err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrSessionSave)
-		return
+	if !r.passwordCompare(ctx, in.password, userPassword) {
+		return nil, "", r.uniformError(newError(http.StatusForbidden, common.ErrIncorrectPassword), common.ErrInvalidCredentials)
 	}
 
-	if err = r.deps.UserStorer.Save(u); err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
-		return
+	if err := r.beforeHook(ctx, r.hooks.BeforeSignIn, hooks.Input{AuthMethod: at, UID: in.uid, User: u}, sessionInfo); err != nil {
+		return nil, "", err
 	}
 
-	if r.Modules.GuestUser && sessionInfo.UserIsGuest {
-		err := r.deps.Storage.UserRemover.RemoveByID(sessionInfo.UserID)
-		if err != nil {
-			log.Printf("Failed delete guest user %v: %v", sessionInfo.UserID, err)
+	if sessionInfo.Session != nil {
+		sessionInfo.Session.BindUser(u)
+
+		if err = r.sessions(ctx).Save(sessionInfo.Session); err != nil {
+			return nil, "", wrapError(http.StatusInternalServerError, common.ErrSessionSave, err)
 		}
 	}
 
-	c.Set(r.Config.ContextNames.Session, sessionInfo.Session)
-	c.Set(r.Config.ContextNames.User, u)
-
-	respMap := gin.H{
-		"result": true,
+	if err = r.users(ctx).Save(u); err != nil {
+		return nil, "", wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 	}
 
-	if r.hooks.AfterPasswordSignIn != nil {
-		r.hooks.AfterPasswordSignIn(respMap, sessionInfo.Session, u, at.Key)
+	if r.Modules.GuestUser && sessionInfo.UserIsGuest {
+		r.removeGuestUser(ctx, sessionInfo.UserID)
 	}
 
-	c.JSON(http.StatusOK, respMap)
+	r.publish(ctx, events.UserSignedIn{User: u, AuthKey: at.Key, UID: in.uid, Session: sessionInfo.Session})
+
+	return u, in.uid, nil
 }
 
-func (r *Rauther) validateLoginField(c *gin.Context) {
+func (r *Rauther) validateLoginField(c transport.Context) {
 	at, ok := r.findAuthMethod(c, authtype.Password)
 	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
 
 	request := clone(at.CheckUserExistsRequest).(authtype.CheckUserExistsRequest)
 
-	err := c.ShouldBindBodyWith(request, binding.JSON)
+	err := c.BindJSON(request)
 	if err != nil {
-		log.Print("validate login field handler:", err)
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.logRequest(c, logger.DebugLevel, "invalid request", logger.Err(err))
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+
+		return
+	}
 
+	uid, err := r.checkUID(requestContext(c), at, request.GetUID())
+	if err != nil {
+		r.flowErrorResponse(c, err)
 		return
 	}
 
-	uid := request.GetUID()
+	r.respond(c, Response{Route: RouteCheckUID, AuthKey: at.Key, UID: uid})
+}
+
+// checkUID returns normalized UID if it is not registered yet. Checks are limited in protection mode
+func (r *Rauther) checkUID(ctx context.Context, at *authtype.AuthMethod, uid string) (_ string, err error) {
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowCheckUID, at)
+	defer endFlow(&err)
+
+	if err := r.allowCheckUID(ctx); err != nil {
+		return "", err
+	}
+
+	if uid, err = r.normalizeUID(at, uid); err != nil {
+		return "", err
+	}
 
 	if uid == "" {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		return "", newError(http.StatusBadRequest, common.ErrInvalidRequest)
+	}
 
-		return
+	u, err := r.loadByUID(ctx, at.Key, uid)
+	if err := r.loadError(err); err != nil {
+		return "", err
 	}
 
-	u, err := r.LoadByUID(at.Key, uid)
-	if err != nil {
-		log.Print(err)
-		var customErr CustomError
-		if errors.As(err, &customErr) {
-			customErrorResponse(c, customErr)
-			return
-		}
-	} else if u != nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrUserExist)
-		return
+	if u != nil {
+		return "", newError(http.StatusBadRequest, common.ErrUserExist)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"result": true,
-	})
+	return uid, nil
 }
 
 const (
@@ -287,145 +411,149 @@ const (
 	confirmCodeRequiredKey = "confirmCodeRequired"
 )
 
-func (r *Rauther) initLinkingPasswordAccount(c *gin.Context) {
+func (r *Rauther) initLinkingPasswordAccount(c transport.Context) {
 	at, ok := r.findAuthMethod(c, authtype.Password)
 	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
 
+	var request initLinkRequest
+
 	if at.DisableLink {
-		errorResponse(c, http.StatusBadRequest, common.ErrLinkingNotAllowed)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrLinkingNotAllowed)
 		return
 	}
 
-	type linkAccountRequest struct {
-		UID string `json:"uid" binding:"required"`
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.logRequest(c, logger.DebugLevel, "invalid request", logger.Err(err))
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+
+		return
 	}
-	var request linkAccountRequest
 
-	err := c.ShouldBindBodyWith(&request, binding.JSON)
+	sessionInfo, success := r.checkSession(c)
+	if !success {
+		return
+	}
+
+	result, err := r.initLink(requestContext(c), at, request.UID, sessionInfo)
 	if err != nil {
-		log.Print("init linking password account:", err)
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		var fields map[string]interface{}
+
+		if result != nil {
+			fields = map[string]interface{}{
+				actionKey:              result.Action,
+				confirmCodeRequiredKey: result.ConfirmCodeRequired,
+			}
+		}
+
+		r.logFlowError(c, err)
+		r.renderError(c, err, fields)
 
 		return
 	}
 
-	uid := request.UID
+	respMap := gin.H{
+		"result":               true,
+		actionKey:              result.Action,
+		confirmCodeRequiredKey: result.ConfirmCodeRequired,
+	}
+
+	r.respond(c, Response{
+		Route:               RouteInitLink,
+		AuthKey:             at.Key,
+		UID:                 result.UID,
+		Action:              result.Action,
+		ConfirmCodeRequired: result.ConfirmCodeRequired,
+		User:                sessionInfo.User,
+		Session:             sessionInfo.Session,
+		Fields:              respMap,
+	})
+}
 
-	if uid == "" {
-		log.Print("init linking password account handler: empty uid")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+// initLink returns partial result with error if code cannot be sent
+func (r *Rauther) initLink(ctx context.Context, at *authtype.AuthMethod, uid string, sessionInfo sessionInfo) (res *InitLinkResult, err error) { // nolint:lll
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowInitLink, at)
+	defer endFlow(&err)
 
-		return
+	if uid, err = r.normalizeUID(at, uid); err != nil {
+		return nil, err
 	}
 
-	sessionInfo, success := r.checkSession(c)
-	if !success {
-		return
+	if at.DisableLink {
+		return nil, newError(http.StatusBadRequest, common.ErrLinkingNotAllowed)
 	}
 
-	u, err := r.initLinkAccount(sessionInfo, at.Key, uid)
+	if uid == "" {
+		return nil, newError(http.StatusBadRequest, common.ErrInvalidRequest)
+	}
+
+	u, err := r.initLinkAccount(ctx, sessionInfo, at.Key, uid)
 	if err != nil {
-		log.Print(err)
-
-		var customErr CustomError
-
-		switch {
-		case errors.Is(err, errAuthIdentityExists):
-			errorResponse(c, http.StatusBadRequest, common.ErrAuthIdentityExists)
-		case errors.Is(err, errCurrentUserNotConfirmed):
-			errorResponse(c, http.StatusBadRequest, common.ErrUserNotConfirmed)
-		case errors.Is(err, errUserAlreadyRegistered):
-			errorResponse(c, http.StatusBadRequest, common.ErrUserExist)
-		case errors.Is(err, errCannotMergeSelf):
-			errorResponse(c, http.StatusBadRequest, common.ErrCannotMergeSelf)
-		case errors.As(err, &customErr):
-			customErrorResponse(c, customErr)
-		default:
-			errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
-		}
+		return nil, linkError(err)
+	}
 
-		return
+	result := &InitLinkResult{
+		UID:    uid,
+		Action: linkAction,
 	}
 
-	action := linkAction
 	if !u.(user.TempUser).IsTemp() {
-		action = mergeAction
+		result.Action = mergeAction
 	}
 
 	// confirmation
-	var confirmCodeRequired bool
-
 	if !u.(user.ConfirmableUser).GetConfirmed(at.Key) {
-		confirmCodeRequired = true
+		result.ConfirmCodeRequired = true
 
 		if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
-			curTime := time.Now()
-			if resendTime, ok := r.checkResendTime(u, curTime, at); !ok {
-				resp, code := getCodeTimeoutResponse(*resendTime, curTime)
-
-				resp[actionKey] = action
-				resp[confirmCodeRequiredKey] = confirmCodeRequired
-
-				c.JSON(code, resp)
-
-				return
+			if err := r.checkCodeTimeout(u, time.Now(), at); err != nil {
+				return result, err
 			}
 		}
 
-		r.setAndSendConfirmCode(at, u, uid)
-	}
-
-	if err = r.deps.UserStorer.Save(u); err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
-		return
+		// code is required by link route
+		if err := r.setAndSendConfirmCode(ctx, at, u, uid, false); err != nil {
+			if e := sendError(err); e.Type == common.ErrRequestCodeTimeout {
+				return result, e
+			}
+		}
 	}
 
-	err = r.deps.SessionStorer.Save(sessionInfo.Session)
-	if err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrSessionSave)
-		return
+	if err = r.users(ctx).Save(u); err != nil {
+		return nil, wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 	}
 
-	respMap := gin.H{
-		"result":               true,
-		actionKey:              action,
-		confirmCodeRequiredKey: confirmCodeRequired,
+	if sessionInfo.Session != nil {
+		err = r.sessions(ctx).Save(sessionInfo.Session)
+		if err != nil {
+			return nil, wrapError(http.StatusInternalServerError, common.ErrSessionSave, err)
+		}
 	}
 
-	c.JSON(http.StatusOK, respMap)
+	return result, nil
 }
 
-func (r *Rauther) linkPasswordAccount(c *gin.Context) {
+func (r *Rauther) linkPasswordAccount(c transport.Context) {
 	at, ok := r.findAuthMethod(c, authtype.Password)
 	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
 
 	if at.DisableLink {
-		errorResponse(c, http.StatusBadRequest, common.ErrLinkingNotAllowed)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrLinkingNotAllowed)
 		return
 	}
 
-	type linkAccountRequest struct {
-		UID          string `json:"uid" binding:"required"`
-		Password     string `json:"password" binding:"required"`
-		Code         string `json:"code"`
-		Merge        bool   `json:"merge"`
-		ConfirmMerge bool   `json:"confirmMerge"`
-	}
-
 	var request linkAccountRequest
 
-	if err := c.ShouldBindBodyWith(&request, binding.JSON); err != nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+	if err := c.BindJSON(&request); err != nil {
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 		return
 	}
 
@@ -434,45 +562,73 @@ func (r *Rauther) linkPasswordAccount(c *gin.Context) {
 		return
 	}
 
-	u, err := r.LoadByUID(at.Key, request.UID)
-	if err != nil || u == nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
+	uid, err := r.link(contextWithRequest(requestContext(c), LinkRequest(request)), linkInput{
+		at:       at,
+		request:  LinkRequest(request),
+		session:  sessionInfo,
+		tContext: c,
+	})
+	if err != nil {
+		r.flowErrorResponse(c, err)
 		return
 	}
 
+	r.respond(c, Response{
+		Route:   RouteLink,
+		AuthKey: at.Key,
+		UID:     uid,
+		User:    sessionInfo.User,
+		Session: sessionInfo.Session,
+	})
+}
+
+func (r *Rauther) link(ctx context.Context, in linkInput) (_ string, err error) { // nolint:cyclop
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowLink, in.at)
+	defer endFlow(&err)
+
+	if in.request.UID, err = r.normalizeUID(in.at, in.request.UID); err != nil {
+		return "", err
+	}
+
+	at, request, sessionInfo := in.at, in.request, in.session
+
+	rec := r.startAudit(ctx, audit.Link, at, request.UID)
+	defer rec.end(&err)
+	rec.setSession(sessionInfo)
+
+	if at.DisableLink {
+		return "", newError(http.StatusBadRequest, common.ErrLinkingNotAllowed)
+	}
+
+	u, err := r.loadByUID(ctx, at.Key, request.UID)
+	if err != nil || u == nil {
+		return "", wrapError(http.StatusBadRequest, common.ErrUserNotFound, err)
+	}
+
 	laUser := u.(user.TempUser)
 	isTempUser := laUser.IsTemp()
 	var mergeAccount bool
 
 	if r.Modules.MergeAccount && request.Merge {
 		if isTempUser {
-			errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
-			return
+			return "", newError(http.StatusBadRequest, common.ErrUserNotFound)
 		}
 
 		mergeAccount = true
-
 	} else if !isTempUser {
-		errorResponse(c, http.StatusBadRequest, common.ErrUserExist)
-		return
+		return "", newError(http.StatusBadRequest, common.ErrUserExist)
 	}
 
 	// check code
 	if !laUser.(user.ConfirmableUser).GetConfirmed(at.Key) {
 		code := laUser.(user.ConfirmableUser).GetConfirmCode(at.Key)
-		if request.Code != code || code == "" {
-			errorResponse(c, http.StatusBadRequest, common.ErrInvalidConfirmCode)
-			return
+		if !at.VerifyCode(code, request.Code) {
+			return "", newError(http.StatusBadRequest, common.ErrInvalidConfirmCode)
 		}
 
 		if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
-			codeSent := laUser.(user.CodeSentTimeUser).GetCodeSentTime(at.Key)
-
-			expiredAt := calcExpiredAt(codeSent, r.Config.Password.CodeLifeTime)
-
-			if expiredAt.Before(time.Now()) {
-				errorResponse(c, http.StatusBadRequest, common.ErrCodeExpired)
-				return
+			if err := r.checkCodeExpired(laUser, at, r.Config.Password.CodeLifeTime); err != nil {
+				return "", err
 			}
 
 			laUser.(user.CodeSentTimeUser).SetCodeSentTime(at.Key, nil)
@@ -485,27 +641,22 @@ func (r *Rauther) linkPasswordAccount(c *gin.Context) {
 	if mergeAccount {
 		userPassword := laUser.(user.PasswordAuthableUser).GetPassword(at.Key)
 
-		if !passwordCompare(request.Password, userPassword) {
-			errorResponse(c, http.StatusForbidden, common.ErrIncorrectPassword)
-			return
+		if !r.passwordCompare(ctx, request.Password, userPassword) {
+			return "", newError(http.StatusForbidden, common.ErrIncorrectPassword)
 		}
 	} else {
-		encryptedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+		encryptedPassword, err := r.hashPassword(ctx, request.Password)
 		if err != nil {
-			log.Print("encrypt password error:", err)
-			errorResponse(c, http.StatusInternalServerError, common.ErrUnknownError)
-
-			return
+			return "", err
 		}
 
-		laUser.(user.PasswordAuthableUser).SetPassword(at.Key, string(encryptedPassword))
+		laUser.(user.PasswordAuthableUser).SetPassword(at.Key, encryptedPassword)
 	}
 
 	// TODO: Unnecessary saving? Remove?
-	err = r.deps.UserStorer.Save(laUser)
+	err = r.users(ctx).Save(laUser)
 	if err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
-		return
+		return "", wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 	}
 
 	var confirmMerge bool
@@ -514,44 +665,21 @@ func (r *Rauther) linkPasswordAccount(c *gin.Context) {
 		confirmMerge = request.Merge && request.ConfirmMerge
 	}
 
-	err = r.linkAccount(sessionInfo, laUser, at, confirmMerge, c)
+	err = r.linkAccount(ctx, sessionInfo, laUser, at, confirmMerge, in.tContext)
 	if err != nil {
-		var customErr CustomError
-		var mergeErr MergeError
-
-		switch {
-		case errors.Is(err, errAuthIdentityExists):
-			errorResponse(c, http.StatusBadRequest, common.ErrAuthIdentityExists)
-		case errors.Is(err, errCurrentUserNotConfirmed):
-			errorResponse(c, http.StatusBadRequest, common.ErrUserNotConfirmed)
-		case errors.Is(err, errUserAlreadyRegistered):
-			errorResponse(c, http.StatusBadRequest, common.ErrUserExist)
-		case errors.Is(err, errCannotMergeSelf):
-			errorResponse(c, http.StatusBadRequest, common.ErrCannotMergeSelf)
-		case errors.As(err, &customErr):
-			customErrorResponse(c, customErr)
-		case errors.As(err, &mergeErr):
-			mergeErrorResponse(c, mergeErr)
-		default:
-			errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
-		}
-
-		return
+		return "", linkError(err)
 	}
 
-	err = r.deps.UserStorer.Save(sessionInfo.User)
+	err = r.users(ctx).Save(sessionInfo.User)
 	if err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
-		return
+		return "", wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"result": true,
-	})
+	return request.UID, nil
 }
 
-func (r *Rauther) setAndSendConfirmCode(at *authtype.AuthMethod, u user.User, uid string) error {
-	confirmCode := r.generateCode(at)
+func (r *Rauther) setAndSendConfirmCode(ctx context.Context, at *authtype.AuthMethod, u user.User, uid string, byLink bool) error { // nolint:lll
+	confirmCode := r.generateCode(at, sender.ConfirmationEvent)
 
 	u.(user.ConfirmableUser).SetConfirmCode(at.Key, confirmCode)
 
@@ -560,10 +688,5 @@ func (r *Rauther) setAndSendConfirmCode(at *authtype.AuthMethod, u user.User, ui
 		u.(user.CodeSentTimeUser).SetCodeSentTime(at.Key, &curTime)
 	}
 
-	err := sendConfirmCode(at.Sender, uid, confirmCode)
-	if err != nil {
-		log.Printf("failed send confirm code %v: %v", uid, err)
-	}
-
-	return err
+	return r.sendConfirmation(ctx, at, uid, confirmCode, byLink)
 }
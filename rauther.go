@@ -1,20 +1,27 @@
 package rauther
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"log"
-	"strings"
+	"net/http"
 
+	"github.com/rosberry/rauther/audit"
 	"github.com/rosberry/rauther/authtype"
 	"github.com/rosberry/rauther/checker"
 	"github.com/rosberry/rauther/common"
 	"github.com/rosberry/rauther/config"
 	"github.com/rosberry/rauther/deps"
+	"github.com/rosberry/rauther/events"
 	"github.com/rosberry/rauther/hooks"
+	"github.com/rosberry/rauther/logger"
+	"github.com/rosberry/rauther/metrics"
 	"github.com/rosberry/rauther/modules"
 	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/storage"
+	"github.com/rosberry/rauther/transport/gintransport"
 	"github.com/rosberry/rauther/user"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Rauther main object - contains configuration and other details for running.
@@ -32,21 +39,60 @@ type Rauther struct {
 
 	// defaultSender usage if we not define auth methods with senders
 	defaultSender sender.Sender
+
+	// router records registered routes of deps router
+	router *routeRecorder
+
+	// logger is redacted logger of deps
+	logger logger.Logger
+
+	// tracer of deps tracer provider or noop tracer
+	tracer trace.Tracer
+
+	// errorRenderer and responseRenderer write responses of handlers
+	errorRenderer    ErrorRenderer
+	responseRenderer ResponseRenderer
+
+	// checkLimits counts ValidateLoginField requests in user enumeration protection mode
+	checkLimits sender.ThrottleStore
+	// pendingRecoveries limits recovery requests processed after response in user enumeration protection mode
+	pendingRecoveries chan struct{}
+
+	// depsErrors are problems of dependencies found by New (returned by Validate)
+	depsErrors []ConfigError
+
+	// initialized is set by successful Init
+	initialized bool
 }
 
-// New make new instance of Rauther with default configuration
+// New make new instance of Rauther with default configuration.
+// Problems of dependencies are returned by Validate, Init and InitHandlers. Router is required only by InitHandlers
 func New(deps deps.Deps) *Rauther {
 	var u user.User
 	if deps.Storage.UserStorer != nil {
 		u = deps.Storage.UserStorer.Create()
 	}
 
+	var depsErrors []ConfigError
+
 	if deps.SessionStorer == nil {
-		log.Fatal(common.Errors[common.ErrSessionStorerDependency])
+		depsErrors = append(depsErrors, ConfigError{Module: "Deps", Err: common.Errors[common.ErrSessionStorerDependency]})
 	}
 
-	if deps.R == nil {
-		log.Fatal(common.Errors[common.ErrGinDependency])
+	if deps.Router == nil && deps.R != nil {
+		deps.Router = gintransport.New(deps.R)
+	}
+
+	if deps.Logger == nil {
+		deps.Logger = logger.Nop{}
+	}
+
+	if deps.Metrics == nil {
+		deps.Metrics = metrics.Nop{}
+	}
+
+	if deps.AuditSink == nil {
+		deps.AuditSink = audit.Nop{}
 	}
 
 	cfg := config.Config{}
@@ -54,80 +100,106 @@ func New(deps deps.Deps) *Rauther {
 
 	checker := checker.New(u)
 
+	tracing := deps.TracerProvider != nil
+	if !tracing {
+		deps.TracerProvider = trace.NewNoopTracerProvider()
+	}
+
 	r := &Rauther{
-		Config:  cfg,
-		deps:    deps,
-		Modules: modules.New(checker),
-		checker: checker,
+		Config:           cfg,
+		Modules:          modules.New(checker),
+		checker:          checker,
+		logger:           logger.Redact(deps.Logger),
+		tracer:           deps.TracerProvider.Tracer(tracerName),
+		errorRenderer:    DefaultErrorRenderer,
+		responseRenderer: DefaultResponseRenderer{},
+		checkLimits:      sender.NewMemoryThrottleStore(),
+		depsErrors:       depsErrors,
 	}
 
+	// handler span starts before middlewares of rauther routes
+	if tracing && deps.Router != nil {
+		deps.Router = deps.Router.Group("", r.traceMiddleware)
+	}
+
+	if deps.EventBus == nil {
+		deps.EventBus = events.New(events.Options{OnPanic: r.logEventPanic})
+	}
+
+	r.router = newRouteRecorder(deps.Router, deps.Metrics)
+	deps.Router = r.router
+	r.deps = deps
+
 	return r
 }
 
-func (r *Rauther) InitHandlers() error {
-	var u user.User
-	if r.deps.Storage.UserStorer != nil {
-		u = r.deps.Storage.UserStorer.Create()
+// Init validates configuration and applies defaults. It is enough for Go API (e.g. gRPC service) without HTTP router.
+// It returns *ValidationError if configuration is invalid (or calls log.Fatal if Config.FatalValidation is set).
+// Repeated calls after successful one do nothing
+func (r *Rauther) Init() error {
+	if r.initialized {
+		return nil
 	}
 
-	if r.methods == nil {
-		r.methods = authtype.New(nil)
+	if err := r.Validate(); err != nil {
+		return r.validationFailed(err)
 	}
 
-	if r.emptyAuthMethods() {
-		r.AddAuthMethod(authtype.AuthMethod{
-			Key: "email",
-		})
+	r.applyDefaults()
+
+	if r.Config.UserEnumeration.Protect {
+		// first sign-in of not existing user is not slower than next ones
+		dummyPasswordHash()
 	}
 
-	if ok := r.checkAuthMethods(u); !ok {
-		log.Fatal("failed auth types")
+	if counter, ok := r.deps.SessionStorer.(storage.SessionCounter); ok {
+		r.deps.Metrics.ActiveSessions(counter.CountSessions)
 	}
 
-	log.Printf("\nEnabled auth types:\n- AuthTypePassword: %v\n- AuthTypeSocial: %v\n- AuthTypeOTP: %v",
-		r.methods.ExistingTypes[authtype.Password],
-		r.methods.ExistingTypes[authtype.Social],
-		r.methods.ExistingTypes[authtype.OTP],
-	)
-	log.Printf("\nEnabled auth modules:\n%v", r.Modules)
+	r.logger.Log(logger.InfoLevel, "enabled auth types", logger.F("auth_types", map[string]bool{
+		"password": r.methods.ExistingTypes[authtype.Password],
+		"social":   r.methods.ExistingTypes[authtype.Social],
+		"otp":      r.methods.ExistingTypes[authtype.OTP],
+	}))
+	r.logger.Log(logger.InfoLevel, "enabled auth modules", logger.F("modules", *r.Modules))
 
-	if r.Modules.Session {
-		r.includeSession()
-	}
+	r.initialized = true
 
 	return nil
 }
 
-// checkAuthMethods - run fields definitions check in user model
-func (r *Rauther) checkAuthMethods(user user.User) bool {
-	if r.methods == nil {
-		return false
+// InitHandlers calls Init and registers handlers of enabled modules. Router dependency is required.
+// It returns *ValidationError if configuration is invalid (or calls log.Fatal if Config.FatalValidation is set)
+func (r *Rauther) InitHandlers() error {
+	if r.router.Router == nil {
+		errs := &ValidationError{}
+		errs.add("Deps", "", common.Errors[common.ErrGinDependency])
+
+		return r.validationFailed(errs)
 	}
 
-	ok, badFields := r.methods.CheckFieldsDefine(user)
-	if !ok {
-		log.Print("Please, check `auth` tags in user model:")
+	if err := r.Init(); err != nil {
+		return err
+	}
 
-		for k, v := range badFields {
-			log.Printf("Fields %v for '%v' not found in user model", v, k)
-		}
+	if r.Modules.Session {
+		r.includeSession()
 	}
 
-	if r.Modules.MergeAccount {
-		ok, failedMethods := r.methods.CheckMergeModuleSupport()
-		if !ok {
-			sb := &strings.Builder{}
-			sb.WriteString("\nPlease, check `MergeConfirmRequest` implementations in request struct for auth methods:\n")
+	if r.Config.Routes.OpenAPI != "" {
+		r.deps.Router.Handle(http.MethodGet, r.Config.Routes.OpenAPI, r.openAPIHandler)
+	}
 
-			for atKey, requestStructType := range failedMethods {
-				sb.WriteString(fmt.Sprintf("	- %v (%v)\n", atKey, requestStructType))
-			}
+	return nil
+}
 
-			log.Fatal(sb.String())
-		}
+// validationFailed returns err or calls log.Fatal if Config.FatalValidation is set
+func (r *Rauther) validationFailed(err error) error {
+	if r.Config.FatalValidation {
+		log.Fatal(err)
 	}
 
-	return ok
+	return err
 }
 
 // AddAuthMethod adds a new method of authorization and uses a default sender, if not transmitted another
@@ -154,6 +226,15 @@ func (r *Rauther) AddAuthMethods(methods []authtype.AuthMethod) *Rauther {
 	return r
 }
 
+// RenderErrors sets renderer of error responses, e.g. ProblemErrorRenderer. Default: DefaultErrorRenderer
+func (r *Rauther) RenderErrors(renderer ErrorRenderer) *Rauther {
+	if renderer != nil {
+		r.errorRenderer = renderer
+	}
+
+	return r
+}
+
 // AuthSelector specifies the selector with which the type of authorization will be selected
 func (r *Rauther) AuthSelector(selector authtype.Selector) *Rauther {
 	if r.methods == nil {
@@ -165,6 +246,18 @@ func (r *Rauther) AuthSelector(selector authtype.Selector) *Rauther {
 	return r
 }
 
+// AuthContextSelector specifies the transport independent selector with which the type of authorization will be selected
+func (r *Rauther) AuthContextSelector(selector authtype.ContextSelector) *Rauther {
+	if r.methods == nil {
+		r.methods = authtype.New(nil)
+	}
+
+	r.methods.Selector = nil
+	r.methods.ContextSelector = selector
+
+	return r
+}
+
 // emptyAuthMethods check auth types nil or empty
 func (r *Rauther) emptyAuthMethods() (ok bool) {
 	return r.methods == nil || r.methods.IsEmpty()
@@ -181,12 +274,11 @@ func (r *Rauther) DefaultSender(s sender.Sender) *Rauther {
 	return r
 }
 
-func (r *Rauther) fillFields(request authtype.AuthRequestFieldable, u user.User) (ok bool) {
-	fields := request.Fields()
+func (r *Rauther) fillFields(fields map[string]interface{}, u user.User) (ok bool) {
 	for fieldKey, fieldValue := range fields {
 		err := user.SetFields(u, fieldKey, fieldValue)
 		if err != nil {
-			log.Printf("sign up: set fields %v: %v", fieldKey, err)
+			r.logger.Log(logger.DebugLevel, "set user field", logger.F("field", fieldKey), logger.Err(err))
 			return false
 		}
 	}
@@ -194,13 +286,13 @@ func (r *Rauther) fillFields(request authtype.AuthRequestFieldable, u user.User)
 	return true
 }
 
-func (r *Rauther) generateCode(at *authtype.AuthMethod) string {
+func (r *Rauther) generateCode(at *authtype.AuthMethod, event sender.Event) string {
 	if at == nil {
-		log.Print("Cannot generate code for nil AuthMethod")
+		r.logger.Log(logger.ErrorLevel, "cannot generate code for nil auth method")
 		return ""
 	}
 
-	length := at.CodeLength
+	length := at.GetCodeLength(event)
 
 	if length == 0 {
 		length = r.Config.CodeLength
@@ -211,8 +303,24 @@ func (r *Rauther) generateCode(at *authtype.AuthMethod) string {
 
 var errAuthTypeNotFound = errors.New("auth type not found")
 
+// LoadByUID loads user by UID of auth method and checks that user has this UID
 func (r *Rauther) LoadByUID(key, uid string) (user.User, error) {
-	u, err := r.deps.UserStorer.LoadByUID(key, uid)
+	if r.methods != nil {
+		if at, ok := r.methods.List[key]; ok {
+			normalized, err := at.UIDPipeline.Apply(uid)
+			if err != nil {
+				return nil, wrapError(http.StatusBadRequest, common.ErrInvalidUID, err)
+			}
+
+			uid = normalized
+		}
+	}
+
+	return r.loadByUID(context.Background(), key, uid)
+}
+
+func (r *Rauther) loadByUID(ctx context.Context, key, uid string) (user.User, error) {
+	u, err := r.users(ctx).LoadByUID(key, uid)
 	if err == nil && u != nil && u.(user.AuthableUser).GetUID(key) != uid {
 		return u, errAuthTypeNotFound
 	}
@@ -1,197 +1,521 @@
 package rauther
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
+	"path"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gin-gonic/gin/binding"
+
+	"github.com/rosberry/rauther/audit"
 	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/code"
 	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/hooks"
+	"github.com/rosberry/rauther/metrics"
+	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/transport"
 	"github.com/rosberry/rauther/user"
-	"golang.org/x/crypto/bcrypt"
 )
 
-type recoveryRequest struct {
-	UID string `json:"uid" binding:"required"`
-}
+// Purposes of signed tokens of password reset link
+const (
+	resetLinkPurpose  = "reset_link"
+	resetTokenPurpose = "reset_token"
+
+	// resetLinkKeySize is count of random bytes of recovery code used as key of link signature
+	resetLinkKeySize = 32
+)
 
-func (r *Rauther) requestRecoveryHandler(c *gin.Context) {
+type (
+	recoveryRequest struct {
+		UID string `json:"uid" binding:"required"`
+	}
+
+	recoveryValidationRequest struct {
+		UID  string `json:"uid" binding:"required"`
+		Code string `json:"code" binding:"required"`
+	}
+
+	recoveryResetRequest struct {
+		UID      string `json:"uid" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	recoveryLinkResetRequest struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+)
+
+func (r *Rauther) requestRecoveryHandler(c transport.Context) {
 	at, ok := r.findAuthMethod(c, authtype.Password)
 	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
 
 	var request recoveryRequest
-	if err := c.ShouldBindBodyWith(&request, binding.JSON); err != nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+	if err := c.BindJSON(&request); err != nil {
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 		return
 	}
 
-	u, err := r.LoadByUID(at.Key, request.UID)
+	ctx := contextWithRequest(requestContext(c), RecoveryRequest{UID: request.UID})
+
+	uid, err := r.startRecovery(ctx, at, request.UID)
 	if err != nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
+		r.flowErrorResponse(c, err)
 		return
 	}
 
-	if tempUser, ok := u.(user.TempUser); ok && tempUser.IsTemp() {
-		errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
-		return
+	r.respond(c, Response{Route: RouteRecoveryRequest, AuthKey: at.Key, UID: uid})
+}
+
+func (r *Rauther) requestRecovery(ctx context.Context, at *authtype.AuthMethod, uid string) (_ string, err error) {
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowRecoveryRequest, at)
+	defer endFlow(&err)
+
+	if uid, err = r.normalizeUID(at, uid); err != nil {
+		return "", err
 	}
 
-	code := r.generateCode(at)
+	rec := r.startAudit(ctx, audit.RecoveryRequest, at, uid)
+	defer rec.end(&err)
+
+	u, err := r.loadRecoverableUser(ctx, at, uid)
+	if err != nil {
+		return "", err
+	}
+
+	rec.setUser(u)
+
+	if err := r.beforeHook(ctx, r.hooks.BeforeRecoveryRequest, hooks.Input{AuthMethod: at, UID: uid, User: u}, sessionInfo{}); err != nil {
+		return "", err
+	}
+
+	var recoveryCode string
+	if at.ResetLink {
+		// code is not sent and used only as key of link signature
+		recoveryCode = resetLinkKey()
+	} else {
+		recoveryCode = r.generateCode(at, sender.PasswordRecoveryEvent)
+	}
 
 	// check resend timeout
 	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
 		curTime := time.Now()
-		if resendTime, ok := r.checkResendTime(u, curTime, at); !ok {
-			resp, code := getCodeTimeoutResponse(*resendTime, curTime)
-			c.JSON(code, resp)
-
-			return
+		if err := r.checkCodeTimeout(u, curTime, at); err != nil {
+			return "", err
 		}
 
 		u.(user.CodeSentTimeUser).SetCodeSentTime(at.Key, &curTime)
 	}
 
-	u.(user.RecoverableUser).SetRecoveryCode(at.Key, code)
+	u.(user.RecoverableUser).SetRecoveryCode(at.Key, recoveryCode)
 
-	err = r.deps.Storage.UserStorer.Save(u)
-	if err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
-		return
+	// send before save: previous code stays valid if sending failed
+	if err = r.sendRecovery(ctx, at, uid, recoveryCode); err != nil {
+		return "", sendError(err)
 	}
 
-	err = sendRecoveryCode(at.Sender, request.UID, code)
-	if err != nil {
-		log.Print(err)
-		errorResponse(c, http.StatusInternalServerError, common.ErrUnknownError)
+	if err = r.users(ctx).Save(u); err != nil {
+		return "", wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
+	}
+
+	r.publish(ctx, events.RecoveryRequested{User: u, AuthKey: at.Key, UID: uid})
+
+	return uid, nil
+}
+
+func (r *Rauther) validateRecoveryCodeHandler(c transport.Context) {
+	at, ok := r.findAuthMethod(c, authtype.Password)
+	if !ok {
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"result": true})
-}
+	var request recoveryValidationRequest
+	if err := c.BindJSON(&request); err != nil {
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		return
+	}
 
-func (r *Rauther) validateRecoveryCodeHandler(c *gin.Context) {
-	type recoveryValidationRequest struct {
-		UID  string `json:"uid" binding:"required"`
-		Code string `json:"code" binding:"required"`
+	ctx := contextWithRequest(requestContext(c), RecoveryRequest{UID: request.UID, Code: request.Code})
+
+	uid, err := r.validateRecoveryCode(ctx, at, request.UID, request.Code)
+	if err != nil {
+		r.flowErrorResponse(c, err)
+		return
 	}
 
+	r.respond(c, Response{Route: RouteRecoveryValidate, AuthKey: at.Key, UID: uid})
+}
+
+func (r *Rauther) recoveryHandler(c transport.Context) {
 	at, ok := r.findAuthMethod(c, authtype.Password)
 	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
 
-	var request recoveryValidationRequest
-	if err := c.ShouldBindBodyWith(&request, binding.JSON); err != nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+	var request recoveryResetRequest
+	if err := c.BindJSON(&request); err != nil {
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 		return
 	}
 
-	u, err := r.LoadByUID(at.Key, request.UID)
+	ctx := contextWithRequest(requestContext(c), RecoveryRequest{
+		UID:      request.UID,
+		Code:     request.Code,
+		Password: request.Password,
+	})
+
+	uid, err := r.resetPassword(ctx, at, request.UID, request.Code, request.Password)
 	if err != nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
+		r.flowErrorResponse(c, err)
 		return
 	}
 
-	if tempUser, ok := u.(user.TempUser); ok && tempUser.IsTemp() {
-		errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
-		return
+	r.respond(c, Response{Route: RouteRecoveryReset, AuthKey: at.Key, UID: uid})
+}
+
+// validateRecoveryCode checks password recovery code without using it, returns normalized UID
+func (r *Rauther) validateRecoveryCode(ctx context.Context, at *authtype.AuthMethod, uid, code string) (_ string, err error) { // nolint:lll
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowRecoveryValidate, at)
+	defer endFlow(&err)
+
+	if uid, err = r.normalizeUID(at, uid); err != nil {
+		return "", err
+	}
+
+	if _, err = r.checkRecoveryCode(ctx, at, uid, code); err != nil {
+		return "", err
+	}
+
+	return uid, nil
+}
+
+func (r *Rauther) resetPassword(ctx context.Context, at *authtype.AuthMethod, uid, code, password string) (_ string, err error) { // nolint:lll
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowRecoveryReset, at)
+	defer endFlow(&err)
+
+	if uid, err = r.normalizeUID(at, uid); err != nil {
+		return "", err
+	}
+
+	rec := r.startAudit(ctx, audit.RecoveryReset, at, uid)
+	defer rec.end(&err)
+
+	u, err := r.checkRecoveryCode(ctx, at, uid, code)
+	if err != nil {
+		return "", err
+	}
+
+	rec.setUser(u)
+
+	if err := r.setRecoveredPassword(ctx, at, u, uid, password, false); err != nil {
+		return "", err
+	}
+
+	r.publish(ctx, events.PasswordReset{User: u, AuthKey: at.Key, UID: uid})
+
+	return uid, nil
+}
+
+// setRecoveredPassword sets new password, clears recovery code and saves user. Sessions of user are revoked before
+// save if revokeSessions is set
+func (r *Rauther) setRecoveredPassword(ctx context.Context, at *authtype.AuthMethod, u user.User, uid, password string, revokeSessions bool) error { // nolint:lll
+	if err := r.beforeHook(ctx, r.hooks.BeforeRecoveryReset, hooks.Input{AuthMethod: at, UID: uid, User: u}, sessionInfo{}); err != nil {
+		return err
+	}
+
+	encryptedPassword, err := r.hashPassword(ctx, password)
+	if err != nil {
+		return err
 	}
 
 	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
-		codeSent := u.(user.CodeSentTimeUser).GetCodeSentTime(at.Key)
+		u.(user.CodeSentTimeUser).SetCodeSentTime(at.Key, nil)
+	}
 
-		expiredAt := calcExpiredAt(codeSent, r.Config.Password.CodeLifeTime)
+	u.(user.PasswordAuthableUser).SetPassword(at.Key, encryptedPassword)
+	u.(user.RecoverableUser).SetRecoveryCode(at.Key, "")
 
-		if expiredAt.Before(time.Now()) {
-			errorResponse(c, http.StatusBadRequest, common.ErrCodeExpired)
-			return
+	// revoked before save: token stays valid and request can be retried if revocation failed
+	if revokeSessions {
+		if err := r.revokeUserSessions(ctx, u); err != nil {
+			return err
 		}
 	}
 
-	code := u.(user.RecoverableUser).GetRecoveryCode(at.Key)
-	if code != request.Code {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRecoveryCode)
-		return
+	if err := r.users(ctx).Save(u); err != nil {
+		return wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"result": true})
+	return nil
 }
 
-func (r *Rauther) recoveryHandler(c *gin.Context) {
-	type recoveryRequest struct {
-		UID      string `json:"uid" binding:"required"`
-		Code     string `json:"code" binding:"required"`
-		Password string `json:"password" binding:"required"`
+// checkRecoveryCode returns user if recovery code is valid and not expired
+func (r *Rauther) checkRecoveryCode(ctx context.Context, at *authtype.AuthMethod, uid, code string) (user.User, error) {
+	// recovery code of link is not sent to user
+	if at.ResetLink {
+		return nil, newError(http.StatusBadRequest, common.ErrInvalidRecoveryCode)
 	}
 
-	at, ok := r.findAuthMethod(c, authtype.Password)
-	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+	u, err := r.loadRecoverableUser(ctx, at, uid)
+	if err != nil {
+		return nil, r.uniformError(err, common.ErrInvalidRecoveryCode)
+	}
+
+	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
+		if err := r.checkCodeExpired(u, at, r.Config.Password.CodeLifeTime); err != nil {
+			return nil, r.uniformError(err, common.ErrInvalidRecoveryCode)
+		}
+	}
+
+	if !at.VerifyCode(u.(user.RecoverableUser).GetRecoveryCode(at.Key), code) {
+		return nil, newError(http.StatusBadRequest, common.ErrInvalidRecoveryCode)
+	}
+
+	return u, nil
+}
+
+func (r *Rauther) loadRecoverableUser(ctx context.Context, at *authtype.AuthMethod, uid string) (user.User, error) {
+	u, err := r.loadByUID(ctx, at.Key, uid)
+	if err != nil || u == nil {
+		return nil, wrapError(http.StatusBadRequest, common.ErrUserNotFound, err)
+	}
+
+	if tempUser, ok := u.(user.TempUser); ok && tempUser.IsTemp() {
+		return nil, newError(http.StatusBadRequest, common.ErrUserNotFound)
+	}
+
+	return u, nil
+}
+
+// sendRecovery sends password reset link if auth method resets password by link, otherwise recovery code
+func (r *Rauther) sendRecovery(ctx context.Context, at *authtype.AuthMethod, uid, recoveryCode string) error {
+	if !at.ResetLink {
+		return r.sendRecoveryCode(ctx, at.Sender, uid, recoveryCode)
+	}
+
+	expires := time.Now().Add(r.Config.Password.CodeLifeTime)
+	token := code.SignTokenUntil(r.Config.ResetLink.Secret, resetLinkPurpose, at.Key, uid, recoveryCode, expires)
+
+	return r.sendCode(ctx, at.Sender, sender.PasswordResetLinkEvent, uid, r.Config.ResetLink.URL+token)
+}
+
+// resetLinkKey returns random key of link signature. CodeGenerator of auth method is not used:
+// constant or short codes would keep used link valid after key is rotated
+func resetLinkKey() string {
+	b := make([]byte, resetLinkKeySize)
+	rand.Read(b) // nolint:errcheck
+
+	return hex.EncodeToString(b)
+}
+
+// resetLinkEnabled returns true if any password auth method resets password by link
+func (r *Rauther) resetLinkEnabled() bool {
+	for _, at := range r.methods.List {
+		if at.Type == authtype.Password && at.ResetLink {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Rauther) recoveryLinkHandler(c transport.Context) {
+	token := c.Param("token")
+	if token == "" {
+		token = path.Base(c.Request().URL.Path)
+	}
+
+	cfg := r.Config.ResetLink
+
+	at, resetToken, err := r.exchangeResetLink(requestContext(c), token)
+	if err != nil {
+		if cfg.FailureURL == "" {
+			r.flowErrorResponse(c, err)
+			return
+		}
+
+		r.logFlowError(c, err)
+
+		_, body, _ := ErrorDetails(err)
+		c.Redirect(http.StatusFound, withQuery(cfg.FailureURL, "error", body.Code))
 
 		return
 	}
 
-	var request recoveryRequest
-	if err := c.ShouldBindBodyWith(&request, binding.JSON); err != nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+	if cfg.RedirectURL == "" {
+		r.respond(c, Response{
+			Route:   RouteRecoveryLink,
+			AuthKey: at.Key,
+			Token:   resetToken,
+			Fields:  gin.H{"result": true, "token": resetToken},
+		})
+
 		return
 	}
 
-	u, err := r.LoadByUID(at.Key, request.UID)
-	if err != nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
+	c.Redirect(http.StatusFound, withQuery(cfg.RedirectURL, "token", resetToken))
+}
+
+func (r *Rauther) recoveryLinkResetHandler(c transport.Context) {
+	var request recoveryLinkResetRequest
+	if err := c.BindJSON(&request); err != nil {
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 		return
 	}
 
-	if tempUser, ok := u.(user.TempUser); ok && tempUser.IsTemp() {
-		errorResponse(c, http.StatusBadRequest, common.ErrUserNotFound)
+	ctx := contextWithRequest(requestContext(c), RecoveryRequest{Token: request.Token, Password: request.Password})
+
+	at, err := r.resetPasswordByToken(ctx, request.Token, request.Password)
+	if err != nil {
+		r.flowErrorResponse(c, err)
 		return
 	}
 
-	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
-		codeSent := u.(user.CodeSentTimeUser).GetCodeSentTime(at.Key)
+	r.respond(c, Response{Route: RouteRecoveryLinkReset, AuthKey: at.Key})
+}
 
-		expiredAt := calcExpiredAt(codeSent, r.Config.Password.CodeLifeTime)
-		if expiredAt.Before(time.Now()) {
-			errorResponse(c, http.StatusBadRequest, common.ErrCodeExpired)
-			return
+// exchangeResetLink checks token of reset link and returns short-lived reset token. Link is single-use: reset token
+// is signed with new recovery code
+func (r *Rauther) exchangeResetLink(ctx context.Context, token string) (at *authtype.AuthMethod, resetToken string, err error) { // nolint:lll
+	at, err = r.resetLinkMethod(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowRecoveryLink, at)
+	defer endFlow(&err)
+
+	u, uid, err := r.checkResetToken(ctx, at, token, resetLinkPurpose)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rec := r.startAudit(ctx, audit.RecoveryLink, at, uid)
+	defer rec.end(&err)
+
+	rec.setUser(u)
+
+	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
+		if err := r.checkCodeExpired(u, at, r.Config.Password.CodeLifeTime); err != nil {
+			return nil, "", err
 		}
+	}
 
-		u.(user.CodeSentTimeUser).SetCodeSentTime(at.Key, nil)
+	resetCode := resetLinkKey()
+	u.(user.RecoverableUser).SetRecoveryCode(at.Key, resetCode)
+
+	if err := r.users(ctx).Save(u); err != nil {
+		return nil, "", wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 	}
 
-	code := u.(user.RecoverableUser).GetRecoveryCode(at.Key)
-	if code != request.Code || code == "" {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRecoveryCode)
-		return
+	expires := time.Now().Add(r.Config.ResetLink.TokenLifeTime)
+
+	return at, code.SignTokenUntil(r.Config.ResetLink.Secret, resetTokenPurpose, at.Key, uid, resetCode, expires), nil
+}
+
+// resetPasswordByToken sets new password with reset token of link and revokes all sessions of user
+func (r *Rauther) resetPasswordByToken(ctx context.Context, token, password string) (at *authtype.AuthMethod, err error) {
+	at, err = r.resetLinkMethod(token)
+	if err != nil {
+		return nil, err
 	}
 
-	encryptedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowRecoveryReset, at)
+	defer endFlow(&err)
+
+	u, uid, err := r.checkResetToken(ctx, at, token, resetTokenPurpose)
 	if err != nil {
-		log.Print("encrypt password error:", err)
-		errorResponse(c, http.StatusInternalServerError, common.ErrUnknownError)
+		return nil, err
 	}
 
-	u.(user.PasswordAuthableUser).SetPassword(at.Key, string(encryptedPassword))
-	u.(user.RecoverableUser).SetRecoveryCode(at.Key, "")
+	rec := r.startAudit(ctx, audit.RecoveryReset, at, uid)
+	defer rec.end(&err)
+
+	rec.setUser(u)
+
+	if err := r.setRecoveredPassword(ctx, at, u, uid, password, true); err != nil {
+		return nil, err
+	}
 
-	err = r.deps.Storage.UserStorer.Save(u)
+	r.publish(ctx, events.PasswordReset{User: u, AuthKey: at.Key, UID: uid})
+
+	return at, nil
+}
+
+// resetLinkMethod returns auth method of reset link token
+func (r *Rauther) resetLinkMethod(token string) (*authtype.AuthMethod, error) {
+	authKey, _, err := code.ParseToken(token)
 	if err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
-		return
+		return nil, wrapError(http.StatusBadRequest, common.ErrInvalidRecoveryCode, err)
+	}
+
+	at, err := r.authMethodByKey(authKey, authtype.Password, r.Modules.RecoverableUser)
+	if err != nil || !at.ResetLink {
+		return nil, wrapError(http.StatusBadRequest, common.ErrInvalidRecoveryCode, err)
+	}
+
+	return at, nil
+}
+
+// checkResetToken returns user and UID of token signed with current recovery code of user.
+// Errors do not disclose existence of user
+func (r *Rauther) checkResetToken(ctx context.Context, at *authtype.AuthMethod, token, purpose string) (user.User, string, error) { // nolint:lll
+	_, uid, err := code.ParseToken(token)
+	if err != nil {
+		return nil, "", wrapError(http.StatusBadRequest, common.ErrInvalidRecoveryCode, err)
+	}
+
+	u, err := r.loadRecoverableUser(ctx, at, uid)
+	if err != nil {
+		return nil, "", wrapError(http.StatusBadRequest, common.ErrInvalidRecoveryCode, err)
+	}
+
+	if !code.VerifyToken(r.Config.ResetLink.Secret, purpose, token, u.(user.RecoverableUser).GetRecoveryCode(at.Key)) {
+		return nil, "", newError(http.StatusBadRequest, common.ErrInvalidRecoveryCode)
+	}
+
+	return u, uid, nil
+}
+
+// revokeUserSessions unbinds user from all sessions and sets new tokens. Session storer must implement
+// storage.UserSessionsFinder
+func (r *Rauther) revokeUserSessions(ctx context.Context, u user.User) error {
+	sessions, ok, err := r.findUserSessions(ctx, u.GetID())
+	if err != nil {
+		return wrapError(http.StatusInternalServerError, common.ErrSessionLoad, err)
+	}
+
+	if !ok {
+		return nil
+	}
+
+	storer := r.sessions(ctx)
+
+	for _, sess := range sessions {
+		sess.UnbindUser()
+		sess.SetToken(generateSessionToken())
+
+		if err := storer.Save(sess); err != nil {
+			return wrapError(http.StatusInternalServerError, common.ErrSessionSave, err)
+		}
+
+		r.publish(ctx, events.SessionRevoked{Session: sess, User: u})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"result": true})
+	return nil
 }
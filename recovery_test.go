@@ -0,0 +1,69 @@
+package rauther_test
+
+import (
+	"net/http"
+	"path"
+	"testing"
+
+	"github.com/rosberry/rauther"
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/deps"
+	"github.com/rosberry/rauther/example/basic/models"
+	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/sender/sendertest"
+	"github.com/rosberry/rauther/transport/httptransport"
+)
+
+func TestResetPasswordByLink(t *testing.T) {
+	users := &models.UserStorer{Users: map[uint]*models.User{}}
+	sessions := &models.Sessioner{Sessions: map[string]*models.Session{}}
+	mux := http.NewServeMux()
+	rec := sendertest.New()
+
+	r := rauther.New(deps.NewWithRouter(httptransport.New(mux), deps.Storage{SessionStorer: sessions, UserStorer: users}))
+	r.AddAuthMethod(authtype.AuthMethod{Key: "email", Sender: rec, ResetLink: true})
+	r.Config.ResetLink.Secret = "secret"
+	r.Config.ResetLink.URL = "https://api.example.com/recover/link/"
+	r.Config.Routes.RecoveryLink = "recover/link/"
+	r.Config.Password.ResendDelay = 0
+
+	if err := r.InitHandlers(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{t: t, mux: mux, users: users}
+
+	signedIn := s.auth("device1")
+	s.do(signedIn, "/register", `{"email":"user@mail.com","password":"old password"}`)
+
+	// recovery is requested from other device without session token
+	s.do(s.auth("device2"), "/recover", `{"uid":"user@mail.com"}`)
+
+	link, ok := rec.LastCode("user@mail.com", sender.PasswordResetLinkEvent)
+	if !ok {
+		t.Fatal("reset link is not sent")
+	}
+
+	status, resp := s.request(http.MethodGet, "", "/recover/link/"+path.Base(link), "")
+	if status != http.StatusOK || resp["token"] == nil {
+		t.Fatalf("open link: %d %v", status, resp)
+	}
+
+	resetToken := resp["token"].(string)
+
+	s.do("", "/recover/link", `{"token":"`+resetToken+`","password":"new password"}`)
+
+	if status, _ := s.request(http.MethodGet, signedIn, "/auth", ""); status != http.StatusUnauthorized {
+		t.Errorf("session of user is not revoked: check auth status = %d", status)
+	}
+
+	if status, _ := s.request(http.MethodGet, "", "/recover/link/"+path.Base(link), ""); status == http.StatusOK {
+		t.Error("used reset link is accepted")
+	}
+
+	if status, _ := s.request(http.MethodPost, "", "/recover/link", `{"token":"`+resetToken+`","password":"other"}`); status == http.StatusOK { // nolint:lll
+		t.Error("used reset token is accepted")
+	}
+
+	s.do(s.auth("device3"), "/login", `{"email":"user@mail.com","password":"new password"}`)
+}
@@ -0,0 +1,108 @@
+package rauther
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rosberry/rauther/session"
+	"github.com/rosberry/rauther/transport"
+	"github.com/rosberry/rauther/user"
+)
+
+// Route is handler of successful response
+type Route string
+
+const (
+	RouteAuth              Route = "auth"
+	RouteCheckAuth         Route = "check_auth"
+	RouteSignUp            Route = "sign_up"
+	RouteSignIn            Route = "sign_in"
+	RouteSignOut           Route = "sign_out"
+	RouteSocialSignIn      Route = "social_sign_in"
+	RouteOTPRequest        Route = "otp_request"
+	RouteOTPSignIn         Route = "otp_sign_in"
+	RouteConfirm           Route = "confirm"
+	RouteConfirmLink       Route = "confirm_link"
+	RouteResendCode        Route = "resend_code"
+	RouteRecoveryRequest   Route = "recovery_request"
+	RouteRecoveryValidate  Route = "recovery_validate"
+	RouteRecoveryReset     Route = "recovery_reset"
+	RouteRecoveryLink      Route = "recovery_link"
+	RouteRecoveryLinkReset Route = "recovery_link_reset"
+	RouteCheckUID          Route = "check_uid"
+	RouteInitLink          Route = "init_link"
+	RouteLink              Route = "link"
+	RouteChangeUID         Route = "change_uid"
+	RouteConfirmUIDChange  Route = "confirm_uid_change"
+)
+
+type (
+	// Response is typed payload of successful response. Fields not related to route are empty
+	Response struct {
+		Route   Route
+		AuthKey string
+
+		// Token and DeviceID of session (auth, sign out). Token is reset token for recovery link
+		Token    string
+		DeviceID string
+
+		// UID of request normalized by UIDPipeline of auth method: signed up, signed in, checked, confirmed, recovered UID,
+		// new UID of UID change
+		UID string
+
+		// IsNew is true if user is signed up by social or OTP sign-in
+		IsNew bool
+		// Linked is true if auth identity is linked to current user by OTP
+		Linked bool
+
+		// Action and ConfirmCodeRequired of link init
+		Action              string
+		ConfirmCodeRequired bool
+
+		// User and Session of request, nil if handler has no user
+		User    user.User
+		Session session.Session
+
+		// Fields is default response {"result": true, ...} changed by After... hooks
+		Fields gin.H
+	}
+
+	// ResponseRenderer writes successful response of handler
+	ResponseRenderer interface {
+		RenderResponse(c transport.Context, resp Response)
+	}
+
+	// ResponseRendererFunc is function implementing ResponseRenderer
+	ResponseRendererFunc func(c transport.Context, resp Response)
+
+	// DefaultResponseRenderer writes Fields of response
+	DefaultResponseRenderer struct{}
+)
+
+// RenderResponse calls f
+func (f ResponseRendererFunc) RenderResponse(c transport.Context, resp Response) {
+	f(c, resp)
+}
+
+// RenderResponse writes Fields with status 200
+func (DefaultResponseRenderer) RenderResponse(c transport.Context, resp Response) {
+	c.JSON(http.StatusOK, resp.Fields)
+}
+
+// RenderResponses sets renderer of successful responses. Default: DefaultResponseRenderer
+func (r *Rauther) RenderResponses(renderer ResponseRenderer) *Rauther {
+	if renderer != nil {
+		r.responseRenderer = renderer
+	}
+
+	return r
+}
+
+// respond writes successful response. Fields are {"result": true} if not set
+func (r *Rauther) respond(c transport.Context, resp Response) {
+	if resp.Fields == nil {
+		resp.Fields = gin.H{"result": true}
+	}
+
+	r.responseRenderer.RenderResponse(c, resp)
+}
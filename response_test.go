@@ -0,0 +1,90 @@
+package rauther_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rosberry/rauther"
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/deps"
+	"github.com/rosberry/rauther/example/basic/models"
+	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/sender/sendertest"
+	"github.com/rosberry/rauther/transport"
+	"github.com/rosberry/rauther/transport/httptransport"
+	"github.com/rosberry/rauther/uid"
+)
+
+func TestResponseNormalizedUID(t *testing.T) {
+	users := &models.UserStorer{Users: map[uint]*models.User{}}
+	sessions := &models.Sessioner{Sessions: map[string]*models.Session{}}
+	mux := http.NewServeMux()
+	rec := sendertest.New()
+
+	r := rauther.New(deps.NewWithRouter(httptransport.New(mux), deps.Storage{SessionStorer: sessions, UserStorer: users}))
+	r.AddAuthMethod(authtype.AuthMethod{Key: "email", Sender: rec})
+	r.AddAuthMethod(authtype.AuthMethod{
+		Key:           "sms",
+		Type:          authtype.OTP,
+		Sender:        rec,
+		SignUpRequest: &otpRequest{},
+		SignInRequest: &otpRequest{},
+		UIDPipeline:   uid.PhonePipeline,
+	})
+	r.Config.Password.ResendDelay = 0
+	r.Config.OTP.ResendDelay = 0
+
+	uids := map[rauther.Route]string{}
+
+	r.RenderResponses(rauther.ResponseRendererFunc(func(c transport.Context, resp rauther.Response) {
+		uids[resp.Route] = resp.UID
+		rauther.DefaultResponseRenderer{}.RenderResponse(c, resp)
+	}))
+
+	if err := r.InitHandlers(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{t: t, mux: mux, users: users}
+
+	const (
+		email = "user@mail.com"
+		phone = "+15550100000"
+	)
+
+	token := s.auth("device1")
+	s.do(token, "/register", `{"email":" User@Mail.com","password":"password"}`)
+
+	confirmCode, _ := rec.LastCode(email, sender.ConfirmationEvent)
+	s.do(token, "/confirm", `{"uid":"USER@mail.com ","code":"`+confirmCode+`"}`)
+
+	s.do(token, "/recover", `{"uid":"User@Mail.COM"}`)
+
+	recoveryCode, _ := rec.LastCode(email, sender.PasswordRecoveryEvent)
+	s.do(token, "/recover/validate", `{"uid":" user@MAIL.com","code":"`+recoveryCode+`"}`)
+	s.do(token, "/recover/reset", `{"uid":"USER@MAIL.COM","code":"`+recoveryCode+`","password":"new password"}`)
+
+	otpToken := s.auth("device2")
+	s.do(otpToken, "/otp/code", `{"type":"sms","phone":"+1 (555) 010-0000"}`)
+
+	otpCode, _ := rec.LastCode(phone, sender.ConfirmationEvent)
+	s.do(otpToken, "/otp/auth", `{"type":"sms","phone":"+1 555 010 0000","code":"`+otpCode+`"}`)
+	s.do(otpToken, "/auth/uid/change", `{"type":"sms","uid":"+1 555-010-0001"}`)
+
+	want := map[rauther.Route]string{
+		rauther.RouteSignUp:           email,
+		rauther.RouteConfirm:          email,
+		rauther.RouteRecoveryRequest:  email,
+		rauther.RouteRecoveryValidate: email,
+		rauther.RouteRecoveryReset:    email,
+		rauther.RouteOTPRequest:       phone,
+		rauther.RouteOTPSignIn:        phone,
+		rauther.RouteChangeUID:        "+15550100001",
+	}
+
+	for route, wantUID := range want {
+		if got := uids[route]; got != wantUID {
+			t.Errorf("%s: UID = %q, want %q", route, got, wantUID)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package rauther
+
+import (
+	"time"
+
+	"github.com/rosberry/rauther/metrics"
+	"github.com/rosberry/rauther/transport"
+)
+
+// route is registered handler of rauther
+type route struct {
+	Method string
+	// Path is full path relative to router of deps
+	Path string
+	// Security is auth requirement of route: securityNone, securitySession or securityUser
+	Security int
+}
+
+// routeRecorder is router that remembers registered routes (used for OpenAPI document)
+// and observes latency of handlers
+type routeRecorder struct {
+	transport.Router
+
+	prefix   string
+	routes   *[]route
+	metrics  metrics.Collector
+	security int
+}
+
+func newRouteRecorder(router transport.Router, collector metrics.Collector) *routeRecorder {
+	return &routeRecorder{
+		Router:  router,
+		routes:  &[]route{},
+		metrics: collector,
+	}
+}
+
+func (rr *routeRecorder) Handle(method, path string, handlers ...transport.HandlerFunc) {
+	fullPath := transport.JoinPaths(rr.prefix, path)
+
+	if len(handlers) > 0 {
+		handlers = append([]transport.HandlerFunc(nil), handlers...)
+		handler := handlers[len(handlers)-1]
+		handlers[len(handlers)-1] = func(c transport.Context) {
+			start := time.Now()
+			handler(c)
+			rr.metrics.Handler(method, fullPath, time.Since(start))
+		}
+	}
+
+	rr.Router.Handle(method, path, handlers...)
+
+	*rr.routes = append(*rr.routes, route{
+		Method:   method,
+		Path:     fullPath,
+		Security: rr.security,
+	})
+}
+
+func (rr *routeRecorder) Group(path string, handlers ...transport.HandlerFunc) transport.Router {
+	return &routeRecorder{
+		Router:   rr.Router.Group(path, handlers...),
+		prefix:   transport.JoinPaths(rr.prefix, path),
+		routes:   rr.routes,
+		metrics:  rr.metrics,
+		security: rr.security,
+	}
+}
+
+// securedGroup returns group of router with auth middleware. Routes of group are recorded with security
+func securedGroup(router transport.Router, security int, middleware transport.HandlerFunc) transport.Router {
+	group := router.Group("", middleware)
+
+	if rr, ok := group.(*routeRecorder); ok {
+		rr.security = security
+	}
+
+	return group
+}
+
+// basePath returns base path of router if router provides it (e.g. gin group)
+func (rr *routeRecorder) basePath() string {
+	if bp, ok := rr.Router.(interface{ BasePath() string }); ok {
+		return bp.BasePath()
+	}
+
+	return ""
+}
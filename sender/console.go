@@ -0,0 +1,34 @@
+package sender
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ConsoleSender prints codes to writer instead of delivering them. Use it for local development only
+type ConsoleSender struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewConsoleSender returns ConsoleSender. If w is nil - os.Stdout is used
+func NewConsoleSender(w io.Writer) *ConsoleSender {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	return &ConsoleSender{w: w}
+}
+
+func (s *ConsoleSender) Send(event Event, recipient string, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := fmt.Fprintf(s.w, "%s [rauther] %s code for %s: %s\n",
+		time.Now().Format(time.RFC3339), event, recipient, message)
+
+	return err
+}
@@ -0,0 +1,252 @@
+package sender
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	errDKIMDomainRequired   = errors.New("dkim: domain is required")
+	errDKIMSelectorRequired = errors.New("dkim: selector is required")
+	errDKIMUnsupportedKey   = errors.New("dkim: unsupported key type, use RSA or Ed25519")
+	errDKIMInvalidPEM       = errors.New("dkim: invalid PEM data")
+	errDKIMInvalidMessage   = errors.New("dkim: message has no header/body separator")
+)
+
+// DefaultDKIMHeaders is list of headers signed by default
+var DefaultDKIMHeaders = []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type"} // nolint:gochecknoglobals,lll
+
+// DKIMOptions configures DKIM signing (RFC 6376) with relaxed/relaxed canonicalization
+type DKIMOptions struct {
+	// Domain is signing domain (d= tag)
+	Domain string
+	// Selector is DNS selector of public key (s= tag)
+	Selector string
+	// Signer is private key. Supported *rsa.PrivateKey (rsa-sha256) and ed25519.PrivateKey (ed25519-sha256)
+	Signer crypto.Signer
+	// Headers is list of signed headers. Default: DefaultDKIMHeaders
+	Headers []string
+}
+
+// ParseDKIMPrivateKey parses PEM encoded PKCS#1 or PKCS#8 private key for DKIMOptions.Signer
+func ParseDKIMPrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errDKIMInvalidPEM
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("dkim: parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errDKIMUnsupportedKey
+	}
+
+	return signer, nil
+}
+
+func (o *DKIMOptions) validate() error {
+	switch {
+	case o.Domain == "":
+		return errDKIMDomainRequired
+	case o.Selector == "":
+		return errDKIMSelectorRequired
+	}
+
+	_, err := o.algorithm()
+
+	return err
+}
+
+func (o *DKIMOptions) algorithm() (string, error) {
+	switch o.Signer.(type) {
+	case *rsa.PrivateKey:
+		return "rsa-sha256", nil
+	case ed25519.PrivateKey, *ed25519.PrivateKey:
+		return "ed25519-sha256", nil
+	default:
+		return "", errDKIMUnsupportedKey
+	}
+}
+
+// Sign returns message with prepended DKIM-Signature header
+func (o *DKIMOptions) Sign(msg []byte) ([]byte, error) {
+	algorithm, err := o.algorithm()
+	if err != nil {
+		return nil, err
+	}
+
+	sep := bytes.Index(msg, []byte("\r\n\r\n"))
+	if sep < 0 {
+		return nil, errDKIMInvalidMessage
+	}
+
+	headers := parseHeaders(msg[:sep+2])
+	body := msg[sep+4:]
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+
+	signedHeaders := o.Headers
+	if len(signedHeaders) == 0 {
+		signedHeaders = DefaultDKIMHeaders
+	}
+
+	names := make([]string, 0, len(signedHeaders))
+	hashed := &bytes.Buffer{}
+
+	for _, name := range signedHeaders {
+		value, ok := headers.pop(name)
+		if !ok {
+			continue
+		}
+
+		names = append(names, strings.ToLower(name))
+		hashed.WriteString(relaxedHeader(name, value))
+		hashed.WriteString("\r\n")
+	}
+
+	dkimValue := fmt.Sprintf("v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		algorithm,
+		o.Domain,
+		o.Selector,
+		time.Now().Unix(),
+		strings.Join(names, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+
+	hashed.WriteString(relaxedHeader("DKIM-Signature", dkimValue))
+
+	signature, err := o.sign(hashed.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	out := &bytes.Buffer{}
+	out.WriteString("DKIM-Signature: ")
+	out.WriteString(dkimValue)
+	out.WriteString(base64.StdEncoding.EncodeToString(signature))
+	out.WriteString("\r\n")
+	out.Write(msg)
+
+	return out.Bytes(), nil
+}
+
+func (o *DKIMOptions) sign(data []byte) ([]byte, error) {
+	hash := sha256.Sum256(data)
+
+	if _, ok := o.Signer.(*rsa.PrivateKey); ok {
+		return o.Signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+	}
+
+	// RFC 8463: Ed25519 signs SHA-256 hash of data with PureEdDSA
+	return o.Signer.Sign(rand.Reader, hash[:], crypto.Hash(0))
+}
+
+// headerList keeps header fields in order of appearance
+type headerList [][2]string
+
+func parseHeaders(raw []byte) headerList {
+	var list headerList
+
+	for _, line := range strings.SplitAfter(string(raw), "\r\n") {
+		if line == "" {
+			continue
+		}
+
+		// continuation of folded header
+		if (line[0] == ' ' || line[0] == '\t') && len(list) > 0 {
+			list[len(list)-1][1] += line
+			continue
+		}
+
+		if i := strings.Index(line, ":"); i > 0 {
+			list = append(list, [2]string{line[:i], line[i+1:]})
+		}
+	}
+
+	return list
+}
+
+// pop returns last instance of header and removes it from list (RFC 6376 5.4.2)
+func (l headerList) pop(name string) (string, bool) {
+	for i := len(l) - 1; i >= 0; i-- {
+		if strings.EqualFold(l[i][0], name) && l[i][0] != "" {
+			value := l[i][1]
+			l[i][0] = ""
+
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// relaxedHeader canonicalizes header field with "relaxed" algorithm without trailing CRLF
+func relaxedHeader(name, value string) string {
+	value = strings.NewReplacer("\r\n", "", "\n", "").Replace(value)
+
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + compressWSP(strings.TrimSpace(value))
+}
+
+// relaxedBody canonicalizes body with "relaxed" algorithm
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(compressWSP(line), " ")
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+func compressWSP(s string) string {
+	sb := strings.Builder{}
+	space := false
+
+	for _, r := range s {
+		if r == ' ' || r == '\t' {
+			space = true
+			continue
+		}
+
+		if space {
+			sb.WriteByte(' ')
+
+			space = false
+		}
+
+		sb.WriteRune(r)
+	}
+
+	if space {
+		sb.WriteByte(' ')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,207 @@
+package sender
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEd25519Key(t *testing.T) (crypto.Signer, crypto.PublicKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return priv, pub
+}
+
+func testRSAKey(t *testing.T) (crypto.Signer, crypto.PublicKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048) // nolint:gomnd
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, &key.PublicKey
+}
+
+// verifyTestDKIM checks body hash and signature of first DKIM-Signature header of message
+func verifyTestDKIM(msg []byte, pub crypto.PublicKey) error {
+	sep := bytes.Index(msg, []byte("\r\n\r\n"))
+	if sep < 0 {
+		return errDKIMInvalidMessage
+	}
+
+	headers := parseHeaders(msg[:sep+2])
+	body := msg[sep+4:]
+
+	value, ok := headers.pop("DKIM-Signature")
+	if !ok {
+		return errors.New("no DKIM-Signature header")
+	}
+
+	tags := map[string]string{}
+
+	for _, tag := range strings.Split(value, ";") {
+		if i := strings.Index(tag, "="); i > 0 {
+			tags[strings.TrimSpace(tag[:i])] = strings.TrimSpace(tag[i+1:])
+		}
+	}
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+	if tags["bh"] != base64.StdEncoding.EncodeToString(bodyHash[:]) {
+		return errors.New("body hash mismatch")
+	}
+
+	hashed := &bytes.Buffer{}
+
+	for _, name := range strings.Split(tags["h"], ":") {
+		v, _ := headers.pop(name)
+		hashed.WriteString(relaxedHeader(name, v))
+		hashed.WriteString("\r\n")
+	}
+
+	// signature is computed with empty b= tag
+	unsigned := value[:strings.LastIndex(value, "b=")+2]
+	hashed.WriteString(relaxedHeader("DKIM-Signature", unsigned))
+
+	signature, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(hashed.Bytes())
+
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, hash[:], signature) {
+			return errors.New("invalid ed25519 signature")
+		}
+
+		return nil
+	default:
+		return errDKIMUnsupportedKey
+	}
+}
+
+func testMessage() []byte {
+	msg := &emailMessage{
+		From:        mail.Address{Name: "Service", Address: "noreply@example.com"},
+		To:          mail.Address{Address: "user@example.com"},
+		Subject:     "Confirmation",
+		ContentType: defaultContentType,
+		Body:        "Code: 123456",
+		Date:        time.Now(),
+		MessageID:   "<id@example.com>",
+	}
+
+	return msg.Bytes()
+}
+
+func TestDKIMSign(t *testing.T) {
+	rsaKey, rsaPub := testRSAKey(t)
+	edKey, edPub := testEd25519Key(t)
+
+	tests := []struct {
+		name      string
+		signer    crypto.Signer
+		pub       crypto.PublicKey
+		algorithm string
+	}{
+		{name: "rsa", signer: rsaKey, pub: rsaPub, algorithm: "a=rsa-sha256"},
+		{name: "ed25519", signer: edKey, pub: edPub, algorithm: "a=ed25519-sha256"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			o := &DKIMOptions{Domain: "example.com", Selector: "mail", Signer: tt.signer}
+
+			signed, err := o.Sign(testMessage())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.HasPrefix(signed, []byte("DKIM-Signature: v=1; "+tt.algorithm+"; c=relaxed/relaxed; d=example.com; s=mail;")) { // nolint:lll
+				t.Errorf("unexpected header: %s", signed[:bytes.IndexByte(signed, '\n')])
+			}
+
+			if err := verifyTestDKIM(signed, tt.pub); err != nil {
+				t.Errorf("verify: %v", err)
+			}
+
+			tamperedBody := bytes.Replace(signed, []byte("\r\n\r\n"), []byte("\r\n\r\nX"), 1)
+			if err := verifyTestDKIM(tamperedBody, tt.pub); err == nil {
+				t.Error("verify of tampered body succeeded")
+			}
+
+			tamperedHeader := bytes.Replace(signed, []byte("Subject: Confirmation"), []byte("Subject: Other"), 1)
+			if err := verifyTestDKIM(tamperedHeader, tt.pub); err == nil {
+				t.Error("verify of tampered header succeeded")
+			}
+		})
+	}
+}
+
+func TestDKIMOptionsValidate(t *testing.T) {
+	edKey, _ := testEd25519Key(t)
+
+	tests := []struct {
+		name    string
+		opts    DKIMOptions
+		wantErr error
+	}{
+		{name: "valid", opts: DKIMOptions{Domain: "example.com", Selector: "mail", Signer: edKey}},
+		{name: "no domain", opts: DKIMOptions{Selector: "mail", Signer: edKey}, wantErr: errDKIMDomainRequired},
+		{name: "no selector", opts: DKIMOptions{Domain: "example.com", Signer: edKey}, wantErr: errDKIMSelectorRequired},
+		{name: "no key", opts: DKIMOptions{Domain: "example.com", Selector: "mail"}, wantErr: errDKIMUnsupportedKey},
+	}
+
+	for _, tt := range tests {
+		if err := tt.opts.validate(); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: validate() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseDKIMPrivateKey(t *testing.T) {
+	rsaKey, _ := testRSAKey(t)
+	edKey, _ := testEd25519Key(t)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := map[string][]byte{
+		"pkcs1": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey.(*rsa.PrivateKey))}), // nolint:lll
+		"pkcs8": pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+	}
+
+	for name, data := range keys {
+		if _, err := ParseDKIMPrivateKey(data); err != nil {
+			t.Errorf("%s: ParseDKIMPrivateKey() error = %v", name, err)
+		}
+	}
+
+	if _, err := ParseDKIMPrivateKey([]byte("not a key")); !errors.Is(err, errDKIMInvalidPEM) {
+		t.Errorf("ParseDKIMPrivateKey() error = %v, want %v", err, errDKIMInvalidPEM)
+	}
+}
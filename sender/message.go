@@ -0,0 +1,60 @@
+package sender
+
+import (
+	"bytes"
+	"encoding/base64"
+	"mime"
+	"net/mail"
+	"time"
+)
+
+const base64LineLength = 76
+
+// emailMessage is MIME message with single base64 encoded part
+type emailMessage struct {
+	From        mail.Address
+	To          mail.Address
+	Subject     string
+	ContentType string
+	Body        string
+	Date        time.Time
+	MessageID   string
+}
+
+// Bytes returns message with headers in stable order and CRLF line endings
+func (m *emailMessage) Bytes() []byte {
+	buf := &bytes.Buffer{}
+
+	headers := [][2]string{
+		{"From", m.From.String()},
+		{"To", m.To.String()},
+		{"Subject", mime.QEncoding.Encode("UTF-8", m.Subject)},
+		{"Date", m.Date.Format(time.RFC1123Z)},
+		{"Message-ID", m.MessageID},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", m.ContentType},
+		{"Content-Transfer-Encoding", "base64"},
+	}
+
+	for _, h := range headers {
+		buf.WriteString(h[0])
+		buf.WriteString(": ")
+		buf.WriteString(h[1])
+		buf.WriteString("\r\n")
+	}
+
+	buf.WriteString("\r\n")
+
+	body := base64.StdEncoding.EncodeToString([]byte(m.Body))
+	for len(body) > base64LineLength {
+		buf.WriteString(body[:base64LineLength])
+		buf.WriteString("\r\n")
+
+		body = body[base64LineLength:]
+	}
+
+	buf.WriteString(body)
+	buf.WriteString("\r\n")
+
+	return buf.Bytes()
+}
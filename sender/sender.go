@@ -1,19 +1,21 @@
 package sender
 
 import (
-	"encoding/base64"
-	"fmt"
-	"log"
-	"mime"
-	"net"
-	"net/mail"
-	"net/smtp"
+	"context"
 	"time"
 )
 
 const (
 	ConfirmationEvent Event = iota
 	PasswordRecoveryEvent
+	// UIDChangeEvent is code sent to new UID of user
+	UIDChangeEvent
+	// UIDChangeNoticeEvent is notice sent to current UID of user, message is new UID
+	UIDChangeNoticeEvent
+	// ConfirmationLinkEvent is confirmation link sent instead of code, message is URL
+	ConfirmationLinkEvent
+	// PasswordResetLinkEvent is password reset link sent instead of recovery code, message is URL
+	PasswordResetLinkEvent
 )
 
 type (
@@ -23,6 +25,16 @@ type (
 		Send(event Event, recipient string, message string) error
 	}
 
+	// ContextSender is optional interface of Sender. Rauther uses it instead of Send
+	// to pass request context: sending is stopped if client cancels request or deadline is exceeded
+	ContextSender interface {
+		SendContext(ctx context.Context, event Event, recipient string, message string) error
+	}
+
+	senderAdapter struct {
+		Sender
+	}
+
 	EmailCredentials struct {
 		Server   string
 		Port     int
@@ -37,140 +49,85 @@ type (
 )
 
 var eventStrings = map[Event]string{ // nolint:gochecknoglobals
-	ConfirmationEvent:     "Confirmation",
-	PasswordRecoveryEvent: "Password Recovery",
+	ConfirmationEvent:      "Confirmation",
+	PasswordRecoveryEvent:  "Password Recovery",
+	UIDChangeEvent:         "UID Change",
+	UIDChangeNoticeEvent:   "UID Change Notice",
+	ConfirmationLinkEvent:  "Confirmation Link",
+	PasswordResetLinkEvent: "Password Reset Link",
 }
 
-func (e Event) String() string {
-	if s, ok := eventStrings[e]; ok {
-		return s
-	}
-
-	return "Unknown Event"
+var eventKeys = map[Event]string{ // nolint:gochecknoglobals
+	ConfirmationEvent:      "confirmation",
+	PasswordRecoveryEvent:  "password_recovery",
+	UIDChangeEvent:         "uid_change",
+	UIDChangeNoticeEvent:   "uid_change_notice",
+	ConfirmationLinkEvent:  "confirmation_link",
+	PasswordResetLinkEvent: "password_reset_link",
 }
 
-// NewDefaultEmailSender return Sender
-// Argument 'Messages' should be exists '%s' symbols for use substring to wrap your dynamic message
-func NewDefaultEmailSender(cr EmailCredentials, sj Subjects, m Messages) (Sender, error) {
-	s := &defaultEmailSender{
-		Credentials: cr,
-		Subjects: Subjects{
-			ConfirmationEvent:     "Code confirmation",
-			PasswordRecoveryEvent: "Password recovery",
-		},
-		Messages: Messages{
-			ConfirmationEvent:     "Your confirmation code is: %s. Please enter code in your app.",
-			PasswordRecoveryEvent: "Your password recovery code is: %s. Please enter code in your app.",
-		},
-	}
-
-	for key, item := range sj {
-		s.Subjects[key] = item
+// AdaptSender returns ContextSender of sender: sender itself if it implements ContextSender
+// or adapter that checks context before Send
+func AdaptSender(s Sender) ContextSender {
+	if cs, ok := s.(ContextSender); ok {
+		return cs
 	}
 
-	for key, item := range m {
-		s.Messages[key] = item
-	}
-
-	if err := s.validate(); err != nil {
-		return s, err
-	}
-
-	return s, nil
-}
-
-type defaultEmailSender struct {
-	Credentials EmailCredentials
-	Subjects
-	Messages
+	return senderAdapter{s}
 }
 
-func (sender defaultEmailSender) validate() error {
-	log.Print("Start validate smtp connect for default email sender")
-
-	_, err := mail.ParseAddress(sender.Credentials.From)
-	if err != nil {
-		return fmt.Errorf("email credentials error: %w", err)
+func (a senderAdapter) SendContext(ctx context.Context, event Event, recipient string, message string) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	conn, err := net.DialTimeout("tcp", sender.getProvider(), sender.Credentials.Timeout)
-	if err != nil {
-		return fmt.Errorf("smtp connect error: %w", err)
-	}
-	defer conn.Close()
-
-	client, err := smtp.NewClient(conn, sender.Credentials.Server)
-	if err != nil {
-		return fmt.Errorf("smtp connect error: %w", err)
-	}
-	defer client.Close()
-
-	return nil
+	return a.Send(event, recipient, message)
 }
 
-func (sender defaultEmailSender) Send(event Event, recipient string, message string) error {
-	_, err := mail.ParseAddress(recipient)
-	if err != nil {
-		return fmt.Errorf("recipient email error: %w", err)
-	}
-
-	title := sender.Subjects[event]
-	from := mail.Address{
-		Name:    sender.Credentials.FromName,
-		Address: sender.Credentials.From,
-	}
-	to := mail.Address{
-		Name:    recipient,
-		Address: recipient,
-	}
-
-	header := map[string]string{
-		"From":                      from.String(),
-		"To":                        to.String(),
-		"Subject":                   mime.QEncoding.Encode("UTF-8", title),
-		"MIME-Version":              "1.0",
-		"Content-Type":              "text/html; charset=\"utf-8\"",
-		"Content-Transfer-Encoding": "base64",
+func (e Event) String() string {
+	if s, ok := eventStrings[e]; ok {
+		return s
 	}
 
-	message = fmt.Sprintf(sender.Messages[event], message)
-
-	body := ""
-
-	for key, val := range header {
-		row := fmt.Sprintf("%s: %s\r\n", key, val)
-		body += row
-	}
+	return "Unknown Event"
+}
 
-	body += "\r\n" + base64.StdEncoding.EncodeToString([]byte(message))
-
-	err = smtp.SendMail(
-		sender.getProvider(),
-		sender.getAuth(),
-		from.Address,
-		[]string{to.Address},
-		[]byte(body),
-	)
-	if err != nil {
-		return fmt.Errorf("smtp send message error: %w", err)
+// Key returns machine readable event name, e.g. "password_recovery"
+func (e Event) Key() string {
+	if s, ok := eventKeys[e]; ok {
+		return s
 	}
 
-	return nil
+	return "unknown"
 }
 
-func (sender defaultEmailSender) RecipientKey() string {
-	return "email"
+// NewDefaultEmailSender return Sender
+// Argument 'Messages' should be exists '%s' symbols for use substring to wrap your dynamic message
+//
+// It is SMTP sender with default settings: TLS mode selected by port, pooled connections, no DKIM.
+// Use NewSMTPSender for fine tuning.
+func NewDefaultEmailSender(cr EmailCredentials, sj Subjects, m Messages) (Sender, error) {
+	return NewSMTPSender(SMTPConfig{EmailCredentials: cr}, sj, m)
 }
 
-func (sender defaultEmailSender) getProvider() string {
-	return fmt.Sprintf("%s:%v", sender.Credentials.Server, sender.Credentials.Port)
+func defaultSubjects() Subjects {
+	return Subjects{
+		ConfirmationEvent:      "Code confirmation",
+		PasswordRecoveryEvent:  "Password recovery",
+		UIDChangeEvent:         "Login change confirmation",
+		UIDChangeNoticeEvent:   "Login change requested",
+		ConfirmationLinkEvent:  "Email confirmation",
+		PasswordResetLinkEvent: "Password reset",
+	}
 }
 
-func (sender defaultEmailSender) getAuth() smtp.Auth {
-	return smtp.PlainAuth(
-		"",
-		sender.Credentials.From,
-		sender.Credentials.Pass,
-		sender.Credentials.Server,
-	)
+func defaultMessages() Messages {
+	return Messages{
+		ConfirmationEvent:      "Your confirmation code is: %s. Please enter code in your app.",
+		PasswordRecoveryEvent:  "Your password recovery code is: %s. Please enter code in your app.",
+		UIDChangeEvent:         "Your login change code is: %s. Please enter code in your app.",
+		UIDChangeNoticeEvent:   "Change of your login to %s was requested. If it was not you, please sign in and change your password.",
+		ConfirmationLinkEvent:  "Please confirm your email by link: %s",
+		PasswordResetLinkEvent: "Please reset your password by link: %s. If it was not you, ignore this message.",
+	}
 }
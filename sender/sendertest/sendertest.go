@@ -0,0 +1,156 @@
+// Package sendertest provides in-memory sender.Sender that records sent codes for use in tests.
+package sendertest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rosberry/rauther/sender"
+)
+
+type (
+	// Message is recorded Send call
+	Message struct {
+		Event     sender.Event
+		Recipient string
+		Code      string
+		SentAt    time.Time
+	}
+
+	// Filter selects recorded messages
+	Filter func(m Message) bool
+
+	// Recorder is thread-safe sender.Sender that keeps all sent messages in memory
+	Recorder struct {
+		mu       sync.Mutex
+		messages []Message
+		err      error
+		notify   chan struct{}
+	}
+)
+
+// New returns empty Recorder
+func New() *Recorder {
+	return &Recorder{
+		notify: make(chan struct{}),
+	}
+}
+
+// Send records message. If error was set by FailWith - returns it without recording
+func (r *Recorder) Send(event sender.Event, recipient string, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	r.messages = append(r.messages, Message{
+		Event:     event,
+		Recipient: recipient,
+		Code:      message,
+		SentAt:    time.Now(),
+	})
+
+	// wake up all waiters
+	close(r.notify)
+	r.notify = make(chan struct{})
+
+	return nil
+}
+
+// FailWith makes all next Send calls return err. Pass nil to restore normal behavior
+func (r *Recorder) FailWith(err error) {
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+}
+
+// Messages returns copy of all recorded messages
+func (r *Recorder) Messages() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	messages := make([]Message, len(r.messages))
+	copy(messages, r.messages)
+
+	return messages
+}
+
+// Reset removes all recorded messages
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.messages = nil
+	r.mu.Unlock()
+}
+
+// Last returns last recorded message matched by filter
+func (r *Recorder) Last(filter Filter) (Message, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.last(filter)
+}
+
+// LastCode returns last code sent to recipient for event
+func (r *Recorder) LastCode(recipient string, event sender.Event) (code string, ok bool) {
+	m, ok := r.Last(To(recipient, event))
+
+	return m.Code, ok
+}
+
+// Wait blocks until a message matched by filter is recorded or context is done.
+// Returns last matched message, including already recorded ones.
+func (r *Recorder) Wait(ctx context.Context, filter Filter) (Message, error) {
+	for {
+		r.mu.Lock()
+		m, ok := r.last(filter)
+		notify := r.notify
+		r.mu.Unlock()
+
+		if ok {
+			return m, nil
+		}
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		}
+	}
+}
+
+// WaitCode waits for code sent to recipient for event with timeout
+func (r *Recorder) WaitCode(recipient string, event sender.Event, timeout time.Duration) (code string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	m, err := r.Wait(ctx, To(recipient, event))
+
+	return m.Code, err
+}
+
+func (r *Recorder) last(filter Filter) (Message, bool) {
+	for i := len(r.messages) - 1; i >= 0; i-- {
+		if filter == nil || filter(r.messages[i]) {
+			return r.messages[i], true
+		}
+	}
+
+	return Message{}, false
+}
+
+// To matches messages by recipient and event
+func To(recipient string, event sender.Event) Filter {
+	return func(m Message) bool {
+		return m.Recipient == recipient && m.Event == event
+	}
+}
+
+// ToRecipient matches messages by recipient for any event
+func ToRecipient(recipient string) Filter {
+	return func(m Message) bool {
+		return m.Recipient == recipient
+	}
+}
@@ -0,0 +1,479 @@
+package sender
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TLSMode defines how SMTP sender secures connection with server
+type TLSMode int
+
+const (
+	// TLSAuto uses implicit TLS for port 465 and STARTTLS (if server supports it) for other ports
+	TLSAuto TLSMode = iota
+	// TLSNone disables any encryption. Use it only for local development
+	TLSNone
+	// TLSStartTLS requires STARTTLS upgrade of plain connection
+	TLSStartTLS
+	// TLSImplicit opens TLS connection from the start (SMTPS, usually port 465)
+	TLSImplicit
+)
+
+const (
+	implicitTLSPort = 465
+
+	defaultMaxIdleConns = 2
+	defaultIdleTimeout  = 30 * time.Second
+	defaultContentType  = "text/html; charset=\"utf-8\""
+)
+
+var (
+	errSenderClosed     = errors.New("smtp sender closed")
+	errStartTLSRequired = errors.New("smtp server does not support STARTTLS")
+	errAuthNotSupported = errors.New("smtp server does not support AUTH")
+)
+
+type (
+	// SMTPConfig contains settings for SMTP sender
+	SMTPConfig struct {
+		EmailCredentials
+
+		// Username for SMTP auth. Default: Credentials.From
+		Username string
+
+		// TLSMode - see TLSMode constants. Default: TLSAuto
+		TLSMode TLSMode
+		// TLSConfig used for STARTTLS and implicit TLS. Default: config with ServerName = Credentials.Server
+		TLSConfig *tls.Config
+
+		// LocalName is name sent in HELO/EHLO command. Default: "localhost"
+		LocalName string
+
+		// MaxIdleConns is count of connections kept open for reuse. Negative value disables pooling
+		MaxIdleConns int
+		// IdleTimeout is time after which idle connection is closed
+		IdleTimeout time.Duration
+
+		// ContentType of message body. Default: text/html; charset="utf-8"
+		ContentType string
+
+		// DKIM enables DKIM signing of messages if not nil
+		DKIM *DKIMOptions
+
+		// Dial used for open connection with server. It allows to use custom dialers or in-process test servers
+		Dial func(network, address string) (net.Conn, error)
+	}
+
+	// SMTPSender sends codes by email using SMTP with pooled connections
+	SMTPSender struct {
+		config SMTPConfig
+		from   mail.Address
+		Subjects
+		Messages
+
+		mu     sync.Mutex
+		idle   []*smtpConn
+		closed bool
+	}
+
+	smtpConn struct {
+		conn   net.Conn
+		client *smtp.Client
+		usedAt time.Time
+	}
+)
+
+// NewSMTPSender returns SMTP sender with pooled connections.
+// Subjects and Messages override default templates for events.
+// Validates credentials and checks connection to server.
+func NewSMTPSender(cfg SMTPConfig, sj Subjects, m Messages) (*SMTPSender, error) {
+	if cfg.Username == "" {
+		cfg.Username = cfg.From
+	}
+
+	if cfg.LocalName == "" {
+		cfg.LocalName = "localhost"
+	}
+
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = defaultMaxIdleConns
+	}
+
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = defaultIdleTimeout
+	}
+
+	if cfg.ContentType == "" {
+		cfg.ContentType = defaultContentType
+	}
+
+	if cfg.Dial == nil {
+		dialer := &net.Dialer{Timeout: cfg.Timeout}
+		cfg.Dial = dialer.Dial
+	}
+
+	s := &SMTPSender{
+		config:   cfg,
+		Subjects: defaultSubjects(),
+		Messages: defaultMessages(),
+	}
+
+	for key, item := range sj {
+		s.Subjects[key] = item
+	}
+
+	for key, item := range m {
+		s.Messages[key] = item
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return s, fmt.Errorf("email credentials error: %w", err)
+	}
+
+	s.from = mail.Address{Name: cfg.FromName, Address: from.Address}
+
+	if cfg.DKIM != nil {
+		if err := cfg.DKIM.validate(); err != nil {
+			return s, err
+		}
+	}
+
+	if err := s.validate(); err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+// validate opens connection with server, authenticates and returns connection to pool
+func (s *SMTPSender) validate() error {
+	c, err := s.dial()
+	if err != nil {
+		return err
+	}
+
+	s.put(c)
+
+	return nil
+}
+
+// Send message to recipient using template for event
+func (s *SMTPSender) Send(event Event, recipient string, message string) error {
+	return s.SendContext(context.Background(), event, recipient, message)
+}
+
+// SendContext is Send that aborts SMTP session if context is done
+func (s *SMTPSender) SendContext(ctx context.Context, event Event, recipient string, message string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	to, err := mail.ParseAddress(recipient)
+	if err != nil {
+		return fmt.Errorf("recipient email error: %w", err)
+	}
+
+	msg, err := s.buildMessage(event, *to, message)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.get()
+	if err != nil {
+		return err
+	}
+
+	stop := c.watch(ctx)
+	err = s.send(c, to.Address, msg)
+	interrupted := stop()
+
+	if err != nil {
+		if interrupted {
+			err = ctx.Err()
+		}
+
+		c.close()
+
+		return fmt.Errorf("smtp send message error: %w", err)
+	}
+
+	// deadline of interrupted connection is expired: it can't be reused
+	if interrupted {
+		c.close()
+		return nil
+	}
+
+	s.put(c)
+
+	return nil
+}
+
+// Close closes all idle connections. Sender can't be used after Close
+func (s *SMTPSender) Close() error {
+	s.mu.Lock()
+	idle := s.idle
+	s.idle = nil
+	s.closed = true
+	s.mu.Unlock()
+
+	for _, c := range idle {
+		c.quit()
+	}
+
+	return nil
+}
+
+func (s *SMTPSender) RecipientKey() string {
+	return "email"
+}
+
+func (s *SMTPSender) buildMessage(event Event, to mail.Address, message string) ([]byte, error) {
+	msg := &emailMessage{
+		From:        s.from,
+		To:          to,
+		Subject:     s.Subjects[event],
+		ContentType: s.config.ContentType,
+		Body:        fmt.Sprintf(s.Messages[event], message),
+		Date:        time.Now(),
+		MessageID:   generateMessageID(s.from.Address),
+	}
+
+	raw := msg.Bytes()
+
+	if s.config.DKIM != nil {
+		signed, err := s.config.DKIM.Sign(raw)
+		if err != nil {
+			return nil, fmt.Errorf("dkim sign error: %w", err)
+		}
+
+		raw = signed
+	}
+
+	return raw, nil
+}
+
+func (s *SMTPSender) send(c *smtpConn, recipient string, msg []byte) error {
+	c.setDeadline(s.config.Timeout)
+
+	if err := c.client.Mail(s.from.Address); err != nil {
+		return err
+	}
+
+	if err := c.client.Rcpt(recipient); err != nil {
+		return err
+	}
+
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+// get returns idle connection from pool or opens new one
+func (s *SMTPSender) get() (*smtpConn, error) {
+	for {
+		s.mu.Lock()
+
+		if s.closed {
+			s.mu.Unlock()
+			return nil, errSenderClosed
+		}
+
+		if len(s.idle) == 0 {
+			s.mu.Unlock()
+			return s.dial()
+		}
+
+		c := s.idle[len(s.idle)-1]
+		s.idle = s.idle[:len(s.idle)-1]
+		s.mu.Unlock()
+
+		if time.Since(c.usedAt) > s.config.IdleTimeout {
+			c.quit()
+			continue
+		}
+
+		// Check connection is still alive and drop previous transaction state
+		c.setDeadline(s.config.Timeout)
+
+		if err := c.client.Reset(); err != nil {
+			c.close()
+			continue
+		}
+
+		return c, nil
+	}
+}
+
+// put returns connection to pool or closes it if pool is full
+func (s *SMTPSender) put(c *smtpConn) {
+	c.usedAt = time.Now()
+
+	s.mu.Lock()
+
+	if s.closed || len(s.idle) >= s.config.MaxIdleConns {
+		s.mu.Unlock()
+		c.quit()
+
+		return
+	}
+
+	s.idle = append(s.idle, c)
+	s.mu.Unlock()
+}
+
+func (s *SMTPSender) dial() (*smtpConn, error) {
+	host := s.config.Server
+	addr := net.JoinHostPort(host, fmt.Sprint(s.config.Port))
+
+	conn, err := s.config.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("smtp connect error: %w", err)
+	}
+
+	c := &smtpConn{conn: conn}
+	c.setDeadline(s.config.Timeout)
+
+	implicitTLS := s.config.TLSMode == TLSImplicit ||
+		(s.config.TLSMode == TLSAuto && s.config.Port == implicitTLSPort)
+
+	if implicitTLS {
+		tlsConn := tls.Client(conn, s.tlsConfig())
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("smtp tls handshake error: %w", err)
+		}
+
+		c.conn = tlsConn
+	}
+
+	c.client, err = smtp.NewClient(c.conn, host)
+	if err != nil {
+		c.conn.Close()
+		return nil, fmt.Errorf("smtp connect error: %w", err)
+	}
+
+	if err := s.handshake(c, implicitTLS); err != nil {
+		c.close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// handshake sends HELO, upgrades connection to TLS and authenticates
+func (s *SMTPSender) handshake(c *smtpConn, implicitTLS bool) error {
+	if err := c.client.Hello(s.config.LocalName); err != nil {
+		return fmt.Errorf("smtp hello error: %w", err)
+	}
+
+	if !implicitTLS && s.config.TLSMode != TLSNone {
+		if ok, _ := c.client.Extension("STARTTLS"); ok {
+			if err := c.client.StartTLS(s.tlsConfig()); err != nil {
+				return fmt.Errorf("smtp starttls error: %w", err)
+			}
+		} else if s.config.TLSMode == TLSStartTLS {
+			return errStartTLSRequired
+		}
+	}
+
+	if s.config.Pass == "" {
+		return nil
+	}
+
+	if ok, _ := c.client.Extension("AUTH"); !ok {
+		return errAuthNotSupported
+	}
+
+	auth := smtp.PlainAuth("", s.config.Username, s.config.Pass, s.config.Server)
+	if err := c.client.Auth(auth); err != nil {
+		return fmt.Errorf("smtp auth error: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SMTPSender) tlsConfig() *tls.Config {
+	if s.config.TLSConfig != nil {
+		return s.config.TLSConfig
+	}
+
+	return &tls.Config{ServerName: s.config.Server, MinVersion: tls.VersionTLS12}
+}
+
+func (c *smtpConn) setDeadline(timeout time.Duration) {
+	if timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(timeout)) // nolint:errcheck
+	}
+}
+
+// watch interrupts I/O of connection when context is done.
+// Returned function stops watching and reports whether connection was interrupted
+func (c *smtpConn) watch(ctx context.Context) (stop func() bool) {
+	if ctx.Done() == nil {
+		return func() bool { return false }
+	}
+
+	done := make(chan struct{})
+	interrupted := make(chan bool, 1)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.conn.SetDeadline(time.Now()) // nolint:errcheck
+			interrupted <- true
+		case <-done:
+			interrupted <- false
+		}
+	}()
+
+	return func() bool {
+		close(done)
+		return <-interrupted
+	}
+}
+
+// quit closes connection gracefully
+func (c *smtpConn) quit() {
+	if err := c.client.Quit(); err != nil {
+		c.close()
+	}
+}
+
+func (c *smtpConn) close() {
+	if c.client != nil {
+		c.client.Close()
+		return
+	}
+
+	c.conn.Close()
+}
+
+func generateMessageID(from string) string {
+	b := make([]byte, 16) // nolint:gomnd
+	rand.Read(b)          // nolint
+
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+
+	return fmt.Sprintf("<%s.%d@%s>", hex.EncodeToString(b), time.Now().UnixNano(), domain)
+}
@@ -0,0 +1,460 @@
+package sender
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testSMTPUser = "noreply@example.com"
+	testSMTPPass = "secret"
+)
+
+type (
+	// testSMTPServer is minimal in-process SMTP server
+	testSMTPServer struct {
+		ln        net.Listener
+		tlsConfig *tls.Config
+
+		startTLS bool
+		auth     bool
+
+		mu       sync.Mutex
+		conns    int
+		messages []testMail
+	}
+
+	testMail struct {
+		From   string
+		To     string
+		Data   string
+		TLS    bool
+		Authed bool
+	}
+
+	testSMTPOptions struct {
+		implicitTLS bool
+		startTLS    bool
+		auth        bool
+	}
+)
+
+func newTestSMTPServer(t *testing.T, opts testSMTPOptions) (*testSMTPServer, *tls.Config) {
+	t.Helper()
+
+	serverTLS, clientTLS := testTLSConfigs(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.implicitTLS {
+		ln = tls.NewListener(ln, serverTLS)
+	}
+
+	s := &testSMTPServer{
+		ln:        ln,
+		tlsConfig: serverTLS,
+		startTLS:  opts.startTLS,
+		auth:      opts.auth,
+	}
+
+	go s.serve(opts.implicitTLS)
+
+	t.Cleanup(func() { ln.Close() })
+
+	return s, clientTLS
+}
+
+func (s *testSMTPServer) serve(implicitTLS bool) {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+
+		go s.handle(conn, implicitTLS)
+	}
+}
+
+func (s *testSMTPServer) handle(conn net.Conn, isTLS bool) { // nolint:funlen,cyclop
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP") // nolint:errcheck
+
+	var (
+		mail   testMail
+		authed bool
+	)
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			cmd, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-localhost") // nolint:errcheck
+
+			if s.startTLS && !isTLS {
+				tp.PrintfLine("250-STARTTLS") // nolint:errcheck
+			}
+
+			if s.auth {
+				tp.PrintfLine("250-AUTH PLAIN") // nolint:errcheck
+			}
+
+			tp.PrintfLine("250 OK") // nolint:errcheck
+		case "STARTTLS":
+			tp.PrintfLine("220 Ready") // nolint:errcheck
+
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+
+			conn, isTLS = tlsConn, true
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			if string(raw) != "\x00"+testSMTPUser+"\x00"+testSMTPPass {
+				tp.PrintfLine("535 Authentication failed") // nolint:errcheck
+				continue
+			}
+
+			authed = true
+
+			tp.PrintfLine("235 Authenticated") // nolint:errcheck
+		case "MAIL":
+			mail = testMail{From: arg, TLS: isTLS, Authed: authed}
+
+			tp.PrintfLine("250 OK") // nolint:errcheck
+		case "RCPT":
+			mail.To = arg
+
+			tp.PrintfLine("250 OK") // nolint:errcheck
+		case "DATA":
+			tp.PrintfLine("354 Go ahead") // nolint:errcheck
+
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+
+			mail.Data = string(data)
+
+			s.mu.Lock()
+			s.messages = append(s.messages, mail)
+			s.mu.Unlock()
+
+			tp.PrintfLine("250 OK") // nolint:errcheck
+		case "RSET", "NOOP":
+			tp.PrintfLine("250 OK") // nolint:errcheck
+		case "QUIT":
+			tp.PrintfLine("221 Bye") // nolint:errcheck
+			return
+		default:
+			tp.PrintfLine("502 Not implemented") // nolint:errcheck
+		}
+	}
+}
+
+func (s *testSMTPServer) stats() (conns int, messages []testMail) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.conns, append([]testMail(nil), s.messages...)
+}
+
+// config returns sender config with dialer of test server
+func (s *testSMTPServer) config(mode TLSMode, clientTLS *tls.Config) SMTPConfig {
+	return SMTPConfig{
+		EmailCredentials: EmailCredentials{
+			Server:  "localhost",
+			Port:    587,
+			From:    testSMTPUser,
+			Pass:    testSMTPPass,
+			Timeout: 5 * time.Second,
+		},
+		TLSMode:   mode,
+		TLSConfig: clientTLS,
+		Dial: func(network, _ string) (net.Conn, error) {
+			return net.Dial(network, s.ln.Addr().String())
+		},
+	}
+}
+
+// testTLSConfigs returns server config with self-signed certificate for localhost and client config trusting it
+func testTLSConfigs(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	server = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+	}
+	client = &tls.Config{RootCAs: pool, ServerName: "localhost", MinVersion: tls.VersionTLS12}
+
+	return server, client
+}
+
+func TestSMTPSenderTLSModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    testSMTPOptions
+		mode    TLSMode
+		noPass  bool
+		wantTLS bool
+		wantErr error
+	}{
+		{name: "starttls", opts: testSMTPOptions{startTLS: true, auth: true}, mode: TLSStartTLS, wantTLS: true},
+		{name: "auto uses starttls", opts: testSMTPOptions{startTLS: true, auth: true}, mode: TLSAuto, wantTLS: true},
+		{name: "auto without starttls", opts: testSMTPOptions{auth: true}, mode: TLSAuto},
+		{name: "implicit", opts: testSMTPOptions{implicitTLS: true, auth: true}, mode: TLSImplicit, wantTLS: true},
+		{name: "none", opts: testSMTPOptions{startTLS: true}, mode: TLSNone, noPass: true},
+		{name: "starttls required", opts: testSMTPOptions{auth: true}, mode: TLSStartTLS, wantErr: errStartTLSRequired},
+		{name: "auth not supported", opts: testSMTPOptions{startTLS: true}, mode: TLSStartTLS, wantErr: errAuthNotSupported},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			srv, clientTLS := newTestSMTPServer(t, tt.opts)
+
+			cfg := srv.config(tt.mode, clientTLS)
+			if tt.noPass {
+				cfg.Pass = ""
+			}
+
+			s, err := NewSMTPSender(cfg, nil, nil)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("NewSMTPSender() error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("NewSMTPSender() error = %v", err)
+			}
+
+			defer s.Close()
+
+			if err := s.Send(ConfirmationEvent, "User <user@example.com>", "123456"); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			_, messages := srv.stats()
+			if len(messages) != 1 {
+				t.Fatalf("got %d messages, want 1", len(messages))
+			}
+
+			m := messages[0]
+			if m.TLS != tt.wantTLS {
+				t.Errorf("TLS = %v, want %v", m.TLS, tt.wantTLS)
+			}
+
+			if m.Authed == tt.noPass {
+				t.Errorf("Authed = %v, want %v", m.Authed, !tt.noPass)
+			}
+
+			if m.From != "FROM:<"+testSMTPUser+">" || m.To != "TO:<user@example.com>" {
+				t.Errorf("envelope = %q %q", m.From, m.To)
+			}
+
+			if body := decodeTestBody(t, m.Data); !strings.Contains(body, "123456") {
+				t.Errorf("body %q does not contain code", body)
+			}
+		})
+	}
+}
+
+func TestSMTPSenderPool(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxIdleConns int
+		idleTimeout  time.Duration
+		wantConns    int
+	}{
+		// validation connection is reused for all messages
+		{name: "reuse", maxIdleConns: 1, wantConns: 1},
+		{name: "disabled", maxIdleConns: -1, wantConns: 4},
+		{name: "idle timeout", maxIdleConns: 1, idleTimeout: time.Nanosecond, wantConns: 4},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			srv, clientTLS := newTestSMTPServer(t, testSMTPOptions{startTLS: true, auth: true})
+
+			cfg := srv.config(TLSStartTLS, clientTLS)
+			cfg.MaxIdleConns = tt.maxIdleConns
+			cfg.IdleTimeout = tt.idleTimeout
+
+			s, err := NewSMTPSender(cfg, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer s.Close()
+
+			for i := 0; i < 3; i++ {
+				if err := s.Send(PasswordRecoveryEvent, "user@example.com", "code"); err != nil {
+					t.Fatalf("Send() error = %v", err)
+				}
+			}
+
+			conns, messages := srv.stats()
+			if conns != tt.wantConns {
+				t.Errorf("got %d connections, want %d", conns, tt.wantConns)
+			}
+
+			if len(messages) != 3 {
+				t.Errorf("got %d messages, want 3", len(messages))
+			}
+		})
+	}
+}
+
+func TestSMTPSenderClosedAndCanceled(t *testing.T) {
+	srv, clientTLS := newTestSMTPServer(t, testSMTPOptions{startTLS: true, auth: true})
+
+	s, err := NewSMTPSender(srv.config(TLSStartTLS, clientTLS), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.SendContext(ctx, ConfirmationEvent, "user@example.com", "code"); !errors.Is(err, context.Canceled) {
+		t.Errorf("SendContext() error = %v, want context.Canceled", err)
+	}
+
+	s.Close()
+
+	if err := s.Send(ConfirmationEvent, "user@example.com", "code"); !errors.Is(err, errSenderClosed) {
+		t.Errorf("Send() error = %v, want %v", err, errSenderClosed)
+	}
+
+	if _, messages := srv.stats(); len(messages) != 0 {
+		t.Errorf("got %d messages, want 0", len(messages))
+	}
+}
+
+func TestSMTPSenderDKIM(t *testing.T) {
+	srv, clientTLS := newTestSMTPServer(t, testSMTPOptions{startTLS: true, auth: true})
+	signer, pub := testEd25519Key(t)
+
+	cfg := srv.config(TLSStartTLS, clientTLS)
+	cfg.DKIM = &DKIMOptions{Domain: "example.com", Selector: "mail", Signer: signer}
+
+	s, err := NewSMTPSender(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	if err := s.Send(ConfirmationEvent, "user@example.com", "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, messages := srv.stats()
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(messages))
+	}
+
+	// server reads message with LF line endings
+	data := strings.ReplaceAll(messages[0].Data, "\n", "\r\n")
+	if err := verifyTestDKIM([]byte(data), pub); err != nil {
+		t.Errorf("verify DKIM: %v", err)
+	}
+}
+
+// decodeTestBody returns decoded base64 body of message
+func decodeTestBody(t *testing.T, data string) string {
+	t.Helper()
+
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(data)))
+	if _, err := r.ReadMIMEHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+
+	for {
+		line, err := r.ReadLine()
+		if err != nil {
+			break
+		}
+
+		sb.WriteString(line)
+	}
+
+	body, err := base64.StdEncoding.DecodeString(sb.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(body)
+}
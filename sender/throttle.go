@@ -0,0 +1,188 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const throttleCleanupInterval = time.Minute
+
+type (
+	// Limit allows Count sends per Window
+	Limit struct {
+		Count  int
+		Window time.Duration
+	}
+
+	// ThrottleStore keeps fixed window counters. Implement it to share limits between instances (e.g. Redis)
+	ThrottleStore interface {
+		// Incr increments counter for key in current window and returns counter value and window end time.
+		// New window starts if previous one is expired.
+		Incr(key string, window time.Duration) (count int, resetAt time.Time, err error)
+		// Decr decrements counter for key in current window. It rolls back Incr of rejected send
+		Decr(key string) error
+	}
+
+	// ThrottleConfig contains limits for throttled sender
+	ThrottleConfig struct {
+		// PerRecipient limits sends to one recipient address, e.g. Limit{1, time.Minute}. Zero Count disables limit
+		PerRecipient Limit
+		// Global limits total sends of sender, e.g. per minute and per day quotas
+		Global []Limit
+		// Store for counters. Default: in-memory store
+		Store ThrottleStore
+		// KeyPrefix separates counters of senders sharing store. Default: type of wrapped sender, e.g. "*sender.SMTPSender"
+		KeyPrefix string
+	}
+
+	// ThrottledSender is sender middleware that limits sends by recipient and global quotas
+	ThrottledSender struct {
+		next   Sender
+		config ThrottleConfig
+	}
+
+	// ThrottleError is returned by ThrottledSender if limit is reached.
+	// Rauther handlers respond with "code_timeout" error for it.
+	ThrottleError struct {
+		RetryAt time.Time
+		Reason  string
+	}
+)
+
+func (e ThrottleError) Error() string {
+	return fmt.Sprintf("send limit reached (%s), retry at %s", e.Reason, e.RetryAt.Format(time.RFC3339))
+}
+
+// NewThrottledSender wraps sender with limits
+func NewThrottledSender(next Sender, cfg ThrottleConfig) *ThrottledSender {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryThrottleStore()
+	}
+
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = fmt.Sprintf("%T", next)
+	}
+
+	return &ThrottledSender{
+		next:   next,
+		config: cfg,
+	}
+}
+
+// Send checks limits and passes message to wrapped sender
+func (s *ThrottledSender) Send(event Event, recipient string, message string) error {
+	return s.SendContext(context.Background(), event, recipient, message)
+}
+
+// SendContext checks limits and passes message with context to wrapped sender.
+// Counters are not changed if any limit rejects send: retries of rejected client do not extend its throttling
+func (s *ThrottledSender) SendContext(ctx context.Context, event Event, recipient string, message string) error {
+	type counter struct {
+		key    string
+		limit  Limit
+		reason string
+	}
+
+	counters := make([]counter, 0, len(s.config.Global)+1)
+
+	if l := s.config.PerRecipient; l.Count > 0 {
+		counters = append(counters, counter{s.config.KeyPrefix + ":recipient:" + recipient, l, "recipient"})
+	}
+
+	for _, l := range s.config.Global {
+		if l.Count > 0 {
+			counters = append(counters, counter{fmt.Sprintf("%s:global:%d", s.config.KeyPrefix, l.Window), l, "global"})
+		}
+	}
+
+	incremented := make([]string, 0, len(counters))
+
+	for _, c := range counters {
+		count, resetAt, err := s.config.Store.Incr(c.key, c.limit.Window)
+		if err != nil {
+			s.rollback(incremented)
+			return fmt.Errorf("throttle store error: %w", err)
+		}
+
+		incremented = append(incremented, c.key)
+
+		if count > c.limit.Count {
+			s.rollback(incremented)
+
+			return ThrottleError{
+				RetryAt: resetAt,
+				Reason:  c.reason,
+			}
+		}
+	}
+
+	return AdaptSender(s.next).SendContext(ctx, event, recipient, message)
+}
+
+// rollback decrements counters of rejected send. Errors are ignored: counter expires with window anyway
+func (s *ThrottledSender) rollback(keys []string) {
+	for _, key := range keys {
+		s.config.Store.Decr(key) // nolint:errcheck
+	}
+}
+
+type (
+	// MemoryThrottleStore is in-memory ThrottleStore for single instance
+	MemoryThrottleStore struct {
+		mu        sync.Mutex
+		windows   map[string]*throttleWindow
+		cleanedAt time.Time
+	}
+
+	throttleWindow struct {
+		count   int
+		resetAt time.Time
+	}
+)
+
+func NewMemoryThrottleStore() *MemoryThrottleStore {
+	return &MemoryThrottleStore{
+		windows:   make(map[string]*throttleWindow),
+		cleanedAt: time.Now(),
+	}
+}
+
+func (s *MemoryThrottleStore) Incr(key string, window time.Duration) (count int, resetAt time.Time, err error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.cleanedAt) > throttleCleanupInterval {
+		for k, w := range s.windows {
+			if !now.Before(w.resetAt) {
+				delete(s.windows, k)
+			}
+		}
+
+		s.cleanedAt = now
+	}
+
+	w, ok := s.windows[key]
+	if !ok || !now.Before(w.resetAt) {
+		w = &throttleWindow{resetAt: now.Add(window)}
+		s.windows[key] = w
+	}
+
+	w.count++
+
+	return w.count, w.resetAt, nil
+}
+
+func (s *MemoryThrottleStore) Decr(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w, ok := s.windows[key]; ok && w.count > 0 && time.Now().Before(w.resetAt) {
+		w.count--
+	}
+
+	return nil
+}
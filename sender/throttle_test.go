@@ -0,0 +1,91 @@
+package sender
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type countingSender struct {
+	sent int
+}
+
+func (s *countingSender) Send(event Event, recipient string, message string) error {
+	s.sent++
+	return nil
+}
+
+func TestThrottledSender(t *testing.T) {
+	next := &countingSender{}
+	s := NewThrottledSender(next, ThrottleConfig{
+		PerRecipient: Limit{Count: 2, Window: time.Minute},
+		Global:       []Limit{{Count: 3, Window: time.Minute}},
+	})
+
+	send := func(recipient string) error {
+		return s.Send(ConfirmationEvent, recipient, "code")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := send("a"); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	// rejected retries do not extend throttling and do not use global quota
+	for i := 0; i < 5; i++ {
+		var throttleErr ThrottleError
+		if err := send("a"); !errors.As(err, &throttleErr) || throttleErr.Reason != "recipient" {
+			t.Fatalf("send a: error = %v, want recipient limit", err)
+		}
+	}
+
+	if err := send("b"); err != nil {
+		t.Fatalf("send b: %v", err)
+	}
+
+	// global limit rejects send before recipient counter of "c" is used
+	var throttleErr ThrottleError
+	if err := send("c"); !errors.As(err, &throttleErr) || throttleErr.Reason != "global" {
+		t.Fatalf("send c: error = %v, want global limit", err)
+	}
+
+	if next.sent != 3 {
+		t.Errorf("sent %d messages, want 3", next.sent)
+	}
+
+	store := s.config.Store.(*MemoryThrottleStore)
+
+	for key, want := range map[string]int{
+		"*sender.countingSender:recipient:a":        2,
+		"*sender.countingSender:recipient:c":        0,
+		"*sender.countingSender:global:60000000000": 3,
+	} {
+		got := 0
+		if w, ok := store.windows[key]; ok {
+			got = w.count
+		}
+
+		if got != want {
+			t.Errorf("counter %q = %d, want %d", key, got, want)
+		}
+	}
+}
+
+func TestThrottledSenderSharedStore(t *testing.T) {
+	store := NewMemoryThrottleStore()
+	limit := Limit{Count: 1, Window: time.Minute}
+
+	email := NewThrottledSender(&countingSender{}, ThrottleConfig{PerRecipient: limit, Store: store, KeyPrefix: "email"})
+	sms := NewThrottledSender(&countingSender{}, ThrottleConfig{PerRecipient: limit, Store: store, KeyPrefix: "sms"})
+
+	for _, s := range []*ThrottledSender{email, sms} {
+		if err := s.Send(ConfirmationEvent, "user", "code"); err != nil {
+			t.Fatalf("%s: %v", s.config.KeyPrefix, err)
+		}
+	}
+
+	if err := email.Send(ConfirmationEvent, "user", "code"); err == nil {
+		t.Error("second send of email sender is not throttled")
+	}
+}
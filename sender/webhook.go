@@ -0,0 +1,227 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultWebhookTimeout    = 10 * time.Second
+	defaultWebhookMaxRetries = 3
+	defaultWebhookRetryDelay = 500 * time.Millisecond
+
+	DefaultSignatureHeader   = "X-Rauther-Signature"
+	DefaultTimestampHeader   = "X-Rauther-Timestamp"
+	DefaultIdempotencyHeader = "Idempotency-Key"
+)
+
+var errWebhookURLRequired = errors.New("webhook url is required")
+
+type (
+	// WebhookConfig contains settings for webhook sender
+	WebhookConfig struct {
+		// URL of notification service endpoint
+		URL string
+		// Secret is HMAC-SHA256 key for payload signature. Signature is not sent if secret is empty
+		Secret string
+
+		// Timeout of one request. Default: 10s
+		Timeout time.Duration
+		// MaxRetries is count of repeated requests on 5xx response or network error. Default: 3.
+		// Negative value disables retries
+		MaxRetries int
+		// RetryDelay is delay before first retry, doubled for each next retry. Default: 500ms
+		RetryDelay time.Duration
+
+		// Headers added to each request, e.g. Authorization
+		Headers map[string]string
+
+		// SignatureHeader contains "sha256=<hex(hmac(timestamp + "." + body))>". Default: X-Rauther-Signature
+		SignatureHeader string
+		// TimestampHeader contains unix time of request. Default: X-Rauther-Timestamp
+		TimestampHeader string
+		// IdempotencyHeader contains payload ID, the same for all retries. Default: Idempotency-Key
+		IdempotencyHeader string
+
+		// Client used for requests. Default: http.Client with Timeout
+		Client *http.Client
+	}
+
+	// WebhookPayload is JSON body of webhook request
+	WebhookPayload struct {
+		ID        string `json:"id"`
+		Event     string `json:"event"`
+		Recipient string `json:"recipient"`
+		Code      string `json:"code"`
+		Timestamp int64  `json:"timestamp"`
+	}
+
+	// WebhookSender posts signed code delivery events to configured URL
+	WebhookSender struct {
+		config WebhookConfig
+	}
+
+	// WebhookError is returned when service responded with unexpected status
+	WebhookError struct {
+		StatusCode int
+		Body       string
+	}
+)
+
+func (e WebhookError) Error() string {
+	return fmt.Sprintf("webhook response status %d: %s", e.StatusCode, e.Body)
+}
+
+// NewWebhookSender returns sender that delivers events to notification service
+func NewWebhookSender(cfg WebhookConfig) (*WebhookSender, error) {
+	if cfg.URL == "" {
+		return nil, errWebhookURLRequired
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+
+	switch {
+	case cfg.MaxRetries == 0:
+		cfg.MaxRetries = defaultWebhookMaxRetries
+	case cfg.MaxRetries < 0:
+		cfg.MaxRetries = 0
+	}
+
+	if cfg.RetryDelay == 0 {
+		cfg.RetryDelay = defaultWebhookRetryDelay
+	}
+
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = DefaultSignatureHeader
+	}
+
+	if cfg.TimestampHeader == "" {
+		cfg.TimestampHeader = DefaultTimestampHeader
+	}
+
+	if cfg.IdempotencyHeader == "" {
+		cfg.IdempotencyHeader = DefaultIdempotencyHeader
+	}
+
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	return &WebhookSender{config: cfg}, nil
+}
+
+// Send posts event to notification service. Retries on network errors and 5xx responses
+func (s *WebhookSender) Send(event Event, recipient string, message string) error {
+	return s.SendContext(context.Background(), event, recipient, message)
+}
+
+// SendContext is Send that stops requests and retries if context is done
+func (s *WebhookSender) SendContext(ctx context.Context, event Event, recipient string, message string) error {
+	payload := WebhookPayload{
+		ID:        uuid.NewString(),
+		Event:     event.Key(),
+		Recipient: recipient,
+		Code:      message,
+		Timestamp: time.Now().Unix(),
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("webhook marshal error: %w", err)
+	}
+
+	delay := s.config.RetryDelay
+
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(ctx, payload.ID, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retry || attempt >= s.config.MaxRetries {
+			return fmt.Errorf("webhook send error: %w", err)
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("webhook send error: %w", ctx.Err())
+		case <-timer.C:
+		}
+
+		delay *= 2
+	}
+}
+
+// post makes one request. Returns retry = true if request can be repeated
+func (s *WebhookSender) post(ctx context.Context, id string, body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10) // nolint:gomnd
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(s.config.TimestampHeader, timestamp)
+	req.Header.Set(s.config.IdempotencyHeader, id)
+
+	if s.config.Secret != "" {
+		req.Header.Set(s.config.SignatureHeader, "sha256="+Sign(s.config.Secret, timestamp, body))
+	}
+
+	for k, v := range s.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	const maxErrorBody = 1024
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		io.Copy(ioutil.Discard, resp.Body) // nolint:errcheck
+		return false, nil
+	}
+
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	return resp.StatusCode >= http.StatusInternalServerError, WebhookError{
+		StatusCode: resp.StatusCode,
+		Body:       string(respBody),
+	}
+}
+
+// Sign returns hex encoded HMAC-SHA256 of timestamp and body joined with "."
+// Receiver should compute the same value and compare it with signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp)) // nolint:errcheck
+	mac.Write([]byte("."))       // nolint:errcheck
+	mac.Write(body)              // nolint:errcheck
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
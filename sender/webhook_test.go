@@ -0,0 +1,234 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testWebhookServer responds with queued statuses, then with 200
+type testWebhookServer struct {
+	mu       sync.Mutex
+	statuses []int
+	delay    time.Duration
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (s *testWebhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	s.bodies = append(s.bodies, body)
+
+	status := http.StatusOK
+	if len(s.statuses) > 0 {
+		status, s.statuses = s.statuses[0], s.statuses[1:]
+	}
+	s.mu.Unlock()
+
+	if s.delay > 0 {
+		time.Sleep(s.delay)
+	}
+
+	w.WriteHeader(status)
+}
+
+func (s *testWebhookServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.requests)
+}
+
+func newTestWebhookSender(t *testing.T, srv *testWebhookServer, cfg WebhookConfig) *WebhookSender {
+	t.Helper()
+
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	cfg.URL = ts.URL
+	if cfg.RetryDelay == 0 {
+		cfg.RetryDelay = time.Millisecond
+	}
+
+	s, err := NewWebhookSender(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestWebhookSenderSignature(t *testing.T) {
+	srv := &testWebhookServer{}
+	s := newTestWebhookSender(t, srv, WebhookConfig{Secret: "secret", Headers: map[string]string{"Authorization": "Bearer x"}})
+
+	if err := s.Send(ConfirmationEvent, "user@example.com", "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	if srv.count() != 1 {
+		t.Fatalf("got %d requests, want 1", srv.count())
+	}
+
+	req, body := srv.requests[0], srv.bodies[0]
+
+	want := "sha256=" + Sign("secret", req.Header.Get(DefaultTimestampHeader), body)
+	if got := req.Header.Get(DefaultSignatureHeader); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+
+	if req.Header.Get("Authorization") != "Bearer x" {
+		t.Error("custom header is not sent")
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if payload.Event != "confirmation" || payload.Recipient != "user@example.com" || payload.Code != "123456" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+
+	if req.Header.Get(DefaultIdempotencyHeader) != payload.ID {
+		t.Errorf("idempotency key %q != payload id %q", req.Header.Get(DefaultIdempotencyHeader), payload.ID)
+	}
+}
+
+func TestWebhookSenderRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		wantErr    bool
+		wantCount  int
+	}{
+		{name: "retry on 5xx", statuses: []int{500, 503}, wantCount: 3},
+		{name: "retries exhausted", statuses: []int{500, 500, 500, 500}, wantErr: true, wantCount: 4},
+		{name: "no retry on 4xx", statuses: []int{400}, wantErr: true, wantCount: 1},
+		{name: "retries disabled", statuses: []int{500}, maxRetries: -1, wantErr: true, wantCount: 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &testWebhookServer{statuses: tt.statuses}
+			s := newTestWebhookSender(t, srv, WebhookConfig{MaxRetries: tt.maxRetries})
+
+			err := s.Send(ConfirmationEvent, "user", "code")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var webhookErr WebhookError
+			if tt.wantErr && !errors.As(err, &webhookErr) {
+				t.Errorf("error %v is not WebhookError", err)
+			}
+
+			if srv.count() != tt.wantCount {
+				t.Errorf("got %d requests, want %d", srv.count(), tt.wantCount)
+			}
+
+			// all attempts have the same payload ID
+			for _, req := range srv.requests {
+				if req.Header.Get(DefaultIdempotencyHeader) != srv.requests[0].Header.Get(DefaultIdempotencyHeader) {
+					t.Error("retry has different idempotency key")
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookSenderNetworkError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// connections are accepted and closed without response
+	var (
+		mu    sync.Mutex
+		conns int
+	)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			conns++
+			mu.Unlock()
+
+			conn.Close()
+		}
+	}()
+
+	defer ln.Close()
+
+	s, err := NewWebhookSender(WebhookConfig{URL: "http://" + ln.Addr().String(), MaxRetries: 2, RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Send(ConfirmationEvent, "user", "code"); err == nil {
+		t.Fatal("Send() succeeded without response")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// each attempt opens at least one connection
+	if conns < 3 {
+		t.Errorf("got %d connections, want at least 3 (1 request + 2 retries)", conns)
+	}
+}
+
+func TestWebhookSenderTimeout(t *testing.T) {
+	srv := &testWebhookServer{delay: 200 * time.Millisecond}
+	s := newTestWebhookSender(t, srv, WebhookConfig{Timeout: 20 * time.Millisecond, MaxRetries: 1})
+
+	start := time.Now()
+
+	err := s.Send(ConfirmationEvent, "user", "code")
+	if err == nil {
+		t.Fatal("Send() succeeded after timeout")
+	}
+
+	if srv.count() != 2 {
+		t.Errorf("got %d requests, want 2: timeout is retried", srv.count())
+	}
+
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Send() took %s, requests are not limited by timeout", elapsed)
+	}
+}
+
+func TestWebhookSenderContextCanceled(t *testing.T) {
+	srv := &testWebhookServer{statuses: []int{500, 500}}
+	s := newTestWebhookSender(t, srv, WebhookConfig{RetryDelay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := s.SendContext(ctx, ConfirmationEvent, "user", "code"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SendContext() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	if srv.count() != 1 {
+		t.Errorf("got %d requests, want 1", srv.count())
+	}
+}
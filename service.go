@@ -0,0 +1,467 @@
+package rauther
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/session"
+	"github.com/rosberry/rauther/user"
+)
+
+// Go API for auth flows without HTTP: background jobs, admin tools, gRPC services.
+// Methods work after Init (called by InitHandlers) and return *Error (or CustomError of storer, MergeError) on failure.
+// Sessions are not changed by these methods unless session is set with ContextWithSession.
+
+var (
+	errAuthMethodNotFound = errors.New("auth method not found")
+	errModuleDisabled     = errors.New("module is disabled")
+)
+
+type (
+	// SessionResult is result of StartSession
+	SessionResult struct {
+		DeviceID string
+		Session  session.Session
+	}
+
+	// SignUpResult is result of SignUp
+	SignUpResult struct {
+		User user.User
+		UID  string
+		// ConfirmCodeSent is true if confirmation code was generated and sent
+		ConfirmCodeSent bool
+	}
+
+	// OTPResult is result of VerifyOTP
+	OTPResult struct {
+		User user.User
+		// UID is normalized UID of request
+		UID string
+		// IsNew is true if user signed in with OTP first time
+		IsNew bool
+		// Linked is true if auth identity was linked to current user
+		Linked bool
+	}
+
+	// SocialResult is result of SocialSignIn
+	SocialResult struct {
+		User  user.User
+		IsNew bool
+	}
+
+	// InitLinkResult is result of InitLink
+	InitLinkResult struct {
+		// UID is normalized UID of request
+		UID string
+		// Action is "link" for new auth identity or "merge" for identity of other user
+		Action string
+		// ConfirmCodeRequired is true if code was sent and must be passed to Link
+		ConfirmCodeRequired bool
+	}
+
+	// LinkRequest is data for linking password auth identity to current user
+	LinkRequest struct {
+		UID      string
+		Password string
+		Code     string
+		// Merge allows to merge users if identity belongs to other user
+		Merge bool
+		// ConfirmMerge confirms merge when MergeError was returned before
+		ConfirmMerge bool
+	}
+
+	// RecoveryRequest is bound request of password recovery handlers passed to before hooks.
+	// Code and Password are empty for recovery request, Token is set for reset by link only
+	RecoveryRequest struct {
+		UID      string
+		Code     string
+		Password string
+		// Token is reset token of reset link
+		Token string
+	}
+)
+
+type contextKey int
+
+const (
+	sessionContextKey contextKey = iota
+	confirmMergeContextKey
+	clientContextKey
+	hookRequestContextKey
+	httpRequestContextKey
+)
+
+// ContextWithSession returns context with session. Go API methods called with the context
+// bind user to the session and save it like HTTP handlers
+func ContextWithSession(ctx context.Context, sess session.Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey, sess)
+}
+
+// SessionFromContext returns session set by ContextWithSession
+func SessionFromContext(ctx context.Context) (session.Session, bool) {
+	sess, ok := ctx.Value(sessionContextKey).(session.Session)
+
+	return sess, ok && sess != nil
+}
+
+// ContextWithConfirmMerge returns context with merge confirmation for linking in VerifyOTP and SocialSignIn
+func ContextWithConfirmMerge(ctx context.Context, confirm bool) context.Context {
+	return context.WithValue(ctx, confirmMergeContextKey, confirm)
+}
+
+func contextConfirmMerge(ctx context.Context) bool {
+	confirm, _ := ctx.Value(confirmMergeContextKey).(bool)
+
+	return confirm
+}
+
+// ContextWithClient returns context with IP and user agent of client for audit events.
+// HTTP handlers set them from request
+func ContextWithClient(ctx context.Context, ip, userAgent string) context.Context {
+	return context.WithValue(ctx, clientContextKey, clientInfo{ip: ip, userAgent: userAgent})
+}
+
+func contextClient(ctx context.Context) clientInfo {
+	client, _ := ctx.Value(clientContextKey).(clientInfo)
+
+	return client
+}
+
+// StartSession loads session by device ID (new ID is generated if empty) and sets new token, like auth handler
+func (r *Rauther) StartSession(ctx context.Context, deviceID string) (*SessionResult, error) {
+	return r.startSession(ctx, deviceID)
+}
+
+// FindSession returns session by token and user of session, like auth middleware
+func (r *Rauther) FindSession(ctx context.Context, token string) (session.Session, user.User, error) {
+	if token == "" {
+		return nil, nil, newError(http.StatusUnauthorized, common.ErrNotAuth)
+	}
+
+	return r.findSession(ctx, token)
+}
+
+// SignOut unbinds user from session of context and sets new token
+func (r *Rauther) SignOut(ctx context.Context) (session.Session, error) {
+	sess, ok := SessionFromContext(ctx)
+	if !ok {
+		return nil, newError(http.StatusUnauthorized, common.ErrNotAuth)
+	}
+
+	if err := r.signOut(ctx, r.loadSessionInfo(ctx, sess)); err != nil {
+		return nil, err
+	}
+
+	return sess, nil
+}
+
+// SignUp registers user with password auth method
+func (r *Rauther) SignUp(ctx context.Context, authKey, uid, password string, fields map[string]interface{}) (*SignUpResult, error) {
+	at, err := r.authMethodByKey(authKey, authtype.Password, r.Modules.PasswordAuthableUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.signUp(ctx, signUpInput{
+		at:       at,
+		uid:      uid,
+		password: password,
+		fields:   fields,
+		session:  r.contextSessionInfo(ctx),
+	})
+}
+
+// SignIn checks password of user and returns the user
+func (r *Rauther) SignIn(ctx context.Context, authKey, uid, password string) (user.User, error) {
+	at, err := r.authMethodByKey(authKey, authtype.Password, r.Modules.PasswordAuthableUser)
+	if err != nil {
+		return nil, err
+	}
+
+	u, _, err := r.signIn(ctx, signInInput{
+		at:       at,
+		uid:      uid,
+		password: password,
+		session:  r.contextSessionInfo(ctx),
+	})
+
+	return u, err
+}
+
+// CheckUID returns ErrUserExist error if uid is already registered.
+// In protection mode of Config.UserEnumeration checks are limited by client IP of ContextWithClient or disabled
+func (r *Rauther) CheckUID(ctx context.Context, authKey, uid string) error {
+	at, err := r.authMethodByKey(authKey, authtype.Password, r.Modules.PasswordAuthableUser)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.checkUID(ctx, at, uid)
+
+	return err
+}
+
+// Confirm confirms uid with code
+func (r *Rauther) Confirm(ctx context.Context, authKey, uid, code string) error {
+	at, err := r.authMethodByKey(authKey, authtype.Password, r.Modules.ConfirmableUser)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.confirm(ctx, at, uid, code)
+
+	return err
+}
+
+// ConfirmByLink confirms UID with token of confirmation link
+func (r *Rauther) ConfirmByLink(ctx context.Context, token string) error {
+	_, _, err := r.confirmLink(ctx, token)
+	return err
+}
+
+// ResendConfirmCode generates and sends new confirmation code to user
+func (r *Rauther) ResendConfirmCode(ctx context.Context, authKey string, u user.User) error {
+	at, err := r.authMethodByKey(authKey, authtype.Password, r.Modules.ConfirmableUser)
+	if err != nil {
+		return err
+	}
+
+	return r.resendConfirmCode(ctx, at, u)
+}
+
+// RequestRecovery sends password recovery code. In protection mode of Config.UserEnumeration code is sent
+// in background and errors are logged only, like in HTTP handler
+func (r *Rauther) RequestRecovery(ctx context.Context, authKey, uid string) error {
+	at, err := r.authMethodByKey(authKey, authtype.Password, r.Modules.RecoverableUser)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.startRecovery(ctx, at, uid)
+
+	return err
+}
+
+// ValidateRecoveryCode checks password recovery code without using it
+func (r *Rauther) ValidateRecoveryCode(ctx context.Context, authKey, uid, code string) error {
+	at, err := r.authMethodByKey(authKey, authtype.Password, r.Modules.RecoverableUser)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.validateRecoveryCode(ctx, at, uid, code)
+
+	return err
+}
+
+// ResetPassword sets new password with recovery code
+func (r *Rauther) ResetPassword(ctx context.Context, authKey, uid, code, password string) error {
+	at, err := r.authMethodByKey(authKey, authtype.Password, r.Modules.RecoverableUser)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.resetPassword(ctx, at, uid, code, password)
+
+	return err
+}
+
+// ExchangeResetLink checks token of password reset link and returns reset token for ResetPasswordByToken.
+// Link is single-use
+func (r *Rauther) ExchangeResetLink(ctx context.Context, token string) (string, error) {
+	_, resetToken, err := r.exchangeResetLink(ctx, token)
+	return resetToken, err
+}
+
+// ResetPasswordByToken sets new password with reset token and revokes all sessions of user
+func (r *Rauther) ResetPasswordByToken(ctx context.Context, resetToken, password string) error {
+	_, err := r.resetPasswordByToken(ctx, resetToken, password)
+	return err
+}
+
+// RequestOTP generates and sends one time password. User is created if not exists
+func (r *Rauther) RequestOTP(ctx context.Context, authKey, uid string) error {
+	at, err := r.authMethodByKey(authKey, authtype.OTP, r.Modules.OTP)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.requestOTP(ctx, at, uid, r.contextSessionInfo(ctx))
+
+	return err
+}
+
+// VerifyOTP checks one time password and returns the user
+func (r *Rauther) VerifyOTP(ctx context.Context, authKey, uid, code string, fields map[string]interface{}) (*OTPResult, error) {
+	at, err := r.authMethodByKey(authKey, authtype.OTP, r.Modules.OTP)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.verifyOTP(ctx, otpInput{
+		at:           at,
+		uid:          uid,
+		code:         code,
+		fields:       fields,
+		confirmMerge: contextConfirmMerge(ctx),
+		session:      r.contextSessionInfo(ctx),
+	})
+}
+
+// SocialSignIn signs in (or up) user with token of social provider
+func (r *Rauther) SocialSignIn(ctx context.Context, authKey, token string, fields map[string]interface{}) (*SocialResult, error) {
+	at, err := r.authMethodByKey(authKey, authtype.Social, r.Modules.SocialAuthableUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.socialSignIn(ctx, socialInput{
+		at:           at,
+		token:        token,
+		fields:       fields,
+		confirmMerge: contextConfirmMerge(ctx),
+		session:      r.contextSessionInfo(ctx),
+	})
+}
+
+// InitLink starts linking password auth identity to current user and sends confirmation code if needed
+func (r *Rauther) InitLink(ctx context.Context, current user.User, authKey, uid string) (*InitLinkResult, error) {
+	at, err := r.authMethodByKey(authKey, authtype.Password, r.Modules.LinkAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		return nil, newError(http.StatusUnauthorized, common.ErrNotAuth)
+	}
+
+	return r.initLink(ctx, at, uid, r.userSessionInfo(ctx, current))
+}
+
+// Link links password auth identity to current user
+func (r *Rauther) Link(ctx context.Context, current user.User, authKey string, request LinkRequest) error {
+	at, err := r.authMethodByKey(authKey, authtype.Password, r.Modules.LinkAccount)
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		return newError(http.StatusUnauthorized, common.ErrNotAuth)
+	}
+
+	_, err = r.link(ctx, linkInput{
+		at:      at,
+		request: request,
+		session: r.userSessionInfo(ctx, current),
+	})
+
+	return err
+}
+
+// ChangeUID sends code to new UID of password or OTP auth method of current user. UID is changed by ConfirmUIDChange
+func (r *Rauther) ChangeUID(ctx context.Context, current user.User, authKey, uid string) error {
+	at, err := r.uidChangeMethodByKey(authKey)
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		return newError(http.StatusUnauthorized, common.ErrNotAuth)
+	}
+
+	_, err = r.requestUIDChange(ctx, at, current, uid)
+
+	return err
+}
+
+// ConfirmUIDChange sets new UID of current user with code sent by ChangeUID and returns it
+func (r *Rauther) ConfirmUIDChange(ctx context.Context, current user.User, authKey, code string) (string, error) {
+	at, err := r.uidChangeMethodByKey(authKey)
+	if err != nil {
+		return "", err
+	}
+
+	if current == nil {
+		return "", newError(http.StatusUnauthorized, common.ErrNotAuth)
+	}
+
+	return r.confirmUIDChange(ctx, at, current, code)
+}
+
+// uidChangeMethodByKey returns password or OTP auth method with key. Password method is preferred for empty key
+func (r *Rauther) uidChangeMethodByKey(key string) (*authtype.AuthMethod, error) {
+	at, err := r.authMethodByKey(key, authtype.Password, r.Modules.ChangeUID)
+	if err != nil {
+		return r.authMethodByKey(key, authtype.OTP, r.Modules.ChangeUID)
+	}
+
+	return at, nil
+}
+
+// authMethodByKey returns auth method with key and expected type if module is enabled
+func (r *Rauther) authMethodByKey(key string, t authtype.Type, enabled bool) (*authtype.AuthMethod, error) {
+	if !enabled {
+		return nil, wrapError(http.StatusBadRequest, common.ErrInvalidRequest, errModuleDisabled)
+	}
+
+	if r.methods == nil {
+		return nil, wrapError(http.StatusBadRequest, common.ErrInvalidRequest, errAuthMethodNotFound)
+	}
+
+	if key == "" {
+		key = r.singleAuthMethodKey(t)
+	}
+
+	at, ok := r.methods.List[key]
+	if !ok || at.Type != t {
+		return nil, wrapError(http.StatusBadRequest, common.ErrInvalidRequest,
+			fmt.Errorf("%w: %s", errAuthMethodNotFound, key))
+	}
+
+	return &at, nil
+}
+
+// singleAuthMethodKey returns key of auth method if it is single method of the type
+func (r *Rauther) singleAuthMethodKey(t authtype.Type) (key string) {
+	for k, am := range r.methods.List {
+		if am.Type != t {
+			continue
+		}
+
+		if key != "" {
+			return ""
+		}
+
+		key = k
+	}
+
+	return key
+}
+
+// userSessionInfo returns session info of context with current user
+func (r *Rauther) userSessionInfo(ctx context.Context, u user.User) sessionInfo {
+	info := r.contextSessionInfo(ctx)
+
+	info.User = u
+	info.UserID = u.GetID()
+	info.UserIsGuest = false
+
+	if r.Modules.GuestUser {
+		info.UserIsGuest = u.(user.GuestUser).IsGuest()
+	}
+
+	return info
+}
+
+// contextSessionInfo returns session info of session from context or empty info
+func (r *Rauther) contextSessionInfo(ctx context.Context) sessionInfo {
+	if sess, ok := SessionFromContext(ctx); ok {
+		return r.loadSessionInfo(ctx, sess)
+	}
+
+	return sessionInfo{}
+}
@@ -0,0 +1,85 @@
+package rauther_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rosberry/rauther"
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/deps"
+	"github.com/rosberry/rauther/example/basic/models"
+	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/sender/sendertest"
+)
+
+func TestInitWithoutRouter(t *testing.T) {
+	users := &models.UserStorer{Users: map[uint]*models.User{}}
+	sessions := &models.Sessioner{Sessions: map[string]*models.Session{}}
+	rec := sendertest.New()
+
+	r := rauther.New(deps.NewWithRouter(nil, deps.Storage{SessionStorer: sessions, UserStorer: users}))
+	r.DefaultSender(rec)
+
+	if err := r.InitHandlers(); !errors.Is(err, common.Errors[common.ErrGinDependency]) {
+		t.Fatalf("InitHandlers() error = %v, want %v", err, common.Errors[common.ErrGinDependency])
+	}
+
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if _, err := r.SignUp(ctx, "email", "user@mail.com", "password", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	code, ok := rec.LastCode("user@mail.com", sender.ConfirmationEvent)
+	if !ok {
+		t.Fatal("confirmation code is not sent")
+	}
+
+	if err := r.Confirm(ctx, "email", "user@mail.com", code); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.SignIn(ctx, "email", "user@mail.com", "password"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultEmailUIDPipeline(t *testing.T) {
+	tests := []struct {
+		name    string
+		disable bool
+		wantErr bool
+	}{
+		{name: "default pipeline"},
+		{name: "disabled pipeline", disable: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		users := &models.UserStorer{Users: map[uint]*models.User{}}
+		sessions := &models.Sessioner{Sessions: map[string]*models.Session{}}
+		rec := sendertest.New()
+
+		r := rauther.New(deps.NewWithRouter(nil, deps.Storage{SessionStorer: sessions, UserStorer: users}))
+		r.AddAuthMethod(authtype.AuthMethod{Key: "email", Sender: rec, DisableDefaultUIDPipeline: tt.disable})
+
+		if err := r.Init(); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx := context.Background()
+
+		if _, err := r.SignUp(ctx, "email", " User@Mail.com", "password", nil); err != nil {
+			t.Fatalf("%s: SignUp() error = %v", tt.name, err)
+		}
+
+		if _, err := r.SignIn(ctx, "email", "user@mail.com", "password"); (err != nil) != tt.wantErr {
+			t.Errorf("%s: SignIn() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -1,82 +1,106 @@
 package rauther
 
 import (
+	"context"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/metrics"
 	"github.com/rosberry/rauther/session"
+	"github.com/rosberry/rauther/transport"
 	"github.com/rosberry/rauther/user"
 )
 
-func (r *Rauther) authHandler() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		type authRequest struct {
-			DeviceID string `json:"device_id"`
-		}
+type authRequest struct {
+	DeviceID string `json:"device_id"`
+}
 
+func (r *Rauther) authHandler() transport.HandlerFunc {
+	return func(c transport.Context) {
 		var request authRequest
 
 		err := c.Bind(&request)
 		if err != nil {
-			errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+			r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 			return
 		}
 
-		sessionID := request.DeviceID
-		if sessionID == "" {
-			sessionID = generateSessionID()
+		sess, err := r.startSession(requestContext(c), request.DeviceID)
+		if err != nil {
+			r.flowErrorResponse(c, err)
+			return
 		}
 
-		session := r.deps.SessionStorer.LoadByID(sessionID)
-		if session == nil {
-			errorResponse(c, http.StatusInternalServerError, common.ErrSessionLoad)
-			return
+		respMap := gin.H{
+			"result":    true,
+			"device_id": sess.DeviceID,
+			"token":     sess.Session.GetToken(),
 		}
 
-		if userID := session.GetUserID(); userID != nil {
-			if u, err := r.deps.UserStorer.LoadByID(userID); err == nil && u != nil {
-				if !u.(user.GuestUser).IsGuest() {
-					session.UnbindUser()
-				}
-			}
+		if r.hooks.AfterAuth != nil {
+			r.hooks.AfterAuth(respMap, sess.Session)
 		}
 
-		// Create new guest user if it enabled in config
-		if r.Modules.PasswordAuthableUser && r.Modules.GuestUser && session.GetUserID() == nil {
-			user, errType := r.createGuestUser()
-			if errType != 0 {
-				errorResponse(c, http.StatusInternalServerError, errType)
-				return
-			}
+		r.respond(c, Response{
+			Route:    RouteAuth,
+			Token:    sess.Session.GetToken(),
+			DeviceID: sess.DeviceID,
+			Session:  sess.Session,
+			Fields:   respMap,
+		})
+	}
+}
 
-			session.BindUser(user)
-		}
+// startSession loads session by device ID (new ID is generated if empty) and sets new token
+func (r *Rauther) startSession(ctx context.Context, deviceID string) (res *SessionResult, err error) {
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowAuth, nil)
+	defer endFlow(&err)
 
-		session.SetToken(generateSessionToken())
+	if deviceID == "" {
+		deviceID = generateSessionID()
+	}
 
-		err = r.deps.SessionStorer.Save(session)
-		if err != nil {
-			errorResponse(c, http.StatusInternalServerError, common.ErrSessionSave)
-			return
-		}
+	session := r.sessions(ctx).LoadByID(deviceID)
+	if session == nil {
+		return nil, newError(http.StatusInternalServerError, common.ErrSessionLoad)
+	}
 
-		respMap := gin.H{
-			"result":    true,
-			"device_id": sessionID,
-			"token":     session.GetToken(),
+	if userID := session.GetUserID(); userID != nil {
+		if u, err := r.users(ctx).LoadByID(userID); err == nil && u != nil {
+			if !u.(user.GuestUser).IsGuest() {
+				session.UnbindUser()
+			}
 		}
+	}
 
-		if r.hooks.AfterAuth != nil {
-			r.hooks.AfterAuth(respMap, session)
+	// Create new guest user if it enabled in config
+	if r.Modules.PasswordAuthableUser && r.Modules.GuestUser && session.GetUserID() == nil {
+		user, errType := r.createGuestUser(ctx)
+		if errType != 0 {
+			return nil, newError(http.StatusInternalServerError, errType)
 		}
 
-		c.JSON(http.StatusOK, respMap)
+		session.BindUser(user)
+	}
+
+	session.SetToken(generateSessionToken())
+
+	if err := r.sessions(ctx).Save(session); err != nil {
+		return nil, wrapError(http.StatusInternalServerError, common.ErrSessionSave, err)
 	}
+
+	r.publish(ctx, events.SessionStarted{DeviceID: deviceID, Session: session})
+
+	return &SessionResult{
+		DeviceID: deviceID,
+		Session:  session,
+	}, nil
 }
 
-func (r *Rauther) checkAuthHandler(c *gin.Context) {
+func (r *Rauther) checkAuthHandler(c transport.Context) {
 	sessionInfo, ok := r.checkSession(c)
 	if !ok {
 		return
@@ -90,7 +114,12 @@ func (r *Rauther) checkAuthHandler(c *gin.Context) {
 		r.hooks.AfterAuthCheck(respMap, sessionInfo.Session)
 	}
 
-	c.JSON(http.StatusOK, respMap)
+	r.respond(c, Response{
+		Route:   RouteCheckAuth,
+		User:    sessionInfo.User,
+		Session: sessionInfo.Session,
+		Fields:  respMap,
+	})
 }
 
 type sessionInfo struct {
@@ -101,26 +130,31 @@ type sessionInfo struct {
 }
 
 // Check user in current session
-func (r *Rauther) checkSession(c *gin.Context) (info sessionInfo, success bool) {
+func (r *Rauther) checkSession(c transport.Context) (info sessionInfo, success bool) {
 	s, ok := c.Get(r.Config.ContextNames.Session)
 	if !ok {
-		errorResponse(c, http.StatusUnauthorized, common.ErrNotAuth)
+		r.errorResponse(c, http.StatusUnauthorized, common.ErrNotAuth)
 		return
 	}
 
 	sess, ok := s.(session.Session)
 	if !ok {
 		log.Fatal("failed 'sess' type assertion to session.Session")
-		errorResponse(c, http.StatusUnauthorized, common.ErrNotAuth)
+		r.errorResponse(c, http.StatusUnauthorized, common.ErrNotAuth)
 
 		return
 	}
 
+	return r.loadSessionInfo(requestContext(c), sess), true
+}
+
+// loadSessionInfo returns session with current user
+func (r *Rauther) loadSessionInfo(ctx context.Context, sess session.Session) sessionInfo {
 	var currentUserIsGuest bool
 
 	currentUserID := sess.GetUserID()
 	if currentUserID != nil {
-		currentUser, _ := r.deps.UserStorer.LoadByID(currentUserID)
+		currentUser, _ := r.users(ctx).LoadByID(currentUserID)
 
 		if currentUser != nil && r.Modules.GuestUser {
 			currentUserIsGuest = currentUser.(user.GuestUser).IsGuest()
@@ -131,7 +165,7 @@ func (r *Rauther) checkSession(c *gin.Context) (info sessionInfo, success bool)
 			User:        currentUser,
 			UserID:      currentUserID,
 			UserIsGuest: currentUserIsGuest,
-		}, true
+		}
 	}
 
 	return sessionInfo{
@@ -139,5 +173,5 @@ func (r *Rauther) checkSession(c *gin.Context) (info sessionInfo, success bool)
 		User:        nil,
 		UserID:      currentUserID,
 		UserIsGuest: currentUserIsGuest,
-	}, true
+	}
 }
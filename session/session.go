@@ -11,3 +11,8 @@ type Session interface {
 	BindUser(u user.User)
 	UnbindUser()
 }
+
+// IDSession is optional interface of Session with ID (device ID of auth handler). Used in audit events
+type IDSession interface {
+	GetID() (id string)
+}
@@ -1,34 +1,45 @@
 package rauther
 
 import (
-	"errors"
-	"log"
+	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gin-gonic/gin/binding"
 	"github.com/rosberry/auth"
+	"github.com/rosberry/rauther/audit"
 	"github.com/rosberry/rauther/authtype"
 	"github.com/rosberry/rauther/common"
-	"github.com/rosberry/rauther/storage"
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/hooks"
+	"github.com/rosberry/rauther/logger"
+	"github.com/rosberry/rauther/metrics"
+	"github.com/rosberry/rauther/transport"
 	"github.com/rosberry/rauther/user"
 )
 
-func (r *Rauther) socialSignInHandler(c *gin.Context) {
+type socialInput struct {
+	at           *authtype.AuthMethod
+	token        string
+	fields       map[string]interface{}
+	confirmMerge bool
+	session      sessionInfo
+	tContext     transport.Context
+}
+
+func (r *Rauther) socialSignInHandler(c transport.Context) {
 	at, ok := r.findAuthMethod(c, authtype.Social)
 	if !ok {
-		log.Print("not found expected auth method")
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
 
 	request := clone(at.SocialSignInRequest).(authtype.SocialAuthRequest)
 
-	err := c.ShouldBindBodyWith(request, binding.JSON)
+	err := c.BindJSON(request)
 	if err != nil {
-		log.Print("social sign in handler:", err)
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		r.logRequest(c, logger.DebugLevel, "invalid request", logger.Err(err))
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
 
 		return
 	}
@@ -38,70 +49,98 @@ func (r *Rauther) socialSignInHandler(c *gin.Context) {
 		return
 	}
 
+	result, err := r.socialSignIn(contextWithRequest(requestContext(c), request), socialInput{
+		at:           at,
+		token:        request.GetToken(),
+		fields:       requestFields(request),
+		confirmMerge: requestConfirmMerge(request),
+		session:      sessionInfo,
+		tContext:     c,
+	})
+	if err != nil {
+		r.flowErrorResponse(c, err)
+		return
+	}
+
+	c.Set(r.Config.ContextNames.Session, sessionInfo.Session)
+	c.Set(r.Config.ContextNames.User, result.User)
+
+	respMap := gin.H{
+		"result": true,
+	}
+
+	if result.IsNew {
+		if r.hooks.AfterSocialSignUp != nil {
+			r.hooks.AfterSocialSignUp(respMap, sessionInfo.Session, result.User, at.Key)
+		}
+	} else if r.hooks.AfterSocialSignIn != nil {
+		r.hooks.AfterSocialSignIn(respMap, sessionInfo.Session, result.User, at.Key)
+	}
+
+	r.respond(c, Response{
+		Route:   RouteSocialSignIn,
+		AuthKey: at.Key,
+		IsNew:   result.IsNew,
+		User:    result.User,
+		Session: sessionInfo.Session,
+		Fields:  respMap,
+	})
+}
+
+func (r *Rauther) socialSignIn(ctx context.Context, in socialInput) (res *SocialResult, err error) { // nolint:cyclop
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowSocialSignIn, in.at)
+	defer endFlow(&err)
+
+	at, sessionInfo := in.at, in.session
+
+	rec := r.startAudit(ctx, audit.SignIn, at, "")
+	defer rec.end(&err)
+	rec.setSession(sessionInfo)
+
 	var linkAccount bool
 
 	if sessionInfo.User != nil && !sessionInfo.UserIsGuest {
 		if !r.Modules.LinkAccount {
-			errorResponse(c, http.StatusBadRequest, common.ErrAlreadyAuth)
-			return
+			return nil, newError(http.StatusBadRequest, common.ErrAlreadyAuth)
 		}
 
 		if at.DisableLink {
-			errorResponse(c, http.StatusBadRequest, common.ErrLinkingNotAllowed)
-			return
+			return nil, newError(http.StatusBadRequest, common.ErrLinkingNotAllowed)
 		}
 
 		linkAccount = true
+		rec.event.Type = audit.Link
 	}
 
-	token := request.GetToken()
-	if token == "" {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidAuthToken)
-		return
+	if in.token == "" {
+		return nil, newError(http.StatusBadRequest, common.ErrInvalidAuthToken)
 	}
 
-	userInfo, err := auth.Auth(token, at.SocialAuthType)
+	userInfo, err := auth.Auth(in.token, at.SocialAuthType)
 	if err != nil {
-		errorResponse(c, http.StatusBadRequest, common.ErrInvalidAuthToken)
-		return
+		return nil, wrapError(http.StatusBadRequest, common.ErrInvalidAuthToken, err)
 	}
 
-	var u user.User
+	rec.event.UID = userInfo.ID
+
 	var isNew bool
 
-	if socialStorer, ok := r.deps.UserStorer.(storage.SocialStorer); ok {
-		u, err = socialStorer.LoadBySocial(at.Key, user.SocialDetails(userInfo))
-	} else {
-		u, err = r.LoadByUID(at.Key, userInfo.ID)
+	u, ok, err := r.loadBySocial(ctx, at.Key, user.SocialDetails(userInfo))
+	if !ok {
+		u, err = r.loadByUID(ctx, at.Key, userInfo.ID)
 	}
 
-	if err != nil {
-		log.Print(err)
-		var customErr CustomError
-		if errors.As(err, &customErr) {
-			customErrorResponse(c, customErr)
-			return
-		}
+	if err := r.loadError(err); err != nil {
+		return nil, err
 	}
 
 	if linkAccount {
 		if err := r.checkUserCanLinkAccount(sessionInfo.User, at.Key, userInfo.ID); err != nil {
-			switch {
-			case errors.Is(err, errAuthIdentityExists):
-				errorResponse(c, http.StatusBadRequest, common.ErrAuthIdentityExists)
-			case errors.Is(err, errCurrentUserNotConfirmed):
-				errorResponse(c, http.StatusBadRequest, common.ErrUserNotConfirmed)
-			case errors.Is(err, errCannotMergeSelf):
-				errorResponse(c, http.StatusBadRequest, common.ErrCannotMergeSelf)
-			default:
-				errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
-			}
-			return
+			return nil, linkError(err)
 		}
 
 		if !r.Modules.MergeAccount && u != nil {
-			errorResponse(c, http.StatusBadRequest, common.ErrUserExist)
-			return
+			return nil, newError(http.StatusBadRequest, common.ErrUserExist)
 		}
 	}
 
@@ -109,6 +148,10 @@ func (r *Rauther) socialSignInHandler(c *gin.Context) {
 		isNew = true
 		u = r.deps.UserStorer.Create()
 
+		if !linkAccount {
+			rec.event.Type = audit.SignUp
+		}
+
 		if linkAccount {
 			u.(user.TempUser).SetTemp(true)
 		}
@@ -123,77 +166,48 @@ func (r *Rauther) socialSignInHandler(c *gin.Context) {
 			socialUser.SetUserDetails(at.Key, user.SocialDetails(userInfo))
 		}
 
-		if fieldableRequest, ok := request.(authtype.AuthRequestFieldable); ok {
-			if ok := r.fillFields(fieldableRequest, u); !ok {
-				errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
-				return
-			}
+		if ok := r.fillFields(in.fields, u); !ok {
+			return nil, newError(http.StatusBadRequest, common.ErrInvalidRequest)
 		}
 	}
 
-	if linkAccount {
-		var mergeConfirm bool
-
-		if requestWithMergeConfirm, ok := request.(authtype.MergeConfirmRequest); ok {
-			mergeConfirm = requestWithMergeConfirm.GetConfirmMerge()
-		}
+	if err := r.beforeHook(ctx, r.hooks.BeforeSocialSignIn, hooks.Input{AuthMethod: at, UID: userInfo.ID, User: u}, sessionInfo); err != nil { // nolint:lll
+		return nil, err
+	}
 
-		err := r.linkAccount(sessionInfo, u, at, mergeConfirm, c)
-		if err != nil {
-			var mergeErr MergeError
-			var customErr CustomError
-
-			switch {
-			case errors.Is(err, errUserAlreadyRegistered):
-				errorResponse(c, http.StatusBadRequest, common.ErrUserExist)
-			case errors.Is(err, errCannotMergeSelf):
-				errorResponse(c, http.StatusBadRequest, common.ErrCannotMergeSelf)
-			case errors.As(err, &customErr):
-				customErrorResponse(c, customErr)
-			case errors.As(err, &mergeErr):
-				mergeErrorResponse(c, mergeErr)
-			default:
-				errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
-			}
-
-			return
+	if linkAccount {
+		if err := r.linkAccount(ctx, sessionInfo, u, at, in.confirmMerge, in.tContext); err != nil {
+			return nil, linkError(err)
 		}
 
 		u = sessionInfo.User
 	}
 
-	if err = r.deps.UserStorer.Save(u); err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrUserSave)
-		return
+	if err = r.users(ctx).Save(u); err != nil {
+		return nil, wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
 	}
 
-	sessionInfo.Session.BindUser(u)
+	rec.setUser(u)
 
-	if err = r.deps.SessionStorer.Save(sessionInfo.Session); err != nil {
-		errorResponse(c, http.StatusInternalServerError, common.ErrSessionSave)
-		return
-	}
+	if sessionInfo.Session != nil {
+		sessionInfo.Session.BindUser(u)
 
-	if r.Modules.GuestUser && sessionInfo.UserIsGuest {
-		if err := r.deps.Storage.UserRemover.RemoveByID(sessionInfo.UserID); err != nil {
-			log.Printf("Failed delete guest user %v: %v", sessionInfo.UserID, err)
+		if err = r.sessions(ctx).Save(sessionInfo.Session); err != nil {
+			return nil, wrapError(http.StatusInternalServerError, common.ErrSessionSave, err)
 		}
 	}
 
-	c.Set(r.Config.ContextNames.Session, sessionInfo.Session)
-	c.Set(r.Config.ContextNames.User, u)
-
-	respMap := gin.H{
-		"result": true,
+	if r.Modules.GuestUser && sessionInfo.UserIsGuest {
+		r.removeGuestUser(ctx, sessionInfo.UserID)
 	}
 
-	if isNew {
-		if r.hooks.AfterSocialSignUp != nil {
-			r.hooks.AfterSocialSignUp(respMap, sessionInfo.Session, u, at.Key)
-		}
-	} else if r.hooks.AfterSocialSignIn != nil {
-		r.hooks.AfterSocialSignIn(respMap, sessionInfo.Session, u, at.Key)
+	switch {
+	case linkAccount:
+	case isNew:
+		r.publish(ctx, events.UserSignedUp{User: u, AuthKey: at.Key, UID: userInfo.ID, Session: sessionInfo.Session})
+	default:
+		r.publish(ctx, events.UserSignedIn{User: u, AuthKey: at.Key, UID: userInfo.ID, Session: sessionInfo.Session})
 	}
 
-	c.JSON(http.StatusOK, respMap)
+	return &SocialResult{User: u, IsNew: isNew}, nil
 }
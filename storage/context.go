@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/rosberry/rauther/session"
+	"github.com/rosberry/rauther/user"
+)
+
+// Context-aware variants of storage interfaces.
+// Storers may implement them in addition to base interfaces: rauther detects them by type assertion
+// and passes request context (cancellation, deadline, tracing and other request values) to DB queries.
+
+// ContextSessionStorer is optional interface of SessionStorer
+type ContextSessionStorer interface {
+	// LoadByIDContext return Session or create new if not found
+	LoadByIDContext(ctx context.Context, id string) session.Session
+
+	// FindByTokenContext return Session or nil if not found
+	FindByTokenContext(ctx context.Context, token string) session.Session
+
+	// SaveContext save Session
+	SaveContext(ctx context.Context, session session.Session) error
+}
+
+// ContextUserStorer is optional interface of UserStorer. Create has no context variant: it must not use DB
+type ContextUserStorer interface {
+	// LoadByUIDContext return User by uid and auth type or return error if not found.
+	LoadByUIDContext(ctx context.Context, authType, uid string) (user user.User, err error)
+
+	// LoadByIDContext return User by ID or return error if not found.
+	LoadByIDContext(ctx context.Context, id interface{}) (user user.User, err error)
+
+	// SaveContext save User
+	SaveContext(ctx context.Context, user user.User) error
+}
+
+// ContextSocialStorer is optional interface of SocialStorer
+type ContextSocialStorer interface {
+	LoadBySocialContext(ctx context.Context, authType string, userDetails user.SocialDetails) (user user.User, err error)
+}
+
+// ContextRemovableUserStorer is optional interface of RemovableUserStorer
+type ContextRemovableUserStorer interface {
+	RemoveByIDContext(ctx context.Context, id interface{}) error
+}
+
+// ContextUserSessionsFinder is optional interface of UserSessionsFinder
+type ContextUserSessionsFinder interface {
+	FindByUserIDContext(ctx context.Context, userID interface{}) ([]session.Session, error)
+}
+
+type (
+	sessionStorerAdapter      struct{ s SessionStorer }
+	userStorerAdapter         struct{ s UserStorer }
+	socialStorerAdapter       struct{ s SocialStorer }
+	userRemoverAdapter        struct{ s RemovableUserStorer }
+	userSessionsFinderAdapter struct{ s UserSessionsFinder }
+)
+
+// AdaptSessionStorer returns storer itself if it implements ContextSessionStorer or adapter that ignores context
+func AdaptSessionStorer(s SessionStorer) ContextSessionStorer {
+	if cs, ok := s.(ContextSessionStorer); ok {
+		return cs
+	}
+
+	return sessionStorerAdapter{s}
+}
+
+// AdaptUserStorer returns storer itself if it implements ContextUserStorer or adapter that ignores context
+func AdaptUserStorer(s UserStorer) ContextUserStorer {
+	if cs, ok := s.(ContextUserStorer); ok {
+		return cs
+	}
+
+	return userStorerAdapter{s}
+}
+
+// AdaptSocialStorer returns storer itself if it implements ContextSocialStorer or adapter that ignores context
+func AdaptSocialStorer(s SocialStorer) ContextSocialStorer {
+	if cs, ok := s.(ContextSocialStorer); ok {
+		return cs
+	}
+
+	return socialStorerAdapter{s}
+}
+
+// AdaptRemovableUserStorer returns storer itself if it implements ContextRemovableUserStorer
+// or adapter that ignores context
+func AdaptRemovableUserStorer(s RemovableUserStorer) ContextRemovableUserStorer {
+	if cs, ok := s.(ContextRemovableUserStorer); ok {
+		return cs
+	}
+
+	return userRemoverAdapter{s}
+}
+
+// AdaptUserSessionsFinder returns finder itself if it implements ContextUserSessionsFinder
+// or adapter that ignores context
+func AdaptUserSessionsFinder(s UserSessionsFinder) ContextUserSessionsFinder {
+	if cs, ok := s.(ContextUserSessionsFinder); ok {
+		return cs
+	}
+
+	return userSessionsFinderAdapter{s}
+}
+
+func (a sessionStorerAdapter) LoadByIDContext(_ context.Context, id string) session.Session {
+	return a.s.LoadByID(id)
+}
+
+func (a sessionStorerAdapter) FindByTokenContext(_ context.Context, token string) session.Session {
+	return a.s.FindByToken(token)
+}
+
+func (a sessionStorerAdapter) SaveContext(_ context.Context, sess session.Session) error {
+	return a.s.Save(sess)
+}
+
+func (a userStorerAdapter) LoadByUIDContext(_ context.Context, authType, uid string) (user.User, error) {
+	return a.s.LoadByUID(authType, uid)
+}
+
+func (a userStorerAdapter) LoadByIDContext(_ context.Context, id interface{}) (user.User, error) {
+	return a.s.LoadByID(id)
+}
+
+func (a userStorerAdapter) SaveContext(_ context.Context, u user.User) error {
+	return a.s.Save(u)
+}
+
+func (a socialStorerAdapter) LoadBySocialContext(_ context.Context, authType string, details user.SocialDetails) (user.User, error) { // nolint:lll
+	return a.s.LoadBySocial(authType, details)
+}
+
+func (a userRemoverAdapter) RemoveByIDContext(_ context.Context, id interface{}) error {
+	return a.s.RemoveByID(id)
+}
+
+func (a userSessionsFinderAdapter) FindByUserIDContext(_ context.Context, userID interface{}) ([]session.Session, error) { // nolint:lll
+	return a.s.FindByUserID(userID)
+}
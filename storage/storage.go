@@ -42,3 +42,16 @@ type SocialStorer interface {
 type RemovableUserStorer interface {
 	RemoveByID(id interface{}) error
 }
+
+// UserSessionsFinder is optional interface of SessionStorer for revocation of all sessions of user
+// after password reset by link
+type UserSessionsFinder interface {
+	// FindByUserID returns sessions bound to user
+	FindByUserID(userID interface{}) ([]session.Session, error)
+}
+
+// SessionCounter is optional interface of SessionStorer for active sessions gauge of metrics
+type SessionCounter interface {
+	// CountSessions returns number of active sessions
+	CountSessions() (int, error)
+}
@@ -0,0 +1,151 @@
+package rauther
+
+import (
+	"context"
+
+	"github.com/rosberry/rauther/session"
+	"github.com/rosberry/rauther/storage"
+	"github.com/rosberry/rauther/user"
+)
+
+// users returns user storer bound to ctx: context-aware methods of storer get ctx, calls are traced as child spans
+func (r *Rauther) users(ctx context.Context) storage.UserStorer {
+	if r.deps.UserStorer == nil {
+		return nil
+	}
+
+	return &ctxUserStorer{
+		UserStorer: r.deps.UserStorer,
+		cs:         storage.AdaptUserStorer(r.deps.UserStorer),
+		ctx:        ctx,
+		r:          r,
+	}
+}
+
+// sessions returns session storer bound to ctx
+func (r *Rauther) sessions(ctx context.Context) storage.SessionStorer {
+	if r.deps.SessionStorer == nil {
+		return nil
+	}
+
+	return &ctxSessionStorer{
+		SessionStorer: r.deps.SessionStorer,
+		cs:            storage.AdaptSessionStorer(r.deps.SessionStorer),
+		ctx:           ctx,
+		r:             r,
+	}
+}
+
+// remover returns user remover bound to ctx
+func (r *Rauther) remover(ctx context.Context) storage.RemovableUserStorer {
+	if r.deps.UserRemover == nil {
+		return nil
+	}
+
+	return &ctxUserRemover{
+		cs:  storage.AdaptRemovableUserStorer(r.deps.UserRemover),
+		ctx: ctx,
+		r:   r,
+	}
+}
+
+type (
+	ctxUserStorer struct {
+		storage.UserStorer
+		cs  storage.ContextUserStorer
+		ctx context.Context
+		r   *Rauther
+	}
+
+	ctxSessionStorer struct {
+		storage.SessionStorer
+		cs  storage.ContextSessionStorer
+		ctx context.Context
+		r   *Rauther
+	}
+
+	ctxUserRemover struct {
+		cs  storage.ContextRemovableUserStorer
+		ctx context.Context
+		r   *Rauther
+	}
+)
+
+func (s *ctxUserStorer) LoadByUID(authType, uid string) (u user.User, err error) {
+	ctx, span := s.r.startSpan(s.ctx, "UserStorer.LoadByUID", AuthKeyAttribute.String(authType))
+	defer func() { endSpan(span, err) }()
+
+	return s.cs.LoadByUIDContext(ctx, authType, uid)
+}
+
+func (s *ctxUserStorer) LoadByID(id interface{}) (u user.User, err error) {
+	ctx, span := s.r.startSpan(s.ctx, "UserStorer.LoadByID")
+	defer func() { endSpan(span, err) }()
+
+	return s.cs.LoadByIDContext(ctx, id)
+}
+
+func (s *ctxUserStorer) Save(u user.User) (err error) {
+	ctx, span := s.r.startSpan(s.ctx, "UserStorer.Save")
+	defer func() { endSpan(span, err) }()
+
+	return s.cs.SaveContext(ctx, u)
+}
+
+// loadBySocial loads user by social details if user storer implements storage.SocialStorer
+func (r *Rauther) loadBySocial(ctx context.Context, authType string, details user.SocialDetails) (u user.User, ok bool, err error) { // nolint:lll
+	socialStorer, ok := r.deps.UserStorer.(storage.SocialStorer)
+	if !ok {
+		return nil, false, nil
+	}
+
+	ctx, span := r.startSpan(ctx, "UserStorer.LoadBySocial", AuthKeyAttribute.String(authType))
+	defer func() { endSpan(span, err) }()
+
+	u, err = storage.AdaptSocialStorer(socialStorer).LoadBySocialContext(ctx, authType, details)
+
+	return u, true, err
+}
+
+// findUserSessions loads sessions bound to user if session storer implements storage.UserSessionsFinder
+func (r *Rauther) findUserSessions(ctx context.Context, userID interface{}) (sessions []session.Session, ok bool, err error) { // nolint:lll
+	finder, ok := r.deps.SessionStorer.(storage.UserSessionsFinder)
+	if !ok {
+		return nil, false, nil
+	}
+
+	ctx, span := r.startSpan(ctx, "SessionStorer.FindByUserID")
+	defer func() { endSpan(span, err) }()
+
+	sessions, err = storage.AdaptUserSessionsFinder(finder).FindByUserIDContext(ctx, userID)
+
+	return sessions, true, err
+}
+
+func (s *ctxSessionStorer) LoadByID(id string) session.Session {
+	ctx, span := s.r.startSpan(s.ctx, "SessionStorer.LoadByID")
+	defer span.End()
+
+	return s.cs.LoadByIDContext(ctx, id)
+}
+
+func (s *ctxSessionStorer) FindByToken(token string) session.Session {
+	ctx, span := s.r.startSpan(s.ctx, "SessionStorer.FindByToken")
+	defer span.End()
+
+	return s.cs.FindByTokenContext(ctx, token)
+}
+
+func (s *ctxSessionStorer) Save(sess session.Session) (err error) {
+	ctx, span := s.r.startSpan(s.ctx, "SessionStorer.Save")
+	defer func() { endSpan(span, err) }()
+
+	return s.cs.SaveContext(ctx, sess)
+}
+
+func (s *ctxUserRemover) RemoveByID(id interface{}) (err error) {
+	ctx, span := s.r.startSpan(s.ctx, "UserRemover.RemoveByID")
+	defer func() { endSpan(span, err) }()
+
+	return s.cs.RemoveByIDContext(ctx, id)
+}
@@ -0,0 +1,122 @@
+package rauther
+
+import (
+	"context"
+
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/transport"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName = "github.com/rosberry/rauther"
+
+	// requestContextKey is key of request context with span of handler in transport context
+	requestContextKey = "rauther_context"
+)
+
+// Attributes of spans
+const (
+	AuthKeyAttribute   = attribute.Key("rauther.auth_key")
+	AuthTypeAttribute  = attribute.Key("rauther.auth_type")
+	ErrorCodeAttribute = attribute.Key("rauther.error_code")
+	FlowAttribute      = attribute.Key("rauther.flow")
+)
+
+// nolint:gochecknoglobals
+var authTypeNames = map[authtype.Type]string{
+	authtype.Password: "password",
+	authtype.Social:   "social",
+	authtype.OTP:      "otp",
+}
+
+// requestContext returns context of request with span of handler and client info
+func requestContext(c transport.Context) context.Context {
+	req := c.Request()
+	ctx := req.Context()
+
+	if v, ok := c.Get(requestContextKey); ok {
+		if spanCtx, ok := v.(context.Context); ok {
+			ctx = spanCtx
+		}
+	}
+
+	ctx = context.WithValue(ctx, httpRequestContextKey, req)
+
+	return ContextWithClient(ctx, clientIP(req), req.UserAgent())
+}
+
+// traceMiddleware starts span of handler. It is added to root router if tracer provider is set
+func (r *Rauther) traceMiddleware(c transport.Context) {
+	req := c.Request()
+
+	ctx, span := r.tracer.Start(req.Context(), "rauther "+req.Method+" "+req.URL.Path)
+	defer span.End()
+
+	c.Set(requestContextKey, ctx)
+	c.Next()
+}
+
+// startSpan starts child span of ctx
+func (r *Rauther) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records error of dependency call and ends span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// setSpanError records flow error with error code attribute
+func setSpanError(span trace.Span, err error) {
+	_, body, _ := ErrorDetails(err)
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.SetAttributes(ErrorCodeAttribute.String(body.Code))
+}
+
+// authMethodAttributes returns attributes of auth method
+func authMethodAttributes(at *authtype.AuthMethod) []attribute.KeyValue {
+	if at == nil {
+		return nil
+	}
+
+	return []attribute.KeyValue{
+		AuthKeyAttribute.String(at.Key),
+		AuthTypeAttribute.String(authTypeNames[at.Type]),
+	}
+}
+
+// startFlow starts span of auth flow. Returned function ends span and counts result of flow in metrics:
+//
+//	ctx, endFlow := r.startFlow(ctx, metrics.FlowSignUp, at)
+//	defer endFlow(&err)
+func (r *Rauther) startFlow(ctx context.Context, flow string, at *authtype.AuthMethod) (context.Context, func(err *error)) {
+	// handler span gets auth method of request
+	trace.SpanFromContext(ctx).SetAttributes(authMethodAttributes(at)...)
+
+	attrs := append([]attribute.KeyValue{FlowAttribute.String(flow)}, authMethodAttributes(at)...)
+	ctx, span := r.startSpan(ctx, "rauther."+flow, attrs...)
+
+	authKey := ""
+	if at != nil {
+		authKey = at.Key
+	}
+
+	return ctx, func(err *error) {
+		if *err != nil {
+			setSpanError(span, *err)
+		}
+
+		span.End()
+		r.observeFlow(flow, authKey, err)
+	}
+}
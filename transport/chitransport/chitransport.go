@@ -0,0 +1,20 @@
+// Package chitransport adapts chi router to rauther transport
+package chitransport
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rosberry/rauther/transport"
+	"github.com/rosberry/rauther/transport/httptransport"
+)
+
+// New returns transport router for chi router. Path parameters use chi syntax, e.g. "otp/{sendby}/code"
+func New(r chi.Router) transport.Router {
+	return httptransport.New(r, httptransport.WithParamFunc(chi.URLParam))
+}
+
+// Middleware converts rauther middlewares to chi middleware
+func Middleware(handlers ...transport.HandlerFunc) func(next http.Handler) http.Handler {
+	return httptransport.Middleware(chi.URLParam, handlers...)
+}
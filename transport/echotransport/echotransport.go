@@ -0,0 +1,78 @@
+// Package echotransport adapts echo to rauther transport
+package echotransport
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rosberry/rauther/transport"
+)
+
+type (
+	// Routes is *echo.Echo or *echo.Group
+	Routes interface {
+		Add(method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) *echo.Route
+	}
+
+	// Router is transport.Router over echo
+	Router struct {
+		routes   Routes
+		prefix   string
+		handlers []transport.HandlerFunc
+	}
+)
+
+// New returns transport router for echo instance or group
+func New(routes Routes) *Router {
+	return &Router{routes: routes}
+}
+
+func (r *Router) Handle(method, path string, handlers ...transport.HandlerFunc) {
+	r.routes.Add(method, transport.JoinPaths(r.prefix, path), Handler(transport.CombineHandlers(r.handlers, handlers)...))
+}
+
+func (r *Router) Group(path string, handlers ...transport.HandlerFunc) transport.Router {
+	return &Router{
+		routes:   r.routes,
+		prefix:   transport.JoinPaths(r.prefix, path),
+		handlers: transport.CombineHandlers(r.handlers, handlers),
+	}
+}
+
+// Handler converts rauther handlers chain to echo handler
+func Handler(handlers ...transport.HandlerFunc) echo.HandlerFunc {
+	return func(ec echo.Context) error {
+		newContext(ec, handlers).Run()
+		return nil
+	}
+}
+
+// Middleware converts rauther middlewares to echo middleware.
+// Values set by middlewares are available in echo context with ec.Get(key).
+func Middleware(handlers ...transport.HandlerFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			var err error
+
+			final := func(c transport.Context) {
+				for k, v := range c.(*transport.HTTPContext).Keys() {
+					ec.Set(k, v)
+				}
+
+				err = next(ec)
+			}
+
+			newContext(ec, transport.CombineHandlers(handlers, []transport.HandlerFunc{final})).Run()
+
+			return err
+		}
+	}
+}
+
+func newContext(ec echo.Context, handlers []transport.HandlerFunc) *transport.HTTPContext {
+	paramFunc := func(_ *http.Request, name string) string {
+		return ec.Param(name)
+	}
+
+	return transport.NewHTTPContext(ec.Response(), ec.Request(), paramFunc, handlers)
+}
@@ -0,0 +1,159 @@
+// Package gintransport adapts gin to rauther transport
+package gintransport
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/rosberry/rauther/transport"
+)
+
+type (
+	// Context is transport.Context over *gin.Context
+	Context struct {
+		c *gin.Context
+	}
+
+	// Router is transport.Router over gin router group
+	Router struct {
+		group *gin.RouterGroup
+	}
+)
+
+// Wrap returns transport context for gin context
+func Wrap(c *gin.Context) *Context {
+	return &Context{c: c}
+}
+
+// Handler converts transport handler to gin handler
+func Handler(h transport.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h(Wrap(c))
+	}
+}
+
+// Handlers converts transport handlers to gin handlers
+func Handlers(handlers []transport.HandlerFunc) []gin.HandlerFunc {
+	ginHandlers := make([]gin.HandlerFunc, len(handlers))
+	for i := range handlers {
+		ginHandlers[i] = Handler(handlers[i])
+	}
+
+	return ginHandlers
+}
+
+// GinContext returns gin context for transport context.
+// For contexts of other transports it returns detached gin context with the request
+// and cached body. It allows to use gin based callbacks (selectors, merge) with any transport.
+// For nil context (calls without HTTP request) it returns empty gin context.
+func GinContext(c transport.Context) *gin.Context {
+	if c == nil {
+		return &gin.Context{}
+	}
+
+	if gc, ok := c.(*Context); ok {
+		return gc.c
+	}
+
+	req := c.Request()
+
+	if body, err := c.Body(); err == nil {
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	return &gin.Context{Request: req}
+}
+
+// Gin returns underlying gin context
+func (c *Context) Gin() *gin.Context {
+	return c.c
+}
+
+func (c *Context) Request() *http.Request {
+	return c.c.Request
+}
+
+func (c *Context) Body() ([]byte, error) {
+	if cb, ok := c.c.Get(gin.BodyBytesKey); ok {
+		if cbb, ok := cb.([]byte); ok {
+			return cbb, nil
+		}
+	}
+
+	body, err := ioutil.ReadAll(c.c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// share cache with gin ShouldBindBodyWith
+	c.c.Set(gin.BodyBytesKey, body)
+
+	return body, nil
+}
+
+func (c *Context) BindJSON(obj interface{}) error {
+	return c.c.ShouldBindBodyWith(obj, binding.JSON)
+}
+
+func (c *Context) Bind(obj interface{}) error {
+	b := binding.Default(c.c.Request.Method, c.c.ContentType())
+	if bb, ok := b.(binding.BindingBody); ok {
+		return c.c.ShouldBindBodyWith(obj, bb)
+	}
+
+	return c.c.ShouldBindWith(obj, b)
+}
+
+func (c *Context) Param(name string) string {
+	return c.c.Param(name)
+}
+
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	return c.c.Get(key)
+}
+
+func (c *Context) Set(key string, value interface{}) {
+	c.c.Set(key, value)
+}
+
+func (c *Context) Header(key, value string) {
+	c.c.Header(key, value)
+}
+
+func (c *Context) JSON(status int, obj interface{}) {
+	c.c.JSON(status, obj)
+}
+
+func (c *Context) Redirect(status int, location string) {
+	c.c.Redirect(status, location)
+}
+
+func (c *Context) Next() {
+	c.c.Next()
+}
+
+func (c *Context) Abort() {
+	c.c.Abort()
+}
+
+// New returns transport router for gin router group
+func New(group *gin.RouterGroup) *Router {
+	return &Router{group: group}
+}
+
+func (r *Router) Handle(method, path string, handlers ...transport.HandlerFunc) {
+	r.group.Handle(method, path, Handlers(handlers)...)
+}
+
+func (r *Router) Group(path string, handlers ...transport.HandlerFunc) transport.Router {
+	return New(r.group.Group(path, Handlers(handlers)...))
+}
+
+// BasePath returns base path of router group
+func (r *Router) BasePath() string {
+	return r.group.BasePath()
+}
@@ -0,0 +1,645 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package authpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// AuthClient is the client API for Auth service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AuthClient interface {
+	// Auth creates session or refreshes token of device. Does not require token
+	Auth(ctx context.Context, in *AuthRequest, opts ...grpc.CallOption) (*AuthResponse, error)
+	CheckAuth(ctx context.Context, in *CheckAuthRequest, opts ...grpc.CallOption) (*CheckAuthResponse, error)
+	SignUp(ctx context.Context, in *SignUpRequest, opts ...grpc.CallOption) (*SignUpResponse, error)
+	SignIn(ctx context.Context, in *SignInRequest, opts ...grpc.CallOption) (*SignInResponse, error)
+	SignOut(ctx context.Context, in *SignOutRequest, opts ...grpc.CallOption) (*SignOutResponse, error)
+	ValidateLoginField(ctx context.Context, in *ValidateLoginFieldRequest, opts ...grpc.CallOption) (*ValidateLoginFieldResponse, error)
+	// Confirm does not require token
+	Confirm(ctx context.Context, in *ConfirmRequest, opts ...grpc.CallOption) (*ConfirmResponse, error)
+	ResendConfirmCode(ctx context.Context, in *ResendConfirmCodeRequest, opts ...grpc.CallOption) (*ResendConfirmCodeResponse, error)
+	RequestRecovery(ctx context.Context, in *RequestRecoveryRequest, opts ...grpc.CallOption) (*RequestRecoveryResponse, error)
+	ValidateRecoveryCode(ctx context.Context, in *ValidateRecoveryCodeRequest, opts ...grpc.CallOption) (*ValidateRecoveryCodeResponse, error)
+	Recover(ctx context.Context, in *RecoverRequest, opts ...grpc.CallOption) (*RecoverResponse, error)
+	RequestOTPCode(ctx context.Context, in *RequestOTPCodeRequest, opts ...grpc.CallOption) (*RequestOTPCodeResponse, error)
+	OTPAuth(ctx context.Context, in *OTPAuthRequest, opts ...grpc.CallOption) (*OTPAuthResponse, error)
+	SocialSignIn(ctx context.Context, in *SocialSignInRequest, opts ...grpc.CallOption) (*SocialSignInResponse, error)
+	InitLink(ctx context.Context, in *InitLinkRequest, opts ...grpc.CallOption) (*InitLinkResponse, error)
+	Link(ctx context.Context, in *LinkRequest, opts ...grpc.CallOption) (*LinkResponse, error)
+}
+
+type authClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthClient(cc grpc.ClientConnInterface) AuthClient {
+	return &authClient{cc}
+}
+
+func (c *authClient) Auth(ctx context.Context, in *AuthRequest, opts ...grpc.CallOption) (*AuthResponse, error) {
+	out := new(AuthResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/Auth", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) CheckAuth(ctx context.Context, in *CheckAuthRequest, opts ...grpc.CallOption) (*CheckAuthResponse, error) {
+	out := new(CheckAuthResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/CheckAuth", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) SignUp(ctx context.Context, in *SignUpRequest, opts ...grpc.CallOption) (*SignUpResponse, error) {
+	out := new(SignUpResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/SignUp", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) SignIn(ctx context.Context, in *SignInRequest, opts ...grpc.CallOption) (*SignInResponse, error) {
+	out := new(SignInResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/SignIn", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) SignOut(ctx context.Context, in *SignOutRequest, opts ...grpc.CallOption) (*SignOutResponse, error) {
+	out := new(SignOutResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/SignOut", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) ValidateLoginField(ctx context.Context, in *ValidateLoginFieldRequest, opts ...grpc.CallOption) (*ValidateLoginFieldResponse, error) {
+	out := new(ValidateLoginFieldResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/ValidateLoginField", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) Confirm(ctx context.Context, in *ConfirmRequest, opts ...grpc.CallOption) (*ConfirmResponse, error) {
+	out := new(ConfirmResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/Confirm", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) ResendConfirmCode(ctx context.Context, in *ResendConfirmCodeRequest, opts ...grpc.CallOption) (*ResendConfirmCodeResponse, error) {
+	out := new(ResendConfirmCodeResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/ResendConfirmCode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) RequestRecovery(ctx context.Context, in *RequestRecoveryRequest, opts ...grpc.CallOption) (*RequestRecoveryResponse, error) {
+	out := new(RequestRecoveryResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/RequestRecovery", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) ValidateRecoveryCode(ctx context.Context, in *ValidateRecoveryCodeRequest, opts ...grpc.CallOption) (*ValidateRecoveryCodeResponse, error) {
+	out := new(ValidateRecoveryCodeResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/ValidateRecoveryCode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) Recover(ctx context.Context, in *RecoverRequest, opts ...grpc.CallOption) (*RecoverResponse, error) {
+	out := new(RecoverResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/Recover", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) RequestOTPCode(ctx context.Context, in *RequestOTPCodeRequest, opts ...grpc.CallOption) (*RequestOTPCodeResponse, error) {
+	out := new(RequestOTPCodeResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/RequestOTPCode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) OTPAuth(ctx context.Context, in *OTPAuthRequest, opts ...grpc.CallOption) (*OTPAuthResponse, error) {
+	out := new(OTPAuthResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/OTPAuth", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) SocialSignIn(ctx context.Context, in *SocialSignInRequest, opts ...grpc.CallOption) (*SocialSignInResponse, error) {
+	out := new(SocialSignInResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/SocialSignIn", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) InitLink(ctx context.Context, in *InitLinkRequest, opts ...grpc.CallOption) (*InitLinkResponse, error) {
+	out := new(InitLinkResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/InitLink", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) Link(ctx context.Context, in *LinkRequest, opts ...grpc.CallOption) (*LinkResponse, error) {
+	out := new(LinkResponse)
+	err := c.cc.Invoke(ctx, "/rauther.auth.v1.Auth/Link", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthServer is the server API for Auth service.
+// All implementations must embed UnimplementedAuthServer
+// for forward compatibility
+type AuthServer interface {
+	// Auth creates session or refreshes token of device. Does not require token
+	Auth(context.Context, *AuthRequest) (*AuthResponse, error)
+	CheckAuth(context.Context, *CheckAuthRequest) (*CheckAuthResponse, error)
+	SignUp(context.Context, *SignUpRequest) (*SignUpResponse, error)
+	SignIn(context.Context, *SignInRequest) (*SignInResponse, error)
+	SignOut(context.Context, *SignOutRequest) (*SignOutResponse, error)
+	ValidateLoginField(context.Context, *ValidateLoginFieldRequest) (*ValidateLoginFieldResponse, error)
+	// Confirm does not require token
+	Confirm(context.Context, *ConfirmRequest) (*ConfirmResponse, error)
+	ResendConfirmCode(context.Context, *ResendConfirmCodeRequest) (*ResendConfirmCodeResponse, error)
+	RequestRecovery(context.Context, *RequestRecoveryRequest) (*RequestRecoveryResponse, error)
+	ValidateRecoveryCode(context.Context, *ValidateRecoveryCodeRequest) (*ValidateRecoveryCodeResponse, error)
+	Recover(context.Context, *RecoverRequest) (*RecoverResponse, error)
+	RequestOTPCode(context.Context, *RequestOTPCodeRequest) (*RequestOTPCodeResponse, error)
+	OTPAuth(context.Context, *OTPAuthRequest) (*OTPAuthResponse, error)
+	SocialSignIn(context.Context, *SocialSignInRequest) (*SocialSignInResponse, error)
+	InitLink(context.Context, *InitLinkRequest) (*InitLinkResponse, error)
+	Link(context.Context, *LinkRequest) (*LinkResponse, error)
+	mustEmbedUnimplementedAuthServer()
+}
+
+// UnimplementedAuthServer must be embedded to have forward compatible implementations.
+type UnimplementedAuthServer struct {
+}
+
+func (UnimplementedAuthServer) Auth(context.Context, *AuthRequest) (*AuthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Auth not implemented")
+}
+func (UnimplementedAuthServer) CheckAuth(context.Context, *CheckAuthRequest) (*CheckAuthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckAuth not implemented")
+}
+func (UnimplementedAuthServer) SignUp(context.Context, *SignUpRequest) (*SignUpResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignUp not implemented")
+}
+func (UnimplementedAuthServer) SignIn(context.Context, *SignInRequest) (*SignInResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignIn not implemented")
+}
+func (UnimplementedAuthServer) SignOut(context.Context, *SignOutRequest) (*SignOutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignOut not implemented")
+}
+func (UnimplementedAuthServer) ValidateLoginField(context.Context, *ValidateLoginFieldRequest) (*ValidateLoginFieldResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateLoginField not implemented")
+}
+func (UnimplementedAuthServer) Confirm(context.Context, *ConfirmRequest) (*ConfirmResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Confirm not implemented")
+}
+func (UnimplementedAuthServer) ResendConfirmCode(context.Context, *ResendConfirmCodeRequest) (*ResendConfirmCodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResendConfirmCode not implemented")
+}
+func (UnimplementedAuthServer) RequestRecovery(context.Context, *RequestRecoveryRequest) (*RequestRecoveryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestRecovery not implemented")
+}
+func (UnimplementedAuthServer) ValidateRecoveryCode(context.Context, *ValidateRecoveryCodeRequest) (*ValidateRecoveryCodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateRecoveryCode not implemented")
+}
+func (UnimplementedAuthServer) Recover(context.Context, *RecoverRequest) (*RecoverResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Recover not implemented")
+}
+func (UnimplementedAuthServer) RequestOTPCode(context.Context, *RequestOTPCodeRequest) (*RequestOTPCodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestOTPCode not implemented")
+}
+func (UnimplementedAuthServer) OTPAuth(context.Context, *OTPAuthRequest) (*OTPAuthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method OTPAuth not implemented")
+}
+func (UnimplementedAuthServer) SocialSignIn(context.Context, *SocialSignInRequest) (*SocialSignInResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SocialSignIn not implemented")
+}
+func (UnimplementedAuthServer) InitLink(context.Context, *InitLinkRequest) (*InitLinkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InitLink not implemented")
+}
+func (UnimplementedAuthServer) Link(context.Context, *LinkRequest) (*LinkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Link not implemented")
+}
+func (UnimplementedAuthServer) mustEmbedUnimplementedAuthServer() {}
+
+// UnsafeAuthServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuthServer will
+// result in compilation errors.
+type UnsafeAuthServer interface {
+	mustEmbedUnimplementedAuthServer()
+}
+
+func RegisterAuthServer(s grpc.ServiceRegistrar, srv AuthServer) {
+	s.RegisterService(&Auth_ServiceDesc, srv)
+}
+
+func _Auth_Auth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).Auth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/Auth",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).Auth(ctx, req.(*AuthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_CheckAuth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckAuthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).CheckAuth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/CheckAuth",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).CheckAuth(ctx, req.(*CheckAuthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_SignUp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignUpRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).SignUp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/SignUp",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).SignUp(ctx, req.(*SignUpRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_SignIn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignInRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).SignIn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/SignIn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).SignIn(ctx, req.(*SignInRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_SignOut_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignOutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).SignOut(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/SignOut",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).SignOut(ctx, req.(*SignOutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_ValidateLoginField_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateLoginFieldRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).ValidateLoginField(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/ValidateLoginField",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).ValidateLoginField(ctx, req.(*ValidateLoginFieldRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_Confirm_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).Confirm(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/Confirm",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).Confirm(ctx, req.(*ConfirmRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_ResendConfirmCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResendConfirmCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).ResendConfirmCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/ResendConfirmCode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).ResendConfirmCode(ctx, req.(*ResendConfirmCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_RequestRecovery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestRecoveryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).RequestRecovery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/RequestRecovery",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).RequestRecovery(ctx, req.(*RequestRecoveryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_ValidateRecoveryCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateRecoveryCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).ValidateRecoveryCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/ValidateRecoveryCode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).ValidateRecoveryCode(ctx, req.(*ValidateRecoveryCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_Recover_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecoverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).Recover(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/Recover",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).Recover(ctx, req.(*RecoverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_RequestOTPCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestOTPCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).RequestOTPCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/RequestOTPCode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).RequestOTPCode(ctx, req.(*RequestOTPCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_OTPAuth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OTPAuthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).OTPAuth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/OTPAuth",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).OTPAuth(ctx, req.(*OTPAuthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_SocialSignIn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SocialSignInRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).SocialSignIn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/SocialSignIn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).SocialSignIn(ctx, req.(*SocialSignInRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_InitLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InitLinkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).InitLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/InitLink",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).InitLink(ctx, req.(*InitLinkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_Link_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LinkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).Link(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rauther.auth.v1.Auth/Link",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).Link(ctx, req.(*LinkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Auth_ServiceDesc is the grpc.ServiceDesc for Auth service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Auth_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rauther.auth.v1.Auth",
+	HandlerType: (*AuthServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Auth",
+			Handler:    _Auth_Auth_Handler,
+		},
+		{
+			MethodName: "CheckAuth",
+			Handler:    _Auth_CheckAuth_Handler,
+		},
+		{
+			MethodName: "SignUp",
+			Handler:    _Auth_SignUp_Handler,
+		},
+		{
+			MethodName: "SignIn",
+			Handler:    _Auth_SignIn_Handler,
+		},
+		{
+			MethodName: "SignOut",
+			Handler:    _Auth_SignOut_Handler,
+		},
+		{
+			MethodName: "ValidateLoginField",
+			Handler:    _Auth_ValidateLoginField_Handler,
+		},
+		{
+			MethodName: "Confirm",
+			Handler:    _Auth_Confirm_Handler,
+		},
+		{
+			MethodName: "ResendConfirmCode",
+			Handler:    _Auth_ResendConfirmCode_Handler,
+		},
+		{
+			MethodName: "RequestRecovery",
+			Handler:    _Auth_RequestRecovery_Handler,
+		},
+		{
+			MethodName: "ValidateRecoveryCode",
+			Handler:    _Auth_ValidateRecoveryCode_Handler,
+		},
+		{
+			MethodName: "Recover",
+			Handler:    _Auth_Recover_Handler,
+		},
+		{
+			MethodName: "RequestOTPCode",
+			Handler:    _Auth_RequestOTPCode_Handler,
+		},
+		{
+			MethodName: "OTPAuth",
+			Handler:    _Auth_OTPAuth_Handler,
+		},
+		{
+			MethodName: "SocialSignIn",
+			Handler:    _Auth_SocialSignIn_Handler,
+		},
+		{
+			MethodName: "InitLink",
+			Handler:    _Auth_InitLink_Handler,
+		},
+		{
+			MethodName: "Link",
+			Handler:    _Auth_Link_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "auth.proto",
+}
@@ -0,0 +1,4 @@
+// Package authpb contains generated code of auth gRPC service
+package authpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative auth.proto
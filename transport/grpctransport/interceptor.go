@@ -0,0 +1,153 @@
+package grpctransport
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/rosberry/rauther"
+	"github.com/rosberry/rauther/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// AuthMethod is full name of Auth method of auth service
+	AuthMethod = "/rauther.auth.v1.Auth/Auth"
+	// ConfirmMethod is full name of Confirm method of auth service
+	ConfirmMethod = "/rauther.auth.v1.Auth/Confirm"
+
+	authorizationKey = "authorization"
+	userAgentKey     = "user-agent"
+	bearerPrefix     = "Bearer "
+)
+
+type userContextKey struct{}
+
+// Interceptor checks session token from "authorization" metadata like auth middleware of HTTP.
+// Session and user are added to context: see rauther.SessionFromContext and UserFromContext
+type Interceptor struct {
+	r      *rauther.Rauther
+	public map[string]bool
+}
+
+// NewInterceptor returns interceptor. Methods Auth and Confirm of auth service and publicMethods
+// (full method names, e.g. "/package.Service/Method") do not require token
+func NewInterceptor(r *rauther.Rauther, publicMethods ...string) *Interceptor {
+	public := map[string]bool{
+		AuthMethod:    true,
+		ConfirmMethod: true,
+	}
+
+	for _, m := range publicMethods {
+		public[m] = true
+	}
+
+	return &Interceptor{
+		r:      r,
+		public: public,
+	}
+}
+
+// Unary returns unary server interceptor
+func (i *Interceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := i.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns stream server interceptor
+func (i *Interceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (i *Interceptor) authenticate(ctx context.Context, method string) (context.Context, error) {
+	ctx = clientContext(ctx)
+	token := parseToken(ctx)
+
+	// token is optional for public methods
+	if i.public[method] && token == "" {
+		return ctx, nil
+	}
+
+	sess, u, err := i.r.FindSession(ctx, token)
+	if err != nil {
+		return nil, Status(err)
+	}
+
+	ctx = rauther.ContextWithSession(ctx, sess)
+
+	if u != nil {
+		ctx = context.WithValue(ctx, userContextKey{}, u)
+	}
+
+	return ctx, nil
+}
+
+// UserFromContext returns user of session set by interceptor
+func UserFromContext(ctx context.Context) (user.User, bool) {
+	u, ok := ctx.Value(userContextKey{}).(user.User)
+
+	return u, ok && u != nil
+}
+
+// clientContext adds peer address and user agent of client for audit events
+func clientContext(ctx context.Context) context.Context {
+	var ip, userAgent string
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(userAgentKey); len(values) > 0 {
+			userAgent = values[0]
+		}
+	}
+
+	return rauther.ContextWithClient(ctx, ip, userAgent)
+}
+
+func parseToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, value := range md.Get(authorizationKey) {
+		if strings.HasPrefix(value, bearerPrefix) {
+			if token := value[len(bearerPrefix):]; token != "" {
+				return token
+			}
+		}
+	}
+
+	return ""
+}
+
+// serverStream overrides context of stream
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
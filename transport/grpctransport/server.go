@@ -0,0 +1,293 @@
+// Package grpctransport provides gRPC service of rauther. Service is defined in authpb/auth.proto
+// and mirrors HTTP routes. Server delegates to Go API of rauther, so flows are the same as in HTTP handlers.
+//
+// UID change (auth/uid/change, auth/uid/confirm), confirmation link and reset link routes have no RPCs yet.
+// Use Go API methods ChangeUID, ConfirmUIDChange, ConfirmByLink, ExchangeResetLink and ResetPasswordByToken
+// in your services instead.
+package grpctransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rosberry/rauther"
+	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/transport/grpctransport/authpb"
+	"github.com/rosberry/rauther/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements authpb.AuthServer
+type Server struct {
+	authpb.UnimplementedAuthServer
+
+	r *rauther.Rauther
+}
+
+// NewServer returns gRPC auth server. Use it with interceptors of NewInterceptor
+func NewServer(r *rauther.Rauther) *Server {
+	return &Server{r: r}
+}
+
+// Register registers server in gRPC server
+func (s *Server) Register(gs grpc.ServiceRegistrar) {
+	authpb.RegisterAuthServer(gs, s)
+}
+
+func (s *Server) Auth(ctx context.Context, req *authpb.AuthRequest) (*authpb.AuthResponse, error) {
+	res, err := s.r.StartSession(ctx, req.GetDeviceId())
+	if err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.AuthResponse{
+		DeviceId: res.DeviceID,
+		Token:    res.Session.GetToken(),
+	}, nil
+}
+
+func (s *Server) CheckAuth(ctx context.Context, req *authpb.CheckAuthRequest) (*authpb.CheckAuthResponse, error) {
+	if _, ok := rauther.SessionFromContext(ctx); !ok {
+		return nil, Status(errorOf(http.StatusUnauthorized, common.ErrNotAuth))
+	}
+
+	return &authpb.CheckAuthResponse{}, nil
+}
+
+func (s *Server) SignUp(ctx context.Context, req *authpb.SignUpRequest) (*authpb.SignUpResponse, error) {
+	res, err := s.r.SignUp(ctx, req.GetAuthKey(), req.GetUid(), req.GetPassword(), fields(req.GetFields()))
+	if err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.SignUpResponse{Uid: res.UID}, nil
+}
+
+func (s *Server) SignIn(ctx context.Context, req *authpb.SignInRequest) (*authpb.SignInResponse, error) {
+	if _, err := s.r.SignIn(ctx, req.GetAuthKey(), req.GetUid(), req.GetPassword()); err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.SignInResponse{}, nil
+}
+
+func (s *Server) SignOut(ctx context.Context, req *authpb.SignOutRequest) (*authpb.SignOutResponse, error) {
+	sess, err := s.r.SignOut(ctx)
+	if err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.SignOutResponse{Token: sess.GetToken()}, nil
+}
+
+func (s *Server) ValidateLoginField(ctx context.Context, req *authpb.ValidateLoginFieldRequest) (*authpb.ValidateLoginFieldResponse, error) {
+	if err := s.r.CheckUID(ctx, req.GetAuthKey(), req.GetUid()); err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.ValidateLoginFieldResponse{}, nil
+}
+
+func (s *Server) Confirm(ctx context.Context, req *authpb.ConfirmRequest) (*authpb.ConfirmResponse, error) {
+	if err := s.r.Confirm(ctx, req.GetAuthKey(), req.GetUid(), req.GetCode()); err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.ConfirmResponse{}, nil
+}
+
+func (s *Server) ResendConfirmCode(ctx context.Context, req *authpb.ResendConfirmCodeRequest) (*authpb.ResendConfirmCodeResponse, error) {
+	u, _ := UserFromContext(ctx)
+
+	if err := s.r.ResendConfirmCode(ctx, req.GetAuthKey(), u); err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.ResendConfirmCodeResponse{}, nil
+}
+
+func (s *Server) RequestRecovery(ctx context.Context, req *authpb.RequestRecoveryRequest) (*authpb.RequestRecoveryResponse, error) {
+	if err := s.r.RequestRecovery(ctx, req.GetAuthKey(), req.GetUid()); err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.RequestRecoveryResponse{}, nil
+}
+
+func (s *Server) ValidateRecoveryCode(ctx context.Context, req *authpb.ValidateRecoveryCodeRequest) (*authpb.ValidateRecoveryCodeResponse, error) {
+	if err := s.r.ValidateRecoveryCode(ctx, req.GetAuthKey(), req.GetUid(), req.GetCode()); err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.ValidateRecoveryCodeResponse{}, nil
+}
+
+func (s *Server) Recover(ctx context.Context, req *authpb.RecoverRequest) (*authpb.RecoverResponse, error) {
+	if err := s.r.ResetPassword(ctx, req.GetAuthKey(), req.GetUid(), req.GetCode(), req.GetPassword()); err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.RecoverResponse{}, nil
+}
+
+func (s *Server) RequestOTPCode(ctx context.Context, req *authpb.RequestOTPCodeRequest) (*authpb.RequestOTPCodeResponse, error) {
+	if err := s.r.RequestOTP(ctx, req.GetAuthKey(), req.GetUid()); err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.RequestOTPCodeResponse{}, nil
+}
+
+func (s *Server) OTPAuth(ctx context.Context, req *authpb.OTPAuthRequest) (*authpb.OTPAuthResponse, error) {
+	ctx = rauther.ContextWithConfirmMerge(ctx, req.GetConfirmMerge())
+
+	res, err := s.r.VerifyOTP(ctx, req.GetAuthKey(), req.GetUid(), req.GetCode(), fields(req.GetFields()))
+	if err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.OTPAuthResponse{IsNew: res.IsNew}, nil
+}
+
+func (s *Server) SocialSignIn(ctx context.Context, req *authpb.SocialSignInRequest) (*authpb.SocialSignInResponse, error) {
+	ctx = rauther.ContextWithConfirmMerge(ctx, req.GetConfirmMerge())
+
+	res, err := s.r.SocialSignIn(ctx, req.GetAuthKey(), req.GetToken(), fields(req.GetFields()))
+	if err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.SocialSignInResponse{IsNew: res.IsNew}, nil
+}
+
+func (s *Server) InitLink(ctx context.Context, req *authpb.InitLinkRequest) (*authpb.InitLinkResponse, error) {
+	u, err := s.signedInUser(ctx)
+	if err != nil {
+		return nil, Status(err)
+	}
+
+	res, err := s.r.InitLink(ctx, u, req.GetAuthKey(), req.GetUid())
+	if err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.InitLinkResponse{
+		Action:              res.Action,
+		ConfirmCodeRequired: res.ConfirmCodeRequired,
+	}, nil
+}
+
+func (s *Server) Link(ctx context.Context, req *authpb.LinkRequest) (*authpb.LinkResponse, error) {
+	u, err := s.signedInUser(ctx)
+	if err != nil {
+		return nil, Status(err)
+	}
+
+	err = s.r.Link(ctx, u, req.GetAuthKey(), rauther.LinkRequest{
+		UID:          req.GetUid(),
+		Password:     req.GetPassword(),
+		Code:         req.GetCode(),
+		Merge:        req.GetMerge(),
+		ConfirmMerge: req.GetConfirmMerge(),
+	})
+	if err != nil {
+		return nil, Status(err)
+	}
+
+	return &authpb.LinkResponse{}, nil
+}
+
+// signedInUser returns user of session if it is not guest, like auth user middleware
+func (s *Server) signedInUser(ctx context.Context) (user.User, error) {
+	u, ok := UserFromContext(ctx)
+	if !ok {
+		return nil, errorOf(http.StatusUnauthorized, common.ErrNotAuth)
+	}
+
+	if guest, ok := u.(user.GuestUser); ok && s.r.Modules.GuestUser && guest.IsGuest() {
+		return nil, errorOf(http.StatusUnauthorized, common.ErrNotSignIn)
+	}
+
+	return u, nil
+}
+
+func errorOf(status int, errType common.ErrTypes) error {
+	return &rauther.Error{Type: errType, Status: status}
+}
+
+func fields(f map[string]string) map[string]interface{} {
+	if len(f) == 0 {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(f))
+
+	for k, v := range f {
+		result[k] = v
+	}
+
+	return result
+}
+
+// Status converts error of rauther to gRPC status error with authpb.Error detail
+func Status(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var st interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &st) {
+		return err
+	}
+
+	httpStatus, body, info := rauther.ErrorDetails(err)
+
+	detail := &authpb.Error{
+		Code:    body.Code,
+		Message: body.Message,
+	}
+
+	if info != nil {
+		if data, err := json.Marshal(info); err == nil {
+			detail.Info = string(data)
+		}
+	}
+
+	s := status.New(Code(httpStatus), body.Message)
+
+	if withDetails, err := s.WithDetails(detail); err == nil {
+		s = withDetails
+	}
+
+	return s.Err()
+}
+
+// Code returns gRPC code for HTTP status
+func Code(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		if httpStatus >= http.StatusInternalServerError {
+			return codes.Internal
+		}
+
+		return codes.Unknown
+	}
+}
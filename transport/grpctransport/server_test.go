@@ -0,0 +1,149 @@
+package grpctransport_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rosberry/rauther"
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/deps"
+	"github.com/rosberry/rauther/example/basic/models"
+	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/sender/sendertest"
+	"github.com/rosberry/rauther/transport/grpctransport"
+	"github.com/rosberry/rauther/transport/grpctransport/authpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorCode returns code of authpb.Error detail of status error
+func errorCode(err error) (codes.Code, string) {
+	st := status.Convert(err)
+
+	for _, d := range st.Details() {
+		if e, ok := d.(*authpb.Error); ok {
+			return st.Code(), e.GetCode()
+		}
+	}
+
+	return st.Code(), ""
+}
+
+func newProtectedServer(t *testing.T, configure func(r *rauther.Rauther)) (*grpctransport.Server, *sendertest.Recorder) { // nolint:lll
+	t.Helper()
+
+	users := &models.UserStorer{Users: map[uint]*models.User{}}
+	sessions := &models.Sessioner{Sessions: map[string]*models.Session{}}
+	rec := sendertest.New()
+
+	r := rauther.New(deps.NewWithRouter(nil, deps.Storage{SessionStorer: sessions, UserStorer: users}))
+	r.AddAuthMethod(authtype.AuthMethod{Key: "email", Sender: rec})
+	r.Config.UserEnumeration.Protect = true
+	r.Config.UserEnumeration.CheckLimit = 2
+
+	if configure != nil {
+		configure(r)
+	}
+
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if _, err := r.SignUp(ctx, "email", "user@mail.com", "password", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return grpctransport.NewServer(r), rec
+}
+
+func TestRequestRecoveryProtection(t *testing.T) {
+	s, _ := newProtectedServer(t, nil)
+	ctx := context.Background()
+
+	if _, err := s.RequestRecovery(ctx, &authpb.RequestRecoveryRequest{Uid: "unknown@mail.com"}); err != nil {
+		t.Errorf("RequestRecovery() of not existing user error = %v", err)
+	}
+
+	// code timeout of sign-up is not returned, request is processed after response
+	if _, err := s.RequestRecovery(ctx, &authpb.RequestRecoveryRequest{Uid: "user@mail.com"}); err != nil {
+		t.Errorf("RequestRecovery() of existing user error = %v", err)
+	}
+
+	if _, err := s.RequestRecovery(ctx, &authpb.RequestRecoveryRequest{Uid: "not an email"}); err == nil {
+		t.Error("RequestRecovery() of invalid UID succeeded")
+	}
+}
+
+func TestRequestRecoveryDetached(t *testing.T) {
+	s, rec := newProtectedServer(t, func(r *rauther.Rauther) { r.Config.Password.ResendDelay = 0 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if _, err := s.RequestRecovery(ctx, &authpb.RequestRecoveryRequest{Uid: "user@mail.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// canceled context of finished request does not stop sending
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+
+	for {
+		if _, ok := rec.LastCode("user@mail.com", sender.PasswordRecoveryEvent); ok {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("recovery code is not sent")
+		}
+
+		time.Sleep(10 * time.Millisecond) // nolint:gomnd
+	}
+}
+
+func TestSignInProtection(t *testing.T) {
+	s, _ := newProtectedServer(t, nil)
+	ctx := context.Background()
+
+	for _, req := range []*authpb.SignInRequest{
+		{Uid: "unknown@mail.com", Password: "password"},
+		{Uid: "user@mail.com", Password: "wrong password"},
+	} {
+		_, err := s.SignIn(ctx, req)
+		if code, errCode := errorCode(err); code != codes.PermissionDenied || errCode != "invalid_credentials" {
+			t.Errorf("SignIn(%s) error = %v %q, want PermissionDenied invalid_credentials", req.GetUid(), code, errCode)
+		}
+	}
+}
+
+func TestValidateLoginFieldProtection(t *testing.T) {
+	s, _ := newProtectedServer(t, nil)
+
+	client := rauther.ContextWithClient(context.Background(), "192.0.2.1", "test")
+	other := rauther.ContextWithClient(context.Background(), "192.0.2.2", "test")
+
+	for _, uid := range []string{"new@mail.com", "other@mail.com"} {
+		if _, err := s.ValidateLoginField(client, &authpb.ValidateLoginFieldRequest{Uid: uid}); err != nil {
+			t.Fatalf("ValidateLoginField(%s) error = %v", uid, err)
+		}
+	}
+
+	_, err := s.ValidateLoginField(client, &authpb.ValidateLoginFieldRequest{Uid: "third@mail.com"})
+	if code, errCode := errorCode(err); code != codes.ResourceExhausted || errCode != "code_timeout" {
+		t.Errorf("ValidateLoginField() over limit error = %v %q, want ResourceExhausted code_timeout", code, errCode)
+	}
+
+	if _, err := s.ValidateLoginField(other, &authpb.ValidateLoginFieldRequest{Uid: "third@mail.com"}); err != nil {
+		t.Errorf("ValidateLoginField() of other client error = %v", err)
+	}
+
+	disabled, _ := newProtectedServer(t, func(r *rauther.Rauther) { r.Config.UserEnumeration.DisableCheck = true })
+
+	_, err = disabled.ValidateLoginField(other, &authpb.ValidateLoginFieldRequest{Uid: "new@mail.com"})
+	if code, errCode := errorCode(err); code != codes.InvalidArgument || errCode != "req_invalid" {
+		t.Errorf("ValidateLoginField() with disabled check error = %v %q, want InvalidArgument req_invalid", code, errCode)
+	}
+}
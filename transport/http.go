@@ -0,0 +1,192 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// ParamFunc returns path parameter of request. Depends on router, e.g. chi.URLParam
+type ParamFunc func(r *http.Request, name string) string
+
+// HTTPContext is Context over http.ResponseWriter and *http.Request with own handlers chain.
+// It is used by adapters for frameworks based on net/http.
+type HTTPContext struct {
+	Writer    http.ResponseWriter
+	Req       *http.Request
+	ParamFunc ParamFunc
+
+	handlers []HandlerFunc
+	index    int
+
+	keys     map[string]interface{}
+	body     []byte
+	bodyErr  error
+	bodyRead bool
+}
+
+const abortIndex = 1 << 30
+
+// NewHTTPContext returns context for handlers chain
+func NewHTTPContext(w http.ResponseWriter, r *http.Request, paramFunc ParamFunc, handlers []HandlerFunc) *HTTPContext {
+	return &HTTPContext{
+		Writer:    w,
+		Req:       r,
+		ParamFunc: paramFunc,
+		handlers:  handlers,
+		index:     -1,
+	}
+}
+
+// Run executes handlers chain
+func (c *HTTPContext) Run() {
+	c.Next()
+}
+
+func (c *HTTPContext) Request() *http.Request {
+	return c.Req
+}
+
+func (c *HTTPContext) Body() ([]byte, error) {
+	if !c.bodyRead {
+		c.bodyRead = true
+
+		if c.Req.Body != nil {
+			c.body, c.bodyErr = ioutil.ReadAll(c.Req.Body)
+			c.Req.Body.Close()
+			c.Req.Body = ioutil.NopCloser(bytes.NewReader(c.body))
+		}
+	}
+
+	return c.body, c.bodyErr
+}
+
+func (c *HTTPContext) BindJSON(obj interface{}) error {
+	body, err := c.Body()
+	if err != nil {
+		return err
+	}
+
+	return binding.JSON.BindBody(body, obj)
+}
+
+func (c *HTTPContext) Bind(obj interface{}) error {
+	b := binding.Default(c.Req.Method, contentType(c.Req))
+
+	if bb, ok := b.(binding.BindingBody); ok {
+		body, err := c.Body()
+		if err != nil {
+			return err
+		}
+
+		return bb.BindBody(body, obj)
+	}
+
+	// restore body for form binding
+	if _, err := c.Body(); err != nil {
+		return err
+	}
+
+	c.Req.Body = ioutil.NopCloser(bytes.NewReader(c.body))
+
+	return b.Bind(c.Req, obj)
+}
+
+func (c *HTTPContext) Param(name string) string {
+	if c.ParamFunc == nil {
+		return ""
+	}
+
+	return c.ParamFunc(c.Req, name)
+}
+
+func (c *HTTPContext) Get(key string) (value interface{}, exists bool) {
+	value, exists = c.keys[key]
+	return
+}
+
+func (c *HTTPContext) Set(key string, value interface{}) {
+	if c.keys == nil {
+		c.keys = make(map[string]interface{})
+	}
+
+	c.keys[key] = value
+}
+
+// Keys returns all values saved in request scope
+func (c *HTTPContext) Keys() map[string]interface{} {
+	return c.keys
+}
+
+func (c *HTTPContext) Header(key, value string) {
+	c.Writer.Header().Set(key, value)
+}
+
+func (c *HTTPContext) JSON(status int, obj interface{}) {
+	if c.Writer.Header().Get("Content-Type") == "" {
+		c.Writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	c.Writer.WriteHeader(status)
+
+	json.NewEncoder(c.Writer).Encode(obj) // nolint:errcheck
+}
+
+func (c *HTTPContext) Redirect(status int, location string) {
+	http.Redirect(c.Writer, c.Req, location, status)
+}
+
+func (c *HTTPContext) Next() {
+	c.index++
+
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+func (c *HTTPContext) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted returns true if chain was aborted
+func (c *HTTPContext) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+func contentType(r *http.Request) string {
+	ct := r.Header.Get("Content-Type")
+
+	if i := strings.Index(ct, ";"); i >= 0 {
+		ct = ct[:i]
+	}
+
+	return strings.TrimSpace(ct)
+}
+
+// JoinPaths joins route paths with leading slash
+func JoinPaths(base, relative string) string {
+	if relative == "" {
+		return path.Join("/", base)
+	}
+
+	p := path.Join("/", base, relative)
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+
+	return p
+}
+
+// CombineHandlers returns new slice with group handlers followed by route handlers
+func CombineHandlers(group, handlers []HandlerFunc) []HandlerFunc {
+	merged := make([]HandlerFunc, 0, len(group)+len(handlers))
+	merged = append(merged, group...)
+
+	return append(merged, handlers...)
+}
@@ -0,0 +1,148 @@
+// Package httptransport adapts net/http (http.ServeMux and compatible routers) to rauther transport
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/rosberry/rauther/transport"
+)
+
+type (
+	// Mux is http.ServeMux compatible router
+	Mux interface {
+		Handle(pattern string, handler http.Handler)
+	}
+
+	// MethodMux is router with method based routing, e.g. chi.Router.
+	// If mux implements it - routes are registered with Method, otherwise rauther dispatches methods itself.
+	MethodMux interface {
+		Method(method, pattern string, h http.Handler)
+	}
+
+	// Router is transport.Router over net/http mux
+	Router struct {
+		mux       Mux
+		prefix    string
+		handlers  []transport.HandlerFunc
+		paramFunc transport.ParamFunc
+		routes    *routes
+	}
+
+	// Option configures Router
+	Option func(r *Router)
+
+	// routes dispatches methods for muxes without method routing
+	routes struct {
+		mu       sync.Mutex
+		patterns map[string]map[string]http.Handler
+	}
+
+	contextKey struct{}
+)
+
+// WithParamFunc sets function for reading path parameters, e.g. chi.URLParam
+func WithParamFunc(f transport.ParamFunc) Option {
+	return func(r *Router) {
+		r.paramFunc = f
+	}
+}
+
+// New returns transport router for net/http mux
+func New(mux Mux, opts ...Option) *Router {
+	r := &Router{
+		mux: mux,
+		routes: &routes{
+			patterns: make(map[string]map[string]http.Handler),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func (r *Router) Handle(method, path string, handlers ...transport.HandlerFunc) {
+	pattern := transport.JoinPaths(r.prefix, path)
+	h := r.handler(transport.CombineHandlers(r.handlers, handlers))
+
+	if mm, ok := r.mux.(MethodMux); ok {
+		mm.Method(method, pattern, h)
+		return
+	}
+
+	r.routes.add(r.mux, method, pattern, h)
+}
+
+func (r *Router) Group(path string, handlers ...transport.HandlerFunc) transport.Router {
+	return &Router{
+		mux:       r.mux,
+		prefix:    transport.JoinPaths(r.prefix, path),
+		handlers:  transport.CombineHandlers(r.handlers, handlers),
+		paramFunc: r.paramFunc,
+		routes:    r.routes,
+	}
+}
+
+func (r *Router) handler(handlers []transport.HandlerFunc) http.Handler {
+	paramFunc := r.paramFunc
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		transport.NewHTTPContext(w, req, paramFunc, handlers).Run()
+	})
+}
+
+func (rs *routes) add(mux Mux, method, pattern string, h http.Handler) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	methods, ok := rs.patterns[pattern]
+	if !ok {
+		methods = make(map[string]http.Handler)
+		rs.patterns[pattern] = methods
+
+		mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			rs.mu.Lock()
+			h, ok := methods[req.Method]
+			rs.mu.Unlock()
+
+			if !ok {
+				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+				return
+			}
+
+			h.ServeHTTP(w, req)
+		}))
+	}
+
+	methods[method] = h
+}
+
+// Middleware converts rauther middlewares (e.g. Rauther.AuthMiddlewareFunc) to net/http middleware.
+// Values set by middlewares are available in next handler with Value.
+func Middleware(paramFunc transport.ParamFunc, handlers ...transport.HandlerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			final := func(c transport.Context) {
+				hc := c.(*transport.HTTPContext)
+				ctx := context.WithValue(hc.Req.Context(), contextKey{}, hc.Keys())
+
+				next.ServeHTTP(w, hc.Req.WithContext(ctx))
+			}
+
+			chain := transport.CombineHandlers(handlers, []transport.HandlerFunc{final})
+			transport.NewHTTPContext(w, req, paramFunc, chain).Run()
+		})
+	}
+}
+
+// Value returns value set by rauther middleware, e.g. user or session
+func Value(r *http.Request, key string) (value interface{}, exists bool) {
+	keys, _ := r.Context().Value(contextKey{}).(map[string]interface{})
+	value, exists = keys[key]
+
+	return
+}
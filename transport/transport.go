@@ -0,0 +1,58 @@
+// Package transport defines request/response abstraction used by rauther handlers,
+// so they can be served by gin, net/http, chi, echo or any other HTTP framework.
+package transport
+
+import "net/http"
+
+type (
+	// Context of one request. Adapters implement it for HTTP frameworks.
+	Context interface {
+		// Request returns original HTTP request
+		Request() *http.Request
+
+		// Body returns raw request body. Body is read once and cached, so it can be called several times
+		Body() ([]byte, error)
+
+		// BindJSON decodes JSON body to obj and validates it by `binding` tags. Can be called several times
+		BindJSON(obj interface{}) error
+
+		// Bind decodes body to obj according to Content-Type (JSON, form, etc) and validates it
+		Bind(obj interface{}) error
+
+		// Param returns value of path parameter
+		Param(name string) string
+
+		// Get returns value saved in request scope
+		Get(key string) (value interface{}, exists bool)
+
+		// Set saves value in request scope
+		Set(key string, value interface{})
+
+		// Header sets header of response. Content-Type set before JSON is not replaced
+		Header(key, value string)
+
+		// JSON writes response with status and JSON encoded obj
+		JSON(status int, obj interface{})
+
+		// Redirect writes redirect response with status (3xx) to location
+		Redirect(status int, location string)
+
+		// Next executes pending handlers in chain. Used in middlewares
+		Next()
+
+		// Abort prevents pending handlers from being called
+		Abort()
+	}
+
+	// HandlerFunc is rauther handler or middleware
+	HandlerFunc func(c Context)
+
+	// Router registers rauther handlers in HTTP framework
+	Router interface {
+		// Handle registers handlers chain for method and path
+		Handle(method, path string, handlers ...HandlerFunc)
+
+		// Group returns router with path prefix and middlewares executed before handlers of group
+		Group(path string, handlers ...HandlerFunc) Router
+	}
+)
@@ -0,0 +1,183 @@
+// Package uid normalizes and validates UIDs (email, phone, username) of auth methods before users are loaded or saved.
+package uid
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// ErrEmpty is returned if UID is empty after normalization
+	ErrEmpty = errors.New("uid is empty")
+	// ErrInvalidEmail is returned by Email for invalid address
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrInvalidPhone is returned by Phone for number not in E.164 format
+	ErrInvalidPhone = errors.New("invalid phone number")
+	// ErrInvalidUsername is returned by Username for name not matching pattern
+	ErrInvalidUsername = errors.New("invalid username")
+	// ErrDeniedDomain is returned by DenyDomains for address of denied domain
+	ErrDeniedDomain = errors.New("email domain is not allowed")
+)
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+type (
+	// Rule normalizes UID or returns error if UID is invalid
+	Rule func(uid string) (string, error)
+
+	// Pipeline applies rules in order. Empty pipeline does not change UID
+	Pipeline []Rule
+)
+
+// Presets of common UIDs
+var (
+	// EmailPipeline trims spaces, applies NFKC, lowercases and validates address
+	EmailPipeline = Pipeline{TrimSpace, NFKC, Lowercase, Email}
+	// PhonePipeline removes formatting and validates E.164 number
+	PhonePipeline = Pipeline{NFKC, Phone}
+)
+
+// Apply returns normalized UID or error of first failed rule
+func (p Pipeline) Apply(uid string) (string, error) {
+	if len(p) == 0 {
+		return uid, nil
+	}
+
+	var err error
+
+	for _, rule := range p {
+		if uid, err = rule(uid); err != nil {
+			return "", err
+		}
+	}
+
+	if uid == "" {
+		return "", ErrEmpty
+	}
+
+	return uid, nil
+}
+
+// TrimSpace removes leading and trailing spaces
+func TrimSpace(uid string) (string, error) {
+	return strings.TrimSpace(uid), nil
+}
+
+// Lowercase converts UID to lower case
+func Lowercase(uid string) (string, error) {
+	return strings.ToLower(uid), nil
+}
+
+// NFKC converts UID to Unicode normalization form KC: compatible characters (full-width letters, ligatures) are
+// replaced by canonical ones, so visually equal UIDs are equal
+func NFKC(uid string) (string, error) {
+	return norm.NFKC.String(uid), nil
+}
+
+// Email checks that UID is bare email address ("user@example.com", not "User <user@example.com>")
+func Email(uid string) (string, error) {
+	addr, err := mail.ParseAddress(uid)
+	if err != nil || addr.Address != uid || addr.Name != "" {
+		return "", ErrInvalidEmail
+	}
+
+	return uid, nil
+}
+
+// Phone removes spaces, dashes, dots and parentheses and checks E.164 format, e.g. "+1 (555) 010-0000" -> "+15550100000"
+func Phone(uid string) (string, error) {
+	phone := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+
+		return r
+	}, uid)
+
+	if !e164.MatchString(phone) {
+		return "", ErrInvalidPhone
+	}
+
+	return phone, nil
+}
+
+// Username returns rule that checks UID by pattern, e.g. `^[a-z0-9_]{3,32}$`
+func Username(pattern *regexp.Regexp) Rule {
+	return func(uid string) (string, error) {
+		if !pattern.MatchString(uid) {
+			return "", ErrInvalidUsername
+		}
+
+		return uid, nil
+	}
+}
+
+// DenyDomains returns rule that rejects email addresses of domains and their subdomains, e.g. disposable email services.
+// Domains are compared in lower case
+func DenyDomains(domains []string) Rule {
+	denied := make(map[string]bool, len(domains))
+
+	for _, d := range domains {
+		denied[strings.ToLower(strings.TrimSpace(d))] = true
+	}
+
+	return func(uid string) (string, error) {
+		at := strings.LastIndexByte(uid, '@')
+		if at < 0 {
+			return uid, nil
+		}
+
+		domain := strings.ToLower(uid[at+1:])
+
+		for domain != "" {
+			if denied[domain] {
+				return "", ErrDeniedDomain
+			}
+
+			dot := strings.IndexByte(domain, '.')
+			if dot < 0 {
+				break
+			}
+
+			domain = domain[dot+1:]
+		}
+
+		return uid, nil
+	}
+}
+
+// DenyDomainsFile returns DenyDomains rule for file with one domain per line. Empty lines and lines starting with # are
+// skipped
+func DenyDomainsFile(path string) (Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open denied domains: %w", err)
+	}
+	defer f.Close()
+
+	var domains []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		domains = append(domains, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read denied domains: %w", err)
+	}
+
+	return DenyDomains(domains), nil
+}
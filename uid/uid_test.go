@@ -0,0 +1,124 @@
+package uid
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"testing"
+)
+
+func TestEmailPipeline(t *testing.T) {
+	tests := []struct {
+		uid     string
+		want    string
+		wantErr error
+	}{
+		{uid: "user@example.com", want: "user@example.com"},
+		{uid: "  User@Example.COM ", want: "user@example.com"},
+		{uid: "ｕｓｅｒ@example.com", want: "user@example.com"}, // full-width letters
+		{uid: "", wantErr: ErrInvalidEmail},
+		{uid: "user", wantErr: ErrInvalidEmail},
+		{uid: "User <user@example.com>", wantErr: ErrInvalidEmail},
+	}
+
+	for _, tt := range tests {
+		got, err := EmailPipeline.Apply(tt.uid)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("EmailPipeline.Apply(%q) = %q, %v, want %q, %v", tt.uid, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPhonePipeline(t *testing.T) {
+	tests := []struct {
+		uid     string
+		want    string
+		wantErr error
+	}{
+		{uid: "+15550100000", want: "+15550100000"},
+		{uid: "+1 (555) 010-0000", want: "+15550100000"},
+		{uid: "+1.555.010.0000", want: "+15550100000"},
+		{uid: "＋15550100000", want: "+15550100000"}, // full-width plus
+		{uid: "15550100000", wantErr: ErrInvalidPhone},
+		{uid: "+0123", wantErr: ErrInvalidPhone},
+		{uid: "+1555010000012345", wantErr: ErrInvalidPhone},
+	}
+
+	for _, tt := range tests {
+		got, err := PhonePipeline.Apply(tt.uid)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("PhonePipeline.Apply(%q) = %q, %v, want %q, %v", tt.uid, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPipelineApply(t *testing.T) {
+	if got, err := (Pipeline{}).Apply(" Any "); err != nil || got != " Any " {
+		t.Errorf("empty pipeline: Apply() = %q, %v, want UID as is", got, err)
+	}
+
+	if _, err := (Pipeline{TrimSpace}).Apply("   "); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Apply() error = %v, want ErrEmpty", err)
+	}
+
+	username := Pipeline{TrimSpace, Lowercase, Username(regexp.MustCompile(`^[a-z0-9_]{3,32}$`))}
+
+	if got, err := username.Apply(" John_Doe "); err != nil || got != "john_doe" {
+		t.Errorf("username: Apply() = %q, %v, want john_doe", got, err)
+	}
+
+	if _, err := username.Apply("jo"); !errors.Is(err, ErrInvalidUsername) {
+		t.Errorf("username: Apply() error = %v, want ErrInvalidUsername", err)
+	}
+}
+
+func TestDenyDomains(t *testing.T) {
+	rule := DenyDomains([]string{" Mailinator.com ", "tempmail.org"})
+
+	tests := map[string]error{
+		"user@mailinator.com":     ErrDeniedDomain,
+		"user@MAILINATOR.COM":     ErrDeniedDomain,
+		"user@eu.tempmail.org":    ErrDeniedDomain,
+		"user@example.com":        nil,
+		"user@notmailinator.com":  nil,
+		"user@mailinator.com.net": nil,
+		"username":                nil,
+	}
+
+	for email, wantErr := range tests {
+		if _, err := rule(email); !errors.Is(err, wantErr) {
+			t.Errorf("DenyDomains(%q) error = %v, want %v", email, err, wantErr)
+		}
+	}
+}
+
+func TestDenyDomainsFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "denied")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString("# disposable\n\nmailinator.com\n  tempmail.org  \n"); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Close()
+
+	rule, err := DenyDomainsFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, email := range []string{"user@mailinator.com", "user@tempmail.org"} {
+		if _, err := rule(email); !errors.Is(err, ErrDeniedDomain) {
+			t.Errorf("rule(%q) error = %v, want ErrDeniedDomain", email, err)
+		}
+	}
+
+	if _, err := DenyDomainsFile(f.Name() + ".missing"); err == nil {
+		t.Error("DenyDomainsFile() of missing file succeeded")
+	}
+}
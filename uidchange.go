@@ -0,0 +1,275 @@
+package rauther
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/rosberry/rauther/audit"
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/logger"
+	"github.com/rosberry/rauther/metrics"
+	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/transport"
+	"github.com/rosberry/rauther/user"
+)
+
+type (
+	changeUIDRequest struct {
+		UID string `json:"uid" binding:"required"`
+	}
+
+	confirmUIDChangeRequest struct {
+		Code string `json:"code" binding:"required"`
+	}
+)
+
+func (r *Rauther) changeUIDHandler(c transport.Context) {
+	at, ok := r.findUIDChangeMethod(c)
+	if !ok {
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		return
+	}
+
+	var request changeUIDRequest
+	if err := c.BindJSON(&request); err != nil {
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		return
+	}
+
+	sessionInfo, success := r.checkSession(c)
+	if !success {
+		return
+	}
+
+	uid, err := r.requestUIDChange(requestContext(c), at, sessionInfo.User, request.UID)
+	if err != nil {
+		r.flowErrorResponse(c, err)
+		return
+	}
+
+	r.respond(c, Response{
+		Route:   RouteChangeUID,
+		AuthKey: at.Key,
+		UID:     uid,
+		User:    sessionInfo.User,
+		Session: sessionInfo.Session,
+	})
+}
+
+func (r *Rauther) confirmUIDChangeHandler(c transport.Context) {
+	at, ok := r.findUIDChangeMethod(c)
+	if !ok {
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		return
+	}
+
+	var request confirmUIDChangeRequest
+	if err := c.BindJSON(&request); err != nil {
+		r.errorResponse(c, http.StatusBadRequest, common.ErrInvalidRequest)
+		return
+	}
+
+	sessionInfo, success := r.checkSession(c)
+	if !success {
+		return
+	}
+
+	uid, err := r.confirmUIDChange(requestContext(c), at, sessionInfo.User, request.Code)
+	if err != nil {
+		r.flowErrorResponse(c, err)
+		return
+	}
+
+	r.respond(c, Response{
+		Route:   RouteConfirmUIDChange,
+		AuthKey: at.Key,
+		UID:     uid,
+		User:    sessionInfo.User,
+		Session: sessionInfo.Session,
+	})
+}
+
+// findUIDChangeMethod selects password auth method of request or OTP auth method if password one is not found
+func (r *Rauther) findUIDChangeMethod(c transport.Context) (*authtype.AuthMethod, bool) {
+	at := r.methods.Select(c, authtype.Password)
+	if at == nil {
+		at = r.methods.Select(c, authtype.OTP)
+	}
+
+	if at == nil {
+		r.logRequest(c, logger.DebugLevel, "not found password or OTP auth method")
+		return nil, false
+	}
+
+	return at, true
+}
+
+// requestUIDChange sends code to new UID and notice to current UID of user, returns normalized new UID.
+// UID is changed by confirmUIDChange
+func (r *Rauther) requestUIDChange(ctx context.Context, at *authtype.AuthMethod, u user.User, uid string) (_ string, err error) { // nolint:lll
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowChangeUID, at)
+	defer endFlow(&err)
+
+	if uid, err = r.normalizeUID(at, uid); err != nil {
+		return "", err
+	}
+
+	rec := r.startAudit(ctx, audit.UIDChangeRequest, at, uid)
+	defer rec.end(&err)
+
+	if u == nil || uid == "" {
+		return "", newError(http.StatusBadRequest, common.ErrInvalidRequest)
+	}
+
+	rec.setUser(u)
+
+	currentUID := u.(user.AuthableUser).GetUID(at.Key)
+	if currentUID == "" {
+		return "", newError(http.StatusBadRequest, common.ErrInvalidRequest)
+	}
+
+	if currentUID == uid {
+		return "", newError(http.StatusBadRequest, common.ErrUIDNotChanged)
+	}
+
+	if err := r.checkUIDAvailable(ctx, at, u, uid); err != nil {
+		return "", err
+	}
+
+	// check resend timeout
+	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
+		curTime := time.Now()
+		if err := r.checkCodeTimeout(u, curTime, at); err != nil {
+			return "", err
+		}
+
+		u.(user.CodeSentTimeUser).SetCodeSentTime(at.Key, &curTime)
+	}
+
+	code := r.generateCode(at, sender.UIDChangeEvent)
+
+	u.(user.UIDChangeableUser).SetPendingUID(at.Key, uid)
+	u.(user.UIDChangeableUser).SetUIDChangeCode(at.Key, code)
+
+	// send before save: previous request stays valid if sending failed
+	if err := r.sendCode(ctx, at.Sender, sender.UIDChangeEvent, uid, code); err != nil {
+		return "", sendError(err)
+	}
+
+	if err := r.users(ctx).Save(u); err != nil {
+		return "", wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
+	}
+
+	// owner of current UID is warned even if change is not confirmed. Errors are logged by sendCode only
+	_ = r.sendCode(ctx, at.Sender, sender.UIDChangeNoticeEvent, currentUID, uid) // nolint:errcheck
+
+	r.publish(ctx, events.UIDChangeRequested{User: u, AuthKey: at.Key, UID: uid})
+
+	return uid, nil
+}
+
+// confirmUIDChange sets pending UID of user if code is valid and UID is not taken yet. Returns new UID
+func (r *Rauther) confirmUIDChange(ctx context.Context, at *authtype.AuthMethod, u user.User, code string) (uid string, err error) { // nolint:lll
+	ctx, endFlow := r.startFlow(ctx, metrics.FlowConfirmUIDChange, at)
+	defer endFlow(&err)
+
+	if u == nil {
+		return "", newError(http.StatusBadRequest, common.ErrInvalidRequest)
+	}
+
+	changeable := u.(user.UIDChangeableUser)
+	uid = changeable.GetPendingUID(at.Key)
+	previousUID := changeable.GetUID(at.Key)
+
+	rec := r.startAudit(ctx, audit.UIDChange, at, uid)
+	defer rec.end(&err)
+
+	rec.setUser(u)
+
+	if uid == "" || !at.VerifyCode(changeable.GetUIDChangeCode(at.Key), code) {
+		return "", newError(http.StatusBadRequest, common.ErrInvalidCode)
+	}
+
+	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
+		if err := r.checkCodeExpired(u, at, r.codeLifeTime(at)); err != nil {
+			return "", err
+		}
+	}
+
+	// UID could be registered by other user after request
+	if err := r.checkUIDAvailable(ctx, at, u, uid); err != nil {
+		return "", err
+	}
+
+	r.setChangedUID(at, u, uid)
+
+	if err := r.users(ctx).Save(u); err != nil {
+		return "", wrapError(http.StatusInternalServerError, common.ErrUserSave, err)
+	}
+
+	r.publish(ctx, events.UIDChanged{User: u, AuthKey: at.Key, UID: uid, PreviousUID: previousUID})
+
+	return uid, nil
+}
+
+// checkUIDAvailable returns ErrUserExist if uid of auth method belongs to other user
+func (r *Rauther) checkUIDAvailable(ctx context.Context, at *authtype.AuthMethod, u user.User, uid string) error {
+	owner, err := r.loadByUID(ctx, at.Key, uid)
+	if err != nil {
+		return r.loadError(err)
+	}
+
+	if owner != nil && owner.GetID() != u.GetID() {
+		return newError(http.StatusBadRequest, common.ErrUserExist)
+	}
+
+	return nil
+}
+
+// setChangedUID sets new UID keeping password of identity. New UID is confirmed by code, so identity is confirmed too
+func (r *Rauther) setChangedUID(at *authtype.AuthMethod, u user.User, uid string) {
+	var password string
+
+	passwordUser, hasPassword := u.(user.PasswordAuthableUser)
+	if hasPassword {
+		password = passwordUser.GetPassword(at.Key)
+	}
+
+	u.(user.AuthableUser).SetUID(at.Key, uid)
+
+	if hasPassword && password != "" {
+		passwordUser.SetPassword(at.Key, password)
+	}
+
+	u.(user.UIDChangeableUser).SetPendingUID(at.Key, "")
+	u.(user.UIDChangeableUser).SetUIDChangeCode(at.Key, "")
+
+	if r.checker.CodeSentTime && r.Modules.CodeSentTimeUser {
+		u.(user.CodeSentTimeUser).SetCodeSentTime(at.Key, nil)
+	}
+
+	// codes sent to previous UID are not valid anymore
+	if recoverable, ok := u.(user.RecoverableUser); ok {
+		recoverable.SetRecoveryCode(at.Key, "")
+	}
+
+	switch u := u.(type) {
+	case user.ConfirmableUser:
+		u.SetConfirmCode(at.Key, "")
+		u.SetConfirmed(at.Key, true)
+	case user.OTPAuth:
+		u.SetConfirmed(at.Key, true)
+	}
+}
+
+// codeLifeTime returns lifetime of codes of auth method type
+func (r *Rauther) codeLifeTime(at *authtype.AuthMethod) time.Duration {
+	if at.Type == authtype.OTP {
+		return r.Config.OTP.CodeLifeTime
+	}
+
+	return r.Config.Password.CodeLifeTime
+}
@@ -0,0 +1,206 @@
+package rauther_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/rosberry/rauther"
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/deps"
+	"github.com/rosberry/rauther/example/basic/models"
+	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/sender/sendertest"
+	"github.com/rosberry/rauther/transport/httptransport"
+)
+
+type otpRequest struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+}
+
+func (r *otpRequest) GetUID() string        { return r.Phone }
+func (r *otpRequest) GetPassword() string   { return r.Code }
+func (r *otpRequest) GetConfirmMerge() bool { return false }
+
+func TestChangeUIDOfOTPIdentity(t *testing.T) {
+	users := &models.UserStorer{Users: map[uint]*models.User{}}
+	sessions := &models.Sessioner{Sessions: map[string]*models.Session{}}
+	mux := http.NewServeMux()
+	rec := sendertest.New()
+
+	r := rauther.New(deps.NewWithRouter(httptransport.New(mux), deps.Storage{SessionStorer: sessions, UserStorer: users}))
+	r.AddAuthMethod(authtype.AuthMethod{Key: "email", Sender: rec})
+	r.AddAuthMethod(authtype.AuthMethod{
+		Key:           "sms",
+		Type:          authtype.OTP,
+		Sender:        rec,
+		SignUpRequest: &otpRequest{},
+		SignInRequest: &otpRequest{},
+	})
+	r.Config.OTP.ResendDelay = 0
+
+	if err := r.InitHandlers(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{t: t, mux: mux, users: users}
+
+	signIn := func(phone string) (token string) {
+		token = s.auth(phone)
+		s.do(token, "/otp/code", `{"type":"sms","phone":"`+phone+`"}`)
+
+		code, ok := rec.LastCode(phone, sender.ConfirmationEvent)
+		if !ok {
+			t.Fatalf("OTP code is not sent to %s", phone)
+		}
+
+		s.do(token, "/otp/auth", `{"type":"sms","phone":"`+phone+`","code":"`+code+`"}`)
+
+		return token
+	}
+
+	token := signIn("+100")
+
+	s.do(token, "/auth/uid/change", `{"type":"sms","uid":"+200"}`)
+
+	if notice, _ := rec.LastCode("+100", sender.UIDChangeNoticeEvent); notice != "+200" {
+		t.Errorf("notice to current UID = %q, want +200", notice)
+	}
+
+	code, ok := rec.LastCode("+200", sender.UIDChangeEvent)
+	if !ok {
+		t.Fatal("UID change code is not sent to new UID")
+	}
+
+	s.do(token, "/auth/uid/confirm", `{"type":"sms","code":"`+code+`"}`)
+
+	if len(users.Users) != 1 {
+		t.Fatalf("got %d users, want 1", len(users.Users))
+	}
+
+	var userID uint
+
+	for id, u := range users.Users {
+		userID = id
+
+		if got := u.GetUID("sms"); got != "+200" {
+			t.Errorf("UID = %q, want +200", got)
+		}
+	}
+
+	// new UID signs in to the same user
+	signIn("+200")
+
+	if len(users.Users) != 1 || users.Users[userID] == nil {
+		t.Errorf("sign-in with new UID created other user")
+	}
+}
+
+func TestUIDChangeMethodAndCode(t *testing.T) {
+	users := &models.UserStorer{Users: map[uint]*models.User{}}
+	sessions := &models.Sessioner{Sessions: map[string]*models.Session{}}
+	mux := http.NewServeMux()
+	rec := sendertest.New()
+
+	// sequential codes: every request gets other code
+	var codes int
+
+	r := rauther.New(deps.NewWithRouter(httptransport.New(mux), deps.Storage{SessionStorer: sessions, UserStorer: users}))
+	r.AddAuthMethod(authtype.AuthMethod{
+		Key:    "email",
+		Sender: rec,
+		CodeGenerator: func(int) string {
+			codes++
+			return strconv.Itoa(100000 + codes)
+		},
+	})
+	r.AddAuthMethod(authtype.AuthMethod{
+		Key:           "sms",
+		Type:          authtype.OTP,
+		Sender:        rec,
+		SignUpRequest: &otpRequest{},
+		SignInRequest: &otpRequest{},
+	})
+	r.Config.Password.ResendDelay = 0
+
+	if err := r.InitHandlers(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{t: t, mux: mux, users: users}
+
+	signUp := func(deviceID, email string) (token string) {
+		token = s.auth(deviceID)
+		s.do(token, "/register", `{"type":"email","email":"`+email+`","password":"password"}`)
+
+		return token
+	}
+
+	requestChange := func(token, uid string) string {
+		// type is not set: password method is selected before OTP one
+		s.do(token, "/auth/uid/change", `{"uid":"`+uid+`"}`)
+
+		code, ok := rec.LastCode(uid, sender.UIDChangeEvent)
+		if !ok {
+			t.Fatalf("UID change code is not sent to %s", uid)
+		}
+
+		return code
+	}
+
+	confirm := func(token, code string) (int, map[string]interface{}) {
+		return s.request(http.MethodPost, token, "/auth/uid/confirm", `{"code":"`+code+`"}`)
+	}
+
+	first := signUp("device1", "first@mail.com")
+	second := signUp("device2", "second@mail.com")
+
+	t.Run("no matching method", func(t *testing.T) {
+		status, resp := s.request(http.MethodPost, first, "/auth/uid/change", `{"type":"unknown","uid":"new@mail.com"}`)
+		if status != http.StatusBadRequest || errorCode(resp) != "req_invalid" {
+			t.Errorf("change: %d %v, want 400 req_invalid", status, resp)
+		}
+
+		status, resp = confirm(first, "100000")
+		if status != http.StatusBadRequest {
+			t.Errorf("confirm without request: %d %v, want 400", status, resp)
+		}
+	})
+
+	t.Run("stale code", func(t *testing.T) {
+		stale := requestChange(first, "stale@mail.com")
+		requestChange(first, "first-new@mail.com")
+
+		if status, resp := confirm(first, stale); status != http.StatusBadRequest || errorCode(resp) != "invalid_code" {
+			t.Errorf("confirm with stale code: %d %v, want 400 invalid_code", status, resp)
+		}
+	})
+
+	t.Run("code of other user", func(t *testing.T) {
+		other := requestChange(second, "second-new@mail.com")
+
+		if status, resp := confirm(first, other); status != http.StatusBadRequest || errorCode(resp) != "invalid_code" {
+			t.Errorf("confirm with code of other user: %d %v, want 400 invalid_code", status, resp)
+		}
+	})
+
+	t.Run("password method", func(t *testing.T) {
+		code, _ := rec.LastCode("first-new@mail.com", sender.UIDChangeEvent)
+		s.do(first, "/auth/uid/confirm", `{"code":"`+code+`"}`)
+
+		for _, u := range users.Users {
+			if u.GetUID("sms") != "" {
+				t.Errorf("OTP identity is changed: %q", u.GetUID("sms"))
+			}
+		}
+
+		if u, _ := users.LoadByUID("email", "first-new@mail.com"); u == nil {
+			t.Error("user is not found by new UID")
+		}
+
+		if u, _ := users.LoadByUID("email", "stale@mail.com"); u != nil {
+			t.Error("user is found by UID of stale request")
+		}
+	})
+}
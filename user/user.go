@@ -61,6 +61,15 @@ type RecoverableUser interface {
 	SetRecoveryCode(authType, code string)
 }
 
+// UIDChangeableUser keeps new UID of auth method until it is confirmed by code sent to new UID
+type UIDChangeableUser interface {
+	AuthableUser
+	GetPendingUID(authType string) (uid string)
+	SetPendingUID(authType, uid string)
+	GetUIDChangeCode(authType string) (code string)
+	SetUIDChangeCode(authType, code string)
+}
+
 // interface for checking the interval during which confirmation codes cannot be sent
 type CodeSentTimeUser interface {
 	AuthableUser
@@ -1,20 +1,22 @@
 package rauther
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"log"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strings"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rosberry/rauther/authtype"
 	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/logger"
 	"github.com/rosberry/rauther/sender"
+	"github.com/rosberry/rauther/transport"
 	"github.com/rosberry/rauther/user"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -39,61 +41,37 @@ func (ce CustomError) Error() string {
 	return ce.Response.Message
 }
 
-func errorResponse(c *gin.Context, status int, err common.ErrTypes) {
-	c.JSON(status, gin.H{
-		"result": false,
-		"error":  common.Errors[err],
-	})
+func (r *Rauther) errorResponse(c transport.Context, status int, err common.ErrTypes) {
+	r.renderError(c, newError(status, err), nil)
 }
 
-func customErrorResponse(c *gin.Context, cErr CustomError) {
-	c.JSON(cErr.Status, gin.H{
-		"result": false,
-		"error":  cErr.Response,
-	})
-}
-
-func getCodeTimeoutResponse(resendTime, curTime time.Time) (response map[string]interface{}, statusCode int) {
-	interval := resendTime.Sub(curTime) / time.Second
-	nextRequestTime := resendTime.Format(time.RFC3339)
-
-	return gin.H{
-		"result": false,
-		"error":  common.Errors[common.ErrRequestCodeTimeout],
-		"info": common.ResendCodeErrInfo{
-			TimeoutSec:      interval,
-			NextRequestTime: nextRequestTime,
-		},
-	}, http.StatusTooManyRequests
+// mergeErrorInfo returns lost auth methods and custom data of merge error
+func mergeErrorInfo(mergeError MergeError) interface{} {
+	return struct {
+		Lost interface{} `json:"lost"`
+		Data interface{} `json:"data,omitempty"`
+	}{
+		Lost: mergeError,
+		Data: mergeError.info,
+	}
 }
 
-func mergeErrorResponse(c *gin.Context, err error) {
-	var mergeError MergeError
-	if errors.As(err, &mergeError) {
-		log.Printf("mergeError list: %v", mergeError.removeAuthMethods)
-
-		c.JSON(http.StatusConflict, gin.H{
-			"result": false,
-			"error":  common.Errors[common.ErrMergeWarning],
-			"info": struct {
-				Lost interface{} `json:"lost"`
-				Data interface{} `json:"data,omitempty"`
-			}{
-				Lost: mergeError,
-				Data: mergeError.info,
-			},
-		})
+func (r *Rauther) hashPassword(ctx context.Context, password string) (string, error) {
+	_, span := r.startSpan(ctx, "rauther.HashPassword")
+	defer span.End()
 
-		return
+	encryptedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", wrapError(http.StatusInternalServerError, common.ErrUnknownError, err)
 	}
 
-	c.JSON(http.StatusBadRequest, gin.H{
-		"result": false,
-		"error":  common.Errors[common.ErrUnknownError],
-	})
+	return string(encryptedPassword), nil
 }
 
-func passwordCompare(requestPassword, hashedPassword string) (ok bool) {
+func (r *Rauther) passwordCompare(ctx context.Context, requestPassword, hashedPassword string) (ok bool) {
+	_, span := r.startSpan(ctx, "rauther.ComparePassword")
+	defer span.End()
+
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(requestPassword))
 	if err == nil {
 		ok = true
@@ -102,14 +80,15 @@ func passwordCompare(requestPassword, hashedPassword string) (ok bool) {
 	return
 }
 
-func parseAuthToken(c *gin.Context) (token string) {
-	if authHeader := c.Request.Header.Get("Authorization"); authHeader != "" {
-		log.Printf("auth header: %s", authHeader)
+func (r *Rauther) parseAuthToken(c transport.Context) (token string) {
+	if authHeader := c.Request().Header.Get("Authorization"); authHeader != "" {
 		if strings.HasPrefix(authHeader, "Bearer ") {
 			if token = authHeader[7:]; len(token) > 0 {
 				return token
 			}
 		}
+
+		r.logRequest(c, logger.DebugLevel, "invalid authorization header", logger.F("authorization", authHeader))
 	}
 
 	return ""
@@ -123,41 +102,80 @@ func generateSessionToken() string {
 	return uuid.NewString()
 }
 
-func sendConfirmCode(s sender.Sender, recipient, code string) error {
-	return sendCode(s, sender.ConfirmationEvent, recipient, code)
+func (r *Rauther) sendConfirmCode(ctx context.Context, s sender.Sender, recipient, code string) error {
+	return r.sendCode(ctx, s, sender.ConfirmationEvent, recipient, code)
 }
 
-func sendRecoveryCode(s sender.Sender, recipient, code string) error {
-	return sendCode(s, sender.PasswordRecoveryEvent, recipient, code)
+func (r *Rauther) sendRecoveryCode(ctx context.Context, s sender.Sender, recipient, code string) error {
+	return r.sendCode(ctx, s, sender.PasswordRecoveryEvent, recipient, code)
 }
 
-func sendCode(s sender.Sender, event sender.Event, recipient, code string) error {
-	log.Printf("%s code for %s: %s", event, recipient, code)
+func (r *Rauther) sendCode(ctx context.Context, s sender.Sender, event sender.Event, recipient, code string) (err error) {
+	if r.Config.LogCodes {
+		// not redacted logger: codes are logged on purpose
+		r.deps.Logger.Log(logger.InfoLevel, "send code",
+			logger.F("event", event.String()), logger.F("recipient", recipient), logger.F("code", code))
+	}
+
+	ctx, span := r.startSpan(ctx, "Sender.Send", attribute.String("rauther.event", event.String()))
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	err = sender.AdaptSender(s).SendContext(ctx, event, recipient, code)
+	r.deps.Metrics.Send(event.String(), err, time.Since(start))
 
-	err := s.Send(event, recipient, code)
 	if err != nil {
-		err = fmt.Errorf("sendRecoveryCode error: %w", err)
+		err = fmt.Errorf("send %s code error: %w", event, err)
+		r.logger.Log(logger.WarnLevel, "send code", logger.F("event", event.String()), logger.Err(err))
 	}
 
 	return err
 }
 
+// requestFields returns additional fields of request if it implements authtype.AuthRequestFieldable
+func requestFields(request interface{}) map[string]interface{} {
+	if fieldableRequest, ok := request.(authtype.AuthRequestFieldable); ok {
+		return fieldableRequest.Fields()
+	}
+
+	return nil
+}
+
+// requestConfirmMerge returns merge confirmation of request if it implements authtype.MergeConfirmRequest
+func requestConfirmMerge(request interface{}) bool {
+	if requestWithMergeConfirm, ok := request.(authtype.MergeConfirmRequest); ok {
+		return requestWithMergeConfirm.GetConfirmMerge()
+	}
+
+	return false
+}
+
 func clone(obj interface{}) interface{} {
 	return reflect.New(reflect.TypeOf(obj).Elem()).Interface()
 }
 
-func (r *Rauther) findAuthMethod(c *gin.Context, expectedType authtype.Type) (am *authtype.AuthMethod, ok bool) {
+func (r *Rauther) findAuthMethod(c transport.Context, expectedType authtype.Type) (am *authtype.AuthMethod, ok bool) {
 	am = r.methods.Select(c, expectedType)
 
-	if am == nil {
-		return
+	if am == nil || am.Type != expectedType {
+		r.logRequest(c, logger.DebugLevel, "not found expected auth method", logger.F("auth_type", expectedType))
+		return nil, false
 	}
 
-	if am.Type != expectedType {
-		return
+	return am, true
+}
+
+// logRequest logs entry with route of request and ID of user from context
+func (r *Rauther) logRequest(c transport.Context, level logger.Level, msg string, fields ...logger.Field) {
+	requestFields := []logger.Field{logger.Route(c.Request().URL.Path)}
+
+	if u, ok := c.Get(r.Config.ContextNames.User); ok {
+		if u, ok := u.(user.User); ok && u != nil {
+			requestFields = append(requestFields, logger.UserID(u.GetID()))
+		}
 	}
 
-	return am, true
+	r.logger.Log(level, msg, append(requestFields, fields...)...)
 }
 
 func calcExpiredAt(t *time.Time, d time.Duration) time.Time {
@@ -170,6 +188,50 @@ func calcExpiredAt(t *time.Time, d time.Duration) time.Time {
 	return tm.Add(d)
 }
 
+// checkCodeTimeout returns ErrRequestCodeTimeout error if resend delay of last code is not passed
+func (r *Rauther) checkCodeTimeout(u user.User, curTime time.Time, authMethod *authtype.AuthMethod) error {
+	if resendTime, ok := r.checkResendTime(u, curTime, authMethod); !ok {
+		return codeTimeoutError(*resendTime, curTime)
+	}
+
+	return nil
+}
+
+// withQuery returns URL with query parameter key set to value
+func withQuery(rawURL, key, value string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// normalizeUID applies UID pipeline of auth method
+func (r *Rauther) normalizeUID(at *authtype.AuthMethod, uid string) (string, error) {
+	normalized, err := at.UIDPipeline.Apply(uid)
+	if err != nil {
+		return "", wrapError(http.StatusBadRequest, common.ErrInvalidUID, err)
+	}
+
+	return normalized, nil
+}
+
+// checkCodeExpired returns ErrCodeExpired error if code lifetime is passed
+func (r *Rauther) checkCodeExpired(u user.User, authMethod *authtype.AuthMethod, lifeTime time.Duration) error {
+	codeSent := u.(user.CodeSentTimeUser).GetCodeSentTime(authMethod.Key)
+
+	if calcExpiredAt(codeSent, lifeTime).Before(time.Now()) {
+		return newError(http.StatusBadRequest, common.ErrCodeExpired)
+	}
+
+	return nil
+}
+
 func (r *Rauther) checkResendTime(u user.User, curTime time.Time, authMethod *authtype.AuthMethod) (resendTime *time.Time, ok bool) {
 	lastCodeSentTime := u.(user.CodeSentTimeUser).GetCodeSentTime(authMethod.Key)
 
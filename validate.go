@@ -0,0 +1,327 @@
+package rauther
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rosberry/rauther/authtype"
+	"github.com/rosberry/rauther/common"
+	"github.com/rosberry/rauther/storage"
+	"github.com/rosberry/rauther/user"
+)
+
+// Errors of Validate without own type in common.Errors
+var (
+	ErrGuestUserNotImplement     = errors.New("please implement GuestUser interface for use guest user")
+	ErrRemovableUserNotImplement = errors.New("user storer must implement RemovableUserStorer interface for guest user and linking") // nolint:lll
+	ErrConfirmableRequired       = errors.New("please enable ConfirmableUser module for use linking")
+	ErrTempUserNotImplement      = errors.New("please implement TempUser interface for use linking")
+	ErrMergeUserNotImplement     = errors.New("please implement MergeUser interface for use merging")
+	ErrUIDChangeableNotImplement = errors.New("please implement UIDChangeableUser interface for use UID change")
+	ErrConfirmLinkSecretRequired = errors.New("please set Config.ConfirmLink.Secret for use confirmation by link")
+	ErrConfirmLinkURLRequired    = errors.New("please set Config.ConfirmLink.URL for use confirmation by link")
+	ErrResetLinkSecretRequired   = errors.New("please set Config.ResetLink.Secret for use password reset by link")
+	ErrResetLinkURLRequired      = errors.New("please set Config.ResetLink.URL for use password reset by link")
+	ErrUserSessionsNotImplement  = errors.New("session storer must implement UserSessionsFinder interface for password reset by link") // nolint:lll
+)
+
+type (
+	// ConfigError is problem of rauther configuration
+	ConfigError struct {
+		// Module is name of module or part of configuration, e.g. "LinkAccount", "AuthMethods", "Deps"
+		Module string
+		// Key of auth method if problem is related to it
+		Key string
+		Err error
+	}
+
+	// ValidationError contains all problems of configuration found by Validate
+	ValidationError struct {
+		Errors []ConfigError
+	}
+)
+
+func (e ConfigError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("%s '%s': %v", e.Module, e.Key, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %v", e.Module, e.Err)
+}
+
+func (e ConfigError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ValidationError) Error() string {
+	sb := &strings.Builder{}
+	sb.WriteString("invalid rauther configuration:")
+
+	for _, err := range e.Errors {
+		sb.WriteString("\n\t- ")
+		sb.WriteString(err.Error())
+	}
+
+	return sb.String()
+}
+
+// Is reports whether any problem matches target, e.g.
+//
+//	errors.Is(err, common.Errors[common.ErrSenderRequired])
+func (e *ValidationError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *ValidationError) add(module, key string, err error) {
+	e.Errors = append(e.Errors, ConfigError{Module: module, Key: key, Err: err})
+}
+
+// Validate checks dependencies, auth methods and implemented interfaces of enabled modules.
+// It returns *ValidationError with all found problems or nil. Init calls it before applying defaults
+func (r *Rauther) Validate() error {
+	r.initAuthMethods()
+
+	errs := &ValidationError{}
+	errs.Errors = append(errs.Errors, r.depsErrors...)
+
+	for _, err := range r.methods.Errors() {
+		errs.add("AuthMethods", err.Key, err.Err)
+	}
+
+	var u user.User
+	if r.deps.Storage.UserStorer != nil {
+		u = r.deps.Storage.UserStorer.Create()
+	}
+
+	r.validateAuthMethods(errs, u)
+
+	if err := common.ValidateErrors(); err != nil {
+		errs.add("Errors", "", err)
+	}
+
+	if r.Modules.Session && r.Modules.AuthableUser {
+		r.validateAuthable(errs)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// initAuthMethods adds default email auth method if auth methods are not defined
+func (r *Rauther) initAuthMethods() {
+	if r.methods == nil {
+		r.methods = authtype.New(nil)
+	}
+
+	if r.emptyAuthMethods() {
+		r.AddAuthMethod(authtype.AuthMethod{
+			Key: "email",
+		})
+	}
+}
+
+// validateAuthMethods checks `auth` tags of user model for request fields and merge support of requests
+func (r *Rauther) validateAuthMethods(errs *ValidationError, u user.User) {
+	ok, badFields := r.methods.CheckFieldsDefine(u)
+	if !ok {
+		for _, k := range sortedKeys(badFields) {
+			v := badFields[k]
+			// key of bad fields is "sign-up <key>" or "sign-in <key>"
+			parts := strings.SplitN(k, " ", 2) // nolint:gomnd
+			errs.add("AuthMethods", parts[len(parts)-1],
+				fmt.Errorf("%s request fields %v not found in user model, check `auth` tags", parts[0], v))
+		}
+	}
+
+	if r.Modules.MergeAccount {
+		ok, failedMethods := r.methods.CheckMergeModuleSupport()
+		if !ok {
+			for _, atKey := range sortedKeys(failedMethods) {
+				errs.add("MergeAccount", atKey,
+					fmt.Errorf("request %v must implement MergeConfirmRequest", failedMethods[atKey]))
+			}
+		}
+	}
+}
+
+// validateAuthable checks interfaces of user and senders for modules included by includeAuthable
+func (r *Rauther) validateAuthable(errs *ValidationError) {
+	if !r.checker.Authable {
+		errs.add("AuthableUser", "", common.Errors[common.ErrAuthableUserNotImplement])
+	}
+
+	r.validateLink(errs)
+	r.validateRemovableUser(errs)
+
+	sendCodes := false
+
+	if r.Modules.PasswordAuthableUser && r.methods.ExistingTypes[authtype.Password] {
+		if !r.checker.PasswordAuthable {
+			errs.add("PasswordAuthableUser", "", common.Errors[common.ErrPasswordAuthableUserNotImplement])
+		}
+
+		if r.Modules.ConfirmableUser {
+			sendCodes = true
+
+			if !r.checker.Confirmable {
+				errs.add("ConfirmableUser", "", common.Errors[common.ErrConfirmableUserNotImplement])
+			}
+
+			r.validateConfirmLink(errs)
+		}
+
+		if r.Modules.RecoverableUser {
+			sendCodes = true
+
+			if !r.checker.Recoverable {
+				errs.add("RecoverableUser", "", common.Errors[common.ErrRecoverableUserNotImplement])
+			}
+
+			r.validateResetLink(errs)
+		}
+	}
+
+	if r.Modules.OTP && r.methods.ExistingTypes[authtype.OTP] {
+		sendCodes = true
+
+		if !r.checker.OTPAuth {
+			errs.add("OTP", "", common.Errors[common.ErrOTPNotImplement])
+		}
+	}
+
+	if r.Modules.ChangeUID && (r.methods.ExistingTypes[authtype.Password] || r.methods.ExistingTypes[authtype.OTP]) {
+		sendCodes = true
+
+		if !r.checker.UIDChangeable {
+			errs.add("ChangeUID", "", ErrUIDChangeableNotImplement)
+		}
+	}
+
+	if sendCodes {
+		r.validateSender(errs)
+	}
+}
+
+func (r *Rauther) validateConfirmLink(errs *ValidationError) {
+	if !r.confirmLinkEnabled() {
+		return
+	}
+
+	if r.Config.ConfirmLink.Secret == "" {
+		errs.add("ConfirmLink", "", ErrConfirmLinkSecretRequired)
+	}
+
+	if r.Config.ConfirmLink.URL == "" {
+		errs.add("ConfirmLink", "", ErrConfirmLinkURLRequired)
+	}
+}
+
+func (r *Rauther) validateResetLink(errs *ValidationError) {
+	if !r.resetLinkEnabled() {
+		return
+	}
+
+	if r.Config.ResetLink.Secret == "" {
+		errs.add("ResetLink", "", ErrResetLinkSecretRequired)
+	}
+
+	if r.Config.ResetLink.URL == "" {
+		errs.add("ResetLink", "", ErrResetLinkURLRequired)
+	}
+
+	if _, ok := r.deps.SessionStorer.(storage.UserSessionsFinder); !ok {
+		errs.add("ResetLink", "", ErrUserSessionsNotImplement)
+	}
+}
+
+func (r *Rauther) validateRemovableUser(errs *ValidationError) {
+	if r.Modules.GuestUser && !r.checker.Guest {
+		errs.add("GuestUser", "", ErrGuestUserNotImplement)
+	}
+
+	if (r.Modules.GuestUser || r.Modules.LinkAccount) && r.deps.Storage.UserRemover == nil {
+		if _, ok := r.deps.Storage.UserStorer.(storage.RemovableUserStorer); !ok {
+			errs.add("GuestUser", "", ErrRemovableUserNotImplement)
+		}
+	}
+}
+
+func (r *Rauther) validateLink(errs *ValidationError) {
+	if !r.Modules.LinkAccount {
+		return
+	}
+
+	if !r.Modules.ConfirmableUser {
+		errs.add("LinkAccount", "", ErrConfirmableRequired)
+	}
+
+	if !r.checker.LinkAccount {
+		errs.add("LinkAccount", "", ErrTempUserNotImplement)
+	}
+
+	if r.Modules.MergeAccount && !r.checker.MergeAccount {
+		errs.add("MergeAccount", "", ErrMergeUserNotImplement)
+	}
+}
+
+// validateSender checks that every auth method has sender or default sender is defined
+func (r *Rauther) validateSender(errs *ValidationError) {
+	if r.defaultSender != nil {
+		return
+	}
+
+	for _, key := range sortedKeys(r.methods.List) {
+		// social auth methods do not send codes
+		if at := r.methods.List[key]; at.Sender == nil && at.Type != authtype.Social {
+			errs.add("Sender", key, common.Errors[common.ErrSenderRequired])
+		}
+	}
+}
+
+// applyDefaults sets default sender to auth methods without sender and user remover
+func (r *Rauther) applyDefaults() {
+	for key, at := range r.methods.List {
+		if at.Sender == nil && r.defaultSender != nil {
+			at.Sender = r.defaultSender
+			r.methods.List[key] = at
+		}
+	}
+
+	if r.deps.Storage.UserRemover == nil {
+		if userRemover, ok := r.deps.Storage.UserStorer.(storage.RemovableUserStorer); ok {
+			r.deps.Storage.UserRemover = userRemover
+		}
+	}
+
+	maxPendingRecoveries := r.Config.UserEnumeration.MaxPendingRecoveries
+	if maxPendingRecoveries <= 0 {
+		maxPendingRecoveries = defaultMaxPendingRecoveries
+	}
+
+	r.pendingRecoveries = make(chan struct{}, maxPendingRecoveries)
+}
+
+// sortedKeys returns sorted keys of map with string keys for stable order of errors
+func sortedKeys(m interface{}) []string {
+	keys := make([]string, 0)
+
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/session"
+	"github.com/rosberry/rauther/user"
+)
+
+// Payload is JSON body of webhook request
+type Payload struct {
+	// ID of delivery, the same for all retries and replays
+	ID        string `json:"id"`
+	Event     string `json:"event"`
+	Timestamp int64  `json:"timestamp"`
+	Data      Data   `json:"data"`
+}
+
+// Data describes subject of event. Users are sent by ID only: load user by ID to get other fields
+type Data struct {
+	UserID       interface{} `json:"user_id,omitempty"`
+	MergedUserID interface{} `json:"merged_user_id,omitempty"`
+	AuthKey      string      `json:"auth_key,omitempty"`
+	UID          string      `json:"uid,omitempty"`
+	PreviousUID  string      `json:"previous_uid,omitempty"`
+	DeviceID     string      `json:"device_id,omitempty"`
+}
+
+// EventData returns data of known event
+func EventData(e events.Event) Data {
+	switch e := e.(type) {
+	case events.SessionStarted:
+		return Data{UserID: sessionUserID(e.Session), DeviceID: e.DeviceID}
+	case events.SessionRevoked:
+		return Data{UserID: userID(e.User)}
+	case events.GuestCreated:
+		return Data{UserID: userID(e.User)}
+	case events.GuestRemoved:
+		return Data{UserID: e.UserID}
+	case events.GuestConverted:
+		return Data{UserID: userID(e.User), AuthKey: e.AuthKey, UID: e.UID}
+	case events.UserSignedUp:
+		return Data{UserID: userID(e.User), AuthKey: e.AuthKey, UID: e.UID}
+	case events.UserSignedIn:
+		return Data{UserID: userID(e.User), AuthKey: e.AuthKey, UID: e.UID}
+	case events.UserConfirmed:
+		return Data{UserID: userID(e.User), AuthKey: e.AuthKey, UID: e.UID}
+	case events.OTPRequested:
+		return Data{UserID: userID(e.User), AuthKey: e.AuthKey, UID: e.UID}
+	case events.RecoveryRequested:
+		return Data{UserID: userID(e.User), AuthKey: e.AuthKey, UID: e.UID}
+	case events.PasswordReset:
+		return Data{UserID: userID(e.User), AuthKey: e.AuthKey, UID: e.UID}
+	case events.IdentityLinked:
+		return Data{UserID: userID(e.User), AuthKey: e.AuthKey, UID: e.UID}
+	case events.AccountsMerged:
+		return Data{UserID: userID(e.User), MergedUserID: userID(e.Merged), AuthKey: e.AuthKey, UID: e.UID}
+	case events.UIDChangeRequested:
+		return Data{UserID: userID(e.User), AuthKey: e.AuthKey, UID: e.UID}
+	case events.UIDChanged:
+		return Data{UserID: userID(e.User), AuthKey: e.AuthKey, UID: e.UID, PreviousUID: e.PreviousUID}
+	}
+
+	return Data{}
+}
+
+func userID(u user.User) interface{} {
+	if u == nil {
+		return nil
+	}
+
+	return u.GetID()
+}
+
+func sessionUserID(s session.Session) interface{} {
+	if s == nil {
+		return nil
+	}
+
+	return s.GetUserID()
+}
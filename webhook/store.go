@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status of delivery
+type Status string
+
+const (
+	Pending   Status = "pending"
+	Delivered Status = "delivered"
+	Failed    Status = "failed"
+)
+
+// ErrDeliveryNotFound is returned by Store.Load if delivery does not exist
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+type (
+	// Delivery is request of one event to one endpoint
+	Delivery struct {
+		ID         string
+		EndpointID string
+		Event      string
+		// Body is JSON of Payload. It is not changed by retries and replays
+		Body []byte
+
+		Status      Status
+		Attempts    int
+		NextAttempt time.Time
+		LastError   string
+		CreatedAt   time.Time
+		UpdatedAt   time.Time
+	}
+
+	// Filter of deliveries. Zero fields are not checked
+	Filter struct {
+		Status     Status
+		EndpointID string
+		Event      string
+		// Since - created at or after
+		Since time.Time
+		// Due - pending with next attempt at or before
+		Due   time.Time
+		Limit int
+	}
+
+	// Store persists deliveries: pending deliveries are sent after restart, delivered and failed ones can be replayed
+	Store interface {
+		// Save creates or updates delivery by ID
+		Save(ctx context.Context, d Delivery) error
+
+		// Load returns delivery by ID or ErrDeliveryNotFound
+		Load(ctx context.Context, id string) (*Delivery, error)
+
+		// Find returns deliveries matching filter (see Filter.Match) ordered by creation time
+		Find(ctx context.Context, f Filter) ([]Delivery, error)
+	}
+
+	// MemoryStore keeps deliveries in memory. Use it in tests or if lost deliveries on restart are acceptable
+	MemoryStore struct {
+		mu         sync.RWMutex
+		deliveries map[string]Delivery
+	}
+)
+
+var _ Store = (*MemoryStore)(nil)
+
+// Match returns true if delivery matches filter
+func (f Filter) Match(d Delivery) bool {
+	switch {
+	case f.Status != "" && d.Status != f.Status:
+		return false
+	case f.EndpointID != "" && d.EndpointID != f.EndpointID:
+		return false
+	case f.Event != "" && d.Event != f.Event:
+		return false
+	case !f.Since.IsZero() && d.CreatedAt.Before(f.Since):
+		return false
+	case !f.Due.IsZero() && (d.Status != Pending || d.NextAttempt.After(f.Due)):
+		return false
+	}
+
+	return true
+}
+
+// NewMemoryStore returns empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{deliveries: map[string]Delivery{}}
+}
+
+// Save stores copy of delivery
+func (s *MemoryStore) Save(_ context.Context, d Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deliveries[d.ID] = d
+
+	return nil
+}
+
+// Load returns delivery by ID
+func (s *MemoryStore) Load(_ context.Context, id string) (*Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.deliveries[id]
+	if !ok {
+		return nil, ErrDeliveryNotFound
+	}
+
+	return &d, nil
+}
+
+// Find returns deliveries matching filter
+func (s *MemoryStore) Find(_ context.Context, f Filter) ([]Delivery, error) {
+	s.mu.RLock()
+
+	var res []Delivery
+
+	for _, d := range s.deliveries {
+		if f.Match(d) {
+			res = append(res, d)
+		}
+	}
+
+	s.mu.RUnlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+
+	if f.Limit > 0 && len(res) > f.Limit {
+		res = res[:f.Limit]
+	}
+
+	return res, nil
+}
@@ -0,0 +1,458 @@
+// Package webhook delivers auth lifecycle events (see package events) to HTTP endpoints of other services.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/logger"
+	"github.com/rosberry/rauther/sender"
+)
+
+const (
+	defaultTimeout       = 10 * time.Second
+	defaultMaxAttempts   = 5
+	defaultRetryDelay    = time.Second
+	defaultMaxRetryDelay = time.Hour
+	defaultPollInterval  = 10 * time.Second
+	defaultBatchSize     = 100
+)
+
+var (
+	errEndpointsRequired = errors.New("webhook endpoints are required")
+	errEndpointInvalid   = errors.New("webhook endpoint id and url are required")
+	errEndpointNotFound  = errors.New("webhook endpoint not found")
+)
+
+type (
+	// Endpoint receives events
+	Endpoint struct {
+		// ID of endpoint, saved in deliveries. Must not be changed while endpoint has pending deliveries
+		ID  string
+		URL string
+		// Secret is HMAC-SHA256 key for payload signature (see sender.Sign). Signature is not sent if secret is empty
+		Secret string
+		// Events are names of sent events (see events.Event.EventName). Default: all events
+		Events []string
+		// Headers added to each request, e.g. Authorization
+		Headers map[string]string
+	}
+
+	// Config contains settings of dispatcher
+	Config struct {
+		Endpoints []Endpoint
+
+		// Store of deliveries. Default: MemoryStore
+		Store Store
+
+		// Timeout of one request. Default: 10s
+		Timeout time.Duration
+		// MaxAttempts is count of requests before delivery is failed. Default: 5
+		MaxAttempts int
+		// RetryDelay is delay before first retry, doubled for each next retry up to MaxRetryDelay. Default: 1s, 1h
+		RetryDelay    time.Duration
+		MaxRetryDelay time.Duration
+		// PollInterval of Run loop sending due retries. Default: 10s
+		PollInterval time.Duration
+
+		// SignatureHeader, TimestampHeader and IdempotencyHeader are the same as in sender.WebhookConfig
+		SignatureHeader   string
+		TimestampHeader   string
+		IdempotencyHeader string
+
+		// Client used for requests. Default: http.Client with Timeout
+		Client *http.Client
+
+		// Logger for delivery errors. Default: logger.Nop
+		Logger logger.Logger
+	}
+
+	// Dispatcher saves deliveries of events to matching endpoints and sends them with retries.
+	// Run one dispatcher per store: deliveries being sent are tracked in memory
+	Dispatcher struct {
+		config    Config
+		endpoints map[string]Endpoint
+
+		mu       sync.Mutex
+		inFlight map[string]bool
+	}
+
+	// ResponseError is returned when endpoint responded with unexpected status
+	ResponseError struct {
+		StatusCode int
+		Body       string
+	}
+)
+
+func (e ResponseError) Error() string {
+	return fmt.Sprintf("webhook response status %d: %s", e.StatusCode, e.Body)
+}
+
+// New returns dispatcher
+func New(cfg Config) (*Dispatcher, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, errEndpointsRequired
+	}
+
+	endpoints := make(map[string]Endpoint, len(cfg.Endpoints))
+
+	for _, e := range cfg.Endpoints {
+		if e.ID == "" || e.URL == "" {
+			return nil, errEndpointInvalid
+		}
+
+		endpoints[e.ID] = e
+	}
+
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+
+	if cfg.RetryDelay == 0 {
+		cfg.RetryDelay = defaultRetryDelay
+	}
+
+	if cfg.MaxRetryDelay == 0 {
+		cfg.MaxRetryDelay = defaultMaxRetryDelay
+	}
+
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = sender.DefaultSignatureHeader
+	}
+
+	if cfg.TimestampHeader == "" {
+		cfg.TimestampHeader = sender.DefaultTimestampHeader
+	}
+
+	if cfg.IdempotencyHeader == "" {
+		cfg.IdempotencyHeader = sender.DefaultIdempotencyHeader
+	}
+
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = logger.Nop{}
+	}
+
+	return &Dispatcher{
+		config:    cfg,
+		endpoints: endpoints,
+		inFlight:  map[string]bool{},
+	}, nil
+}
+
+// Subscribe subscribes dispatcher to events of bus (see rauther.Rauther.Events). Events are handled asynchronously
+func (d *Dispatcher) Subscribe(bus *events.Bus) (unsubscribe func()) {
+	return bus.Subscribe(d.Handle, nil, events.Async())
+}
+
+// Handle saves deliveries of event to matching endpoints and makes first attempts.
+// Context is used for values only: deliveries are not canceled with request
+func (d *Dispatcher) Handle(_ context.Context, e events.Event) {
+	ctx := context.Background()
+	data := EventData(e)
+	now := time.Now()
+
+	for _, endpoint := range d.config.Endpoints {
+		if !endpoint.accepts(e.EventName()) {
+			continue
+		}
+
+		id := uuid.NewString()
+
+		payload, err := json.Marshal(Payload{
+			ID:        id,
+			Event:     e.EventName(),
+			Timestamp: now.Unix(),
+			Data:      data,
+		})
+		if err != nil {
+			d.config.Logger.Log(logger.ErrorLevel, "webhook marshal payload", logger.F("event", e.EventName()), logger.Err(err))
+			continue
+		}
+
+		delivery := Delivery{
+			ID:          id,
+			EndpointID:  endpoint.ID,
+			Event:       e.EventName(),
+			Body:        payload,
+			Status:      Pending,
+			NextAttempt: now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+
+		if !d.acquire(id) {
+			continue
+		}
+
+		if err := d.config.Store.Save(ctx, delivery); err != nil {
+			d.release(id)
+			d.config.Logger.Log(logger.ErrorLevel, "webhook save delivery", logger.F("event", e.EventName()), logger.Err(err))
+
+			continue
+		}
+
+		d.attempt(ctx, delivery)
+		d.release(id)
+	}
+}
+
+// Run sends due retries until context is done
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Process(ctx); err != nil {
+			d.config.Logger.Log(logger.ErrorLevel, "webhook process deliveries", logger.Err(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process sends pending deliveries with due next attempt once
+func (d *Dispatcher) Process(ctx context.Context) error {
+	due, err := d.config.Store.Find(ctx, Filter{Due: time.Now(), Limit: defaultBatchSize})
+	if err != nil {
+		return fmt.Errorf("find pending deliveries: %w", err)
+	}
+
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if !d.acquire(delivery.ID) {
+			continue
+		}
+
+		// delivery could be sent after it was found
+		current, err := d.config.Store.Load(ctx, delivery.ID)
+		if err == nil && current.Status == Pending {
+			d.attempt(ctx, *current)
+		}
+
+		d.release(delivery.ID)
+	}
+
+	return nil
+}
+
+// Replay sends delivery again with the same payload, e.g. after failure or data loss of receiver.
+// Delivery is pending with new attempts if request failed
+func (d *Dispatcher) Replay(ctx context.Context, id string) (*Delivery, error) {
+	if !d.acquire(id) {
+		return nil, fmt.Errorf("webhook delivery %s is being sent", id)
+	}
+	defer d.release(id)
+
+	delivery, err := d.config.Store.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Status = Pending
+	delivery.Attempts = 0
+	delivery.LastError = ""
+
+	res := d.attempt(ctx, *delivery)
+
+	return &res, nil
+}
+
+// ReplayAll replays deliveries matching filter, e.g. failed deliveries of endpoint since outage.
+// Returns replayed deliveries with results
+func (d *Dispatcher) ReplayAll(ctx context.Context, f Filter) ([]Delivery, error) {
+	found, err := d.config.Store.Find(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("find deliveries: %w", err)
+	}
+
+	res := make([]Delivery, 0, len(found))
+
+	for _, delivery := range found {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+
+		replayed, err := d.Replay(ctx, delivery.ID)
+		if err != nil {
+			d.config.Logger.Log(logger.WarnLevel, "webhook replay delivery", logger.F("delivery", delivery.ID), logger.Err(err))
+			continue
+		}
+
+		res = append(res, *replayed)
+	}
+
+	return res, nil
+}
+
+// attempt makes one request of delivery and saves result
+func (d *Dispatcher) attempt(ctx context.Context, delivery Delivery) Delivery {
+	endpoint, ok := d.endpoints[delivery.EndpointID]
+
+	var (
+		retry bool
+		err   = errEndpointNotFound
+	)
+
+	if ok {
+		retry, err = d.post(ctx, endpoint, delivery)
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.UpdatedAt = now
+
+	switch {
+	case err == nil:
+		delivery.Status = Delivered
+		delivery.LastError = ""
+	case retry && delivery.Attempts < d.config.MaxAttempts:
+		delivery.LastError = err.Error()
+		delivery.NextAttempt = now.Add(d.retryDelay(delivery.Attempts))
+	default:
+		delivery.Status = Failed
+		delivery.LastError = err.Error()
+	}
+
+	if err != nil {
+		d.config.Logger.Log(logger.WarnLevel, "webhook delivery attempt",
+			logger.F("delivery", delivery.ID), logger.F("endpoint", delivery.EndpointID),
+			logger.F("attempt", delivery.Attempts), logger.Err(err))
+	}
+
+	// result is saved even if context of replay is canceled
+	if err := d.config.Store.Save(context.Background(), delivery); err != nil {
+		d.config.Logger.Log(logger.ErrorLevel, "webhook save delivery", logger.F("delivery", delivery.ID), logger.Err(err))
+	}
+
+	return delivery
+}
+
+// retryDelay returns delay after attempt: RetryDelay doubled for each previous retry, at most MaxRetryDelay
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.config.RetryDelay
+
+	for i := 1; i < attempts && delay < d.config.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > d.config.MaxRetryDelay {
+		delay = d.config.MaxRetryDelay
+	}
+
+	return delay
+}
+
+// post makes one request. Returns retry = true on network errors, 429 and 5xx responses
+func (d *Dispatcher) post(ctx context.Context, endpoint Endpoint, delivery Delivery) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10) // nolint:gomnd
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(d.config.TimestampHeader, timestamp)
+	req.Header.Set(d.config.IdempotencyHeader, delivery.ID)
+
+	if endpoint.Secret != "" {
+		req.Header.Set(d.config.SignatureHeader, "sha256="+sender.Sign(endpoint.Secret, timestamp, delivery.Body))
+	}
+
+	for k, v := range endpoint.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := d.config.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	const maxErrorBody = 1024
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		io.Copy(ioutil.Discard, resp.Body) // nolint:errcheck
+		return false, nil
+	}
+
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	retry = resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+
+	return retry, ResponseError{
+		StatusCode: resp.StatusCode,
+		Body:       string(respBody),
+	}
+}
+
+// acquire marks delivery as being sent. Returns false if it is sent already
+func (d *Dispatcher) acquire(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.inFlight[id] {
+		return false
+	}
+
+	d.inFlight[id] = true
+
+	return true
+}
+
+func (d *Dispatcher) release(id string) {
+	d.mu.Lock()
+	delete(d.inFlight, id)
+	d.mu.Unlock()
+}
+
+func (e Endpoint) accepts(event string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+
+	for _, name := range e.Events {
+		if name == event {
+			return true
+		}
+	}
+
+	return false
+}
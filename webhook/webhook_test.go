@@ -0,0 +1,313 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rosberry/rauther/events"
+	"github.com/rosberry/rauther/sender"
+)
+
+type testUser struct {
+	id uint
+}
+
+func (u testUser) GetID() interface{} { return u.id }
+
+type (
+	// testReceiver is endpoint responding with queued statuses, then with 200
+	testReceiver struct {
+		mu       sync.Mutex
+		statuses []int
+		requests []testRequest
+	}
+
+	testRequest struct {
+		Header http.Header
+		Body   []byte
+	}
+)
+
+func newTestReceiver(t *testing.T, statuses ...int) (*testReceiver, *httptest.Server) {
+	t.Helper()
+
+	rec := &testReceiver{statuses: statuses}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	return rec, srv
+}
+
+func (rec *testReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	rec.mu.Lock()
+	rec.requests = append(rec.requests, testRequest{Header: r.Header.Clone(), Body: body})
+
+	status := http.StatusOK
+	if len(rec.statuses) > 0 {
+		status, rec.statuses = rec.statuses[0], rec.statuses[1:]
+	}
+	rec.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func (rec *testReceiver) received() []testRequest {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return append([]testRequest(nil), rec.requests...)
+}
+
+func newTestDispatcher(t *testing.T, cfg Config) *Dispatcher {
+	t.Helper()
+
+	d, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func onlyDelivery(t *testing.T, d *Dispatcher) Delivery {
+	t.Helper()
+
+	found, err := d.config.Store.Find(context.Background(), Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(found))
+	}
+
+	return found[0]
+}
+
+func TestDispatcherSignsPayload(t *testing.T) {
+	rec, srv := newTestReceiver(t)
+	d := newTestDispatcher(t, Config{Endpoints: []Endpoint{{
+		ID:      "crm",
+		URL:     srv.URL,
+		Secret:  "secret",
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	}}})
+
+	d.Handle(context.Background(), events.UserSignedUp{User: testUser{id: 7}, AuthKey: "email", UID: "user@example.com"})
+
+	requests := rec.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+
+	req := requests[0]
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(req.Header.Get(sender.DefaultTimestampHeader) + "." + string(req.Body)))
+
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.Header.Get(sender.DefaultSignatureHeader) != want {
+		t.Errorf("signature = %q, want %q", req.Header.Get(sender.DefaultSignatureHeader), want)
+	}
+
+	if req.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("custom header is not sent")
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(req.Body, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if payload.Event != "user_signed_up" || payload.Data.UID != "user@example.com" || payload.Data.UserID != float64(7) {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+
+	if req.Header.Get(sender.DefaultIdempotencyHeader) != payload.ID {
+		t.Errorf("idempotency key %q != payload id %q", req.Header.Get(sender.DefaultIdempotencyHeader), payload.ID)
+	}
+
+	if delivery := onlyDelivery(t, d); delivery.Status != Delivered || delivery.Attempts != 1 {
+		t.Errorf("delivery status %s, attempts %d", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestDispatcherEndpointEvents(t *testing.T) {
+	rec, srv := newTestReceiver(t)
+	other, otherSrv := newTestReceiver(t)
+
+	d := newTestDispatcher(t, Config{Endpoints: []Endpoint{
+		{ID: "all", URL: srv.URL},
+		{ID: "signups", URL: otherSrv.URL, Events: []string{"user_signed_up"}},
+	}})
+
+	d.Handle(context.Background(), events.UserSignedIn{User: testUser{id: 1}})
+	d.Handle(context.Background(), events.UserSignedUp{User: testUser{id: 1}})
+
+	if n := len(rec.received()); n != 2 {
+		t.Errorf("endpoint without events filter got %d requests, want 2", n)
+	}
+
+	if n := len(other.received()); n != 1 {
+		t.Errorf("endpoint with events filter got %d requests, want 1", n)
+	}
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	rec, srv := newTestReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	d := newTestDispatcher(t, Config{
+		Endpoints:  []Endpoint{{ID: "crm", URL: srv.URL}},
+		RetryDelay: time.Hour,
+	})
+
+	ctx := context.Background()
+	d.Handle(ctx, events.UserSignedUp{User: testUser{id: 1}})
+
+	delivery := onlyDelivery(t, d)
+	if delivery.Status != Pending || delivery.Attempts != 1 || delivery.LastError == "" {
+		t.Fatalf("after first attempt: %+v", delivery)
+	}
+
+	if delay := time.Until(delivery.NextAttempt); delay < 59*time.Minute || delay > time.Hour {
+		t.Errorf("next attempt in %s, want 1h", delay)
+	}
+
+	// retry is not due yet
+	if err := d.Process(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(rec.received()); n != 1 {
+		t.Fatalf("got %d requests before retry is due, want 1", n)
+	}
+
+	for i := 0; i < 2; i++ {
+		delivery = onlyDelivery(t, d)
+		delivery.NextAttempt = time.Now()
+
+		if err := d.config.Store.Save(ctx, delivery); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := d.Process(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	requests := rec.received()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+
+	for _, req := range requests[1:] {
+		if string(req.Body) != string(requests[0].Body) {
+			t.Error("retry body differs from first attempt")
+		}
+	}
+
+	if delivery = onlyDelivery(t, d); delivery.Status != Delivered || delivery.Attempts != 3 {
+		t.Errorf("after retries: status %s, attempts %d", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestDispatcherFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+	}{
+		{name: "client error is not retried", statuses: []int{http.StatusBadRequest}, attempts: 1},
+		{name: "max attempts", statuses: []int{500, 500, 500}, attempts: 3},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			_, srv := newTestReceiver(t, tt.statuses...)
+			d := newTestDispatcher(t, Config{
+				Endpoints:   []Endpoint{{ID: "crm", URL: srv.URL}},
+				MaxAttempts: 3,
+				RetryDelay:  time.Nanosecond,
+			})
+
+			ctx := context.Background()
+			d.Handle(ctx, events.UserSignedUp{User: testUser{id: 1}})
+
+			for i := 0; i < 5; i++ {
+				if err := d.Process(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if delivery := onlyDelivery(t, d); delivery.Status != Failed || delivery.Attempts != tt.attempts {
+				t.Errorf("status %s, attempts %d, want failed after %d", delivery.Status, delivery.Attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestDispatcherRetryDelay(t *testing.T) {
+	d := newTestDispatcher(t, Config{
+		Endpoints:     []Endpoint{{ID: "crm", URL: "http://localhost"}},
+		RetryDelay:    time.Second,
+		MaxRetryDelay: 5 * time.Second,
+	})
+
+	for attempts, want := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 5 * time.Second,
+		9: 5 * time.Second,
+	} {
+		if got := d.retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestDispatcherReplay(t *testing.T) {
+	rec, srv := newTestReceiver(t, http.StatusBadRequest)
+	d := newTestDispatcher(t, Config{Endpoints: []Endpoint{{ID: "crm", URL: srv.URL}}})
+
+	ctx := context.Background()
+	d.Handle(ctx, events.UserSignedUp{User: testUser{id: 1}})
+
+	failed := onlyDelivery(t, d)
+	if failed.Status != Failed {
+		t.Fatalf("status %s, want failed", failed.Status)
+	}
+
+	replayed, err := d.ReplayAll(ctx, Filter{Status: Failed, EndpointID: "crm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(replayed) != 1 || replayed[0].Status != Delivered || replayed[0].Attempts != 1 {
+		t.Fatalf("replayed %+v", replayed)
+	}
+
+	requests := rec.received()
+	if len(requests) != 2 || string(requests[1].Body) != string(requests[0].Body) {
+		t.Fatalf("replay must send the same payload")
+	}
+
+	if requests[1].Header.Get(sender.DefaultIdempotencyHeader) != failed.ID {
+		t.Errorf("replay idempotency key differs from delivery id")
+	}
+
+	if _, err := d.Replay(ctx, "unknown"); err != ErrDeliveryNotFound {
+		t.Errorf("Replay() of unknown delivery error = %v, want %v", err, ErrDeliveryNotFound)
+	}
+}